@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// version and commit are set when building a release:
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.commit=$(git rev-parse --short HEAD)"
+//
+// A plain go build is a "dev" build and never reports updates.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// latestReleaseURL is the GitHub API endpoint of the newest published release
+const latestReleaseURL = "https://api.github.com/repos/Slice277353/airflow/releases/latest"
+
+// updateSettingsFile keeps whether to look for updates at startup
+const updateSettingsFile = "update_settings.json"
+
+type UpdateSettings struct {
+	CheckAtStartup bool
+}
+
+var updateSettings UpdateSettings
+
+// Release is the part of a GitHub release the check needs
+type Release struct {
+	Tag string `json:"tag_name"`
+	URL string `json:"html_url"`
+}
+
+// updateResult is what a check running in the background hands to the frame loop
+type updateResult struct {
+	release Release
+	err     error
+	quiet   bool // a startup check, which only speaks up when there is an update
+}
+
+var updateResults = make(chan updateResult, 1)
+
+// buildVersion describes this build
+func buildVersion() string {
+	s := version
+	if commit != "" {
+		s += " (" + commit + ")"
+	} else if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		s += " (" + info.Main.Version + ")"
+	}
+	return s
+}
+
+// parseVersion splits "v1.4.0" into its numbers, ok is false for anything else
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// newerVersion reports whether release is a later version than current
+func newerVersion(release, current string) bool {
+	r, okR := parseVersion(release)
+	c, okC := parseVersion(current)
+	if !okR || !okC {
+		return false
+	}
+	for i := 0; i < len(r) || i < len(c); i++ {
+		var a, b int
+		if i < len(r) {
+			a = r[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func fetchLatestRelease() (Release, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", latestReleaseURL, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "airflow/"+version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("GitHub answered %s", resp.Status)
+	}
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return Release{}, err
+	}
+	return release, nil
+}
+
+// checkForUpdates asks GitHub for the latest release without holding up the frames, the
+// answer is shown by updateCheckResult
+func checkForUpdates(quiet bool) {
+	go func() {
+		release, err := fetchLatestRelease()
+		select {
+		case updateResults <- updateResult{release, err, quiet}:
+		default: // a check is already waiting to be shown
+		}
+	}()
+}
+
+// updateCheckResult shows the answer of a finished check, called every frame
+func updateCheckResult() {
+	var result updateResult
+	select {
+	case result = <-updateResults:
+	default:
+		return
+	}
+	switch {
+	case result.err != nil:
+		log.Printf("Update check failed: %v", result.err)
+		if !result.quiet {
+			overlays.Notify("Could not check for updates: " + result.err.Error())
+		}
+	case version == "dev":
+		if !result.quiet {
+			overlays.Notify("This is a development build, the latest release is " + result.release.Tag)
+		}
+	case newerVersion(result.release.Tag, version):
+		log.Printf("Version %s is available at %s", result.release.Tag, result.release.URL)
+		overlays.Notify(fmt.Sprintf("Version %s is available (this is %s): %s", result.release.Tag, version, result.release.URL))
+	case !result.quiet:
+		overlays.Notify("This is the latest version")
+	}
+}
+
+func loadUpdateSettings() {
+	data, err := os.ReadFile(updateSettingsFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &updateSettings); err != nil {
+		log.Printf("Error parsing %s: %v", updateSettingsFile, err)
+	}
+}
+
+func saveUpdateSettings() {
+	data, err := json.MarshalIndent(updateSettings, "", "  ")
+	if err == nil {
+		err = os.WriteFile(updateSettingsFile, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Error saving %s: %v", updateSettingsFile, err)
+	}
+}
+
+func showAboutDialog() {
+	dialog := gui.NewPanel(320, 175)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	lines := []string{
+		"Airflow",
+		"Version " + buildVersion(),
+		fmt.Sprintf("Built with %s for %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH),
+	}
+	for i, line := range lines {
+		label := gui.NewLabel(line)
+		label.SetPosition(10, float32(8+22*i))
+		dialog.Add(label)
+	}
+
+	startupCheck := gui.NewCheckBox("Check for updates at startup")
+	startupCheck.SetPosition(10, 100)
+	startupCheck.SetValue(updateSettings.CheckAtStartup)
+	startupCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		updateSettings.CheckAtStartup = startupCheck.Value()
+		saveUpdateSettings()
+	})
+	dialog.Add(startupCheck)
+
+	checkBtn := gui.NewButton("Check now")
+	checkBtn.SetPosition(10, 140)
+	checkBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		checkForUpdates(false)
+	})
+	dialog.Add(checkBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(260, 140)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeAboutUI(scene *core.Node) {
+	loadUpdateSettings()
+	if updateSettings.CheckAtStartup {
+		checkForUpdates(true)
+	}
+
+	aboutBtn := gui.NewButton("About...")
+	aboutBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showAboutDialog()
+	})
+	addSidebarWidget(scene, aboutBtn)
+}
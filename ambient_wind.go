@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// AmbientWind blows into the domain across its upwind faces with the speed profile of
+// the atmospheric boundary layer: still at the ground and increasing with height, the
+// faster the smoother the terrain. It makes building and terrain scale runs realistic
+// where the wind sources only give uniform jets.
+type AmbientWind struct {
+	Enabled   bool
+	Profile   WindProfile
+	Speed     float32 // m/s at RefHeight
+	RefHeight float32 // m above the ground
+	Heading   float32 // degrees from +X towards +Z the wind blows to
+	Roughness float32 // roughness length z0 in m, for the log law
+	Exponent  float32 // for the power law
+}
+
+// WindProfile is the law the wind speed follows with height
+type WindProfile int
+
+const (
+	ProfileLog   WindProfile = iota // u = uref ln(z/z0) / ln(zref/z0)
+	ProfilePower                    // u = uref (z/zref)^alpha
+)
+
+var windProfileNames = []string{"log law", "power law"}
+
+var ambientWind = AmbientWind{
+	Speed:     5,
+	RefHeight: 2,
+	Roughness: 0.1, // open country with hedges
+	Exponent:  0.14,
+}
+
+// SpeedAt returns the wind speed in m/s at height z in m
+func (a *AmbientWind) SpeedAt(z float32) float32 {
+	if z <= 0 {
+		return 0
+	}
+	switch a.Profile {
+	case ProfilePower:
+		return a.Speed * math32.Pow(z/a.RefHeight, a.Exponent)
+	default:
+		if z <= a.Roughness || a.RefHeight <= a.Roughness {
+			return 0
+		}
+		return a.Speed * float32(math.Log(float64(z/a.Roughness))/math.Log(float64(a.RefHeight/a.Roughness)))
+	}
+}
+
+// direction is the horizontal unit vector the wind blows along
+func (a *AmbientWind) direction() math32.Vector3 {
+	heading := math32.DegToRad(a.Heading)
+	return math32.Vector3{X: math32.Cos(heading), Z: math32.Sin(heading)}
+}
+
+// VelocityAt returns the ambient wind at a point of the field at height y in scene units
+func (a *AmbientWind) VelocityAt(y float32) math32.Vector3 {
+	speed := math32.Min(a.SpeedAt(y*simConfig.LengthUnit), maxFieldSpeed)
+	dir := a.direction()
+	return *dir.MultiplyScalar(speed)
+}
+
+// applyAmbientWind sets the faces of the boundary cells on the upwind sides of the domain
+// to the wind profile, and the faces between them and the interior, so the wind enters
+// there. Periodic axes have no upwind side.
+func (f *VectorField) applyAmbientWind() {
+	if !ambientWind.Enabled {
+		return
+	}
+	dir := ambientWind.direction()
+	wind := [3]float32{dir.X, 0, dir.Z}
+	periodic, isPeriodic := simConfig.Periodic.axis()
+	f.forEachCell(func(c [3]int) {
+		for _, a := range []int{axisX, axisZ} {
+			if (isPeriodic && a == periodic) || wind[a] == 0 {
+				continue
+			}
+			lo, hi := f.interior(a)
+			upwind := (wind[a] > 0 && c[a] < lo) || (wind[a] < 0 && c[a] > hi)
+			inflowFace := wind[a] > 0 && c[a] == lo
+			if !upwind && !inflowFace {
+				continue
+			}
+			center := f.cellCenter(c[0], c[1], c[2])
+			v := ambientWind.VelocityAt(center.Y)
+			values := [3]float32{v.X, 0, v.Z}
+			if inflowFace {
+				f.setFace(a, c, values[a])
+				continue
+			}
+			for axis := 0; axis < 3; axis++ {
+				if axis == axisY && c[1] == 0 {
+					continue // the ground stays closed
+				}
+				f.setFace(axis, c, values[axis])
+			}
+		}
+	})
+}
+
+// seedAmbientWind fills the whole field with the wind profile, so turning the wind on
+// doesn't wait for it to blow through the domain
+func (f *VectorField) seedAmbientWind() {
+	f.prepare()
+	for _, face := range f.topology.openFaces {
+		if face.axis == axisY {
+			continue
+		}
+		center := f.cellCenter(face.c[0], face.c[1], face.c[2])
+		v := ambientWind.VelocityAt(center.Y)
+		f.setFace(face.axis, face.c, [3]float32{v.X, 0, v.Z}[face.axis])
+	}
+}
+
+// showAmbientWindDialog edits the ambient wind
+func showAmbientWindDialog(scene *core.Node) {
+	dialog := gui.NewPanel(300, 250)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Ambient wind")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	enabledCheck := gui.NewCheckBox("Enabled")
+	enabledCheck.SetPosition(140, 8)
+	enabledCheck.SetValue(ambientWind.Enabled)
+	enabledCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		ambientWind.Enabled = enabledCheck.Value()
+		if ambientWind.Enabled {
+			requestFieldUpdate("ambient wind", func() {
+				vectorField.seedAmbientWind()
+			})
+		}
+	})
+	dialog.Add(enabledCheck)
+
+	profileLabel := gui.NewLabel("Profile")
+	profileLabel.SetPosition(10, 38)
+	dialog.Add(profileLabel)
+	profileBtn := gui.NewButton(windProfileNames[ambientWind.Profile])
+	profileBtn.SetPosition(140, 35)
+	profileBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		ambientWind.Profile = (ambientWind.Profile + 1) % WindProfile(len(windProfileNames))
+		profileBtn.Label.SetText(windProfileNames[ambientWind.Profile])
+	})
+	dialog.Add(profileBtn)
+
+	y := float32(65)
+	row := func(name string, input *NumericInput) {
+		label := gui.NewLabel(name)
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		input.SetPosition(140, y)
+		dialog.Add(input)
+		y += 30
+	}
+	row("Speed", NewNumericInput(ambientWind.Speed, 0, 60, 0.5, "m/s", func(value float32) {
+		ambientWind.Speed = value
+	}))
+	row("At height", NewNumericInput(ambientWind.RefHeight, 0.1, 500, 0.5, "m", func(value float32) {
+		ambientWind.RefHeight = value
+	}))
+	row("Heading", NewNumericInput(ambientWind.Heading, -360, 360, 15, "°", func(value float32) {
+		ambientWind.Heading = value
+	}))
+	row("Roughness z0", NewNumericInput(ambientWind.Roughness, 0.0001, 5, 0.01, "m", func(value float32) {
+		ambientWind.Roughness = value
+	}))
+	row("Exponent", NewNumericInput(ambientWind.Exponent, 0.05, 0.6, 0.01, "", func(value float32) {
+		ambientWind.Exponent = value
+	}))
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(240, 215)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeAmbientWindUI(scene *core.Node) {
+	ambientBtn := gui.NewButton("Ambient wind...")
+	ambientBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showAmbientWindDialog(scene)
+	})
+	addSidebarWidget(scene, ambientBtn)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/gui/assets"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/text"
+	"github.com/g3n/engine/texture"
+	"github.com/g3n/engine/window"
+)
+
+// Annotation is a 3D text callout, optionally with an arrow pointing at its anchor
+type Annotation struct {
+	Text   string
+	Anchor math32.Vector3 // point of interest the arrow points at
+	Offset math32.Vector3 // label position relative to the anchor
+	Arrow  bool
+
+	node *core.Node
+}
+
+const annotationLabelHeight = 0.3
+
+var annotations []*Annotation
+var labelFont *text.Font
+
+func annotationFont() *text.Font {
+	if labelFont == nil {
+		font, err := text.NewFontFromData(assets.MustAsset("fonts/FreeSans.ttf"))
+		if err != nil {
+			log.Println("Error loading annotation font:", err)
+			return nil
+		}
+		font.SetPointSize(48)
+		font.SetColor(&math32.Color4{R: 1, G: 1, B: 1, A: 1})
+		font.SetBgColor(&math32.Color4{R: 0, G: 0, B: 0, A: 0.5})
+		labelFont = font
+	}
+	return labelFont
+}
+
+func addAnnotation(scene *core.Node, a *Annotation) {
+	a.node = buildAnnotationNode(a)
+	objects.Add(a.node, "annotation")
+	annotations = append(annotations, a)
+	log.Printf("Annotation %q added at %v", a.Text, a.Anchor)
+}
+
+func clearAnnotations(scene *core.Node) {
+	for _, a := range annotations {
+		objects.Remove(a.node)
+	}
+	annotations = nil
+}
+
+func buildAnnotationNode(a *Annotation) *core.Node {
+	node := core.NewNode()
+	labelPos := a.Anchor.Clone().Add(&a.Offset)
+
+	if font := annotationFont(); font != nil && a.Text != "" {
+		img := font.DrawText(a.Text)
+		tex := texture.NewTexture2DFromRGBA(img)
+		mat := material.NewStandard(&math32.Color{R: 1, G: 1, B: 1})
+		mat.AddTexture(tex)
+		mat.SetTransparent(true)
+		aspect := float32(img.Bounds().Dx()) / float32(img.Bounds().Dy())
+		sprite := graphic.NewSprite(annotationLabelHeight*aspect, annotationLabelHeight, mat)
+		sprite.SetPositionVec(labelPos)
+		node.Add(sprite)
+	}
+
+	if a.Arrow {
+		addArrow(node, labelPos, &a.Anchor)
+	}
+	return node
+}
+
+// addArrow adds a shaft and head to node pointing from 'from' to 'to'
+func addArrow(node *core.Node, from, to *math32.Vector3) {
+	dir := to.Clone().Sub(from)
+	length := dir.Length()
+	if length < 0.2 {
+		return
+	}
+	dir.Normalize()
+
+	const headLength = 0.15
+	up := math32.NewVector3(0, 1, 0)
+	rot := math32.NewQuaternion(0, 0, 0, 1)
+	rot.SetFromUnitVectors(up, dir)
+	arrowMat := material.NewStandard(math32.NewColor("Yellow"))
+
+	// Leave room under the label for the text, then run the shaft to the head
+	start := from.Clone().Add(dir.Clone().MultiplyScalar(annotationLabelHeight * 0.6))
+	shaftLength := to.Clone().Sub(start).Length() - headLength
+	if shaftLength <= 0 {
+		return
+	}
+	shaft := graphic.NewMesh(geometry.NewCylinder(0.02, 1, 8, 1, true, true), arrowMat)
+	shaft.SetScale(1, shaftLength, 1)
+	shaft.SetPositionVec(start.Clone().Add(dir.Clone().MultiplyScalar(shaftLength / 2)))
+	shaft.SetQuaternionQuat(rot)
+	node.Add(shaft)
+
+	arrowMat.Incref()
+	head := graphic.NewMesh(geometry.NewCone(0.06, headLength, 12, 1, true), arrowMat)
+	head.SetPositionVec(to.Clone().Sub(dir.Clone().MultiplyScalar(headLength / 2)))
+	head.SetQuaternionQuat(rot)
+	node.Add(head)
+}
+
+func initializeAnnotationUI(scene *core.Node, cam camera.ICamera) {
+	labelText := gui.NewEdit(120, "Label text")
+	addSidebarWidget(scene, labelText)
+
+	waitingForPlacement := false
+	addLabelBtn := gui.NewButton("Add Label")
+	addLabelBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		waitingForPlacement = true
+		log.Println("Click on the scene to place the label")
+	})
+	addSidebarWidget(scene, addLabelBtn)
+
+	clearLabelsBtn := gui.NewButton("Clear Labels")
+	clearLabelsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		clearAnnotations(scene)
+	})
+	addSidebarWidget(scene, clearLabelsBtn)
+
+	app.App().Subscribe(window.OnMouseDown, func(evname string, ev interface{}) {
+		if !waitingForPlacement || overlays.Blocking() {
+			return
+		}
+		mev := ev.(*window.MouseEvent)
+		if mev.Button != window.MouseButtonLeft {
+			return
+		}
+		point, ok := pickGroundPoint(cam, mev.Xpos, mev.Ypos)
+		if !ok {
+			return
+		}
+		addAnnotation(scene, &Annotation{
+			Text:   labelText.Text(),
+			Anchor: *point,
+			Offset: *math32.NewVector3(0, 1, 0),
+			Arrow:  true,
+		})
+		waitingForPlacement = false
+	})
+}
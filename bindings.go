@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// Wind source parameters can be bound to expressions of the simulated time t, which are
+// evaluated every simulation step, for time-varying conditions without scripting.
+
+// compiledExprs caches the compiled expressions by source text
+var compiledExprs = map[string]*Expr{}
+
+// compileExpr returns the compiled src, nil for an empty one
+func compileExpr(src string) (*Expr, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, nil
+	}
+	if e, ok := compiledExprs[src]; ok {
+		return e, nil
+	}
+	e, err := ParseExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	compiledExprs[src] = e
+	return e, nil
+}
+
+// applyBindings sets the bound parameters of the wind sources for the simulated time t
+func applyBindings(t float32) {
+	for i := range windSources {
+		wind := &windSources[i]
+		if e, err := compileExpr(wind.SpeedExpr); err == nil && e != nil {
+			wind.Speed = math32.Max(e.Eval(t), 0)
+		}
+		if e, err := compileExpr(wind.HeadingExpr); err == nil && e != nil {
+			// turn the horizontal part of the direction, its slope stays
+			heading := math32.DegToRad(e.Eval(t))
+			horizontal := math32.Sqrt(wind.Direction.X*wind.Direction.X + wind.Direction.Z*wind.Direction.Z)
+			if horizontal == 0 {
+				horizontal = 1
+			}
+			wind.Direction.X = horizontal * math32.Cos(heading)
+			wind.Direction.Z = horizontal * math32.Sin(heading)
+			wind.Direction.Normalize()
+		}
+	}
+}
+
+// showBindingsDialog edits the expressions of every wind source
+func showBindingsDialog(scene *core.Node) {
+	height := float32(110 + 30*len(windSources))
+	dialog := gui.NewPanel(460, height)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Bindings, expressions of the time t in s, e.g. 5 + 3*sin(2*pi*t/10)")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+	speedLabel := gui.NewLabel("Speed (m/s)")
+	speedLabel.SetPosition(80, 32)
+	dialog.Add(speedLabel)
+	headingLabel := gui.NewLabel("Heading (°)")
+	headingLabel.SetPosition(270, 32)
+	dialog.Add(headingLabel)
+
+	speedEdits := make([]*gui.Edit, len(windSources))
+	headingEdits := make([]*gui.Edit, len(windSources))
+	y := float32(55)
+	for i := range windSources {
+		label := gui.NewLabel(fmt.Sprintf("Source %d", i+1))
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		speedEdits[i] = gui.NewEdit(180, "constant")
+		speedEdits[i].SetText(windSources[i].SpeedExpr)
+		speedEdits[i].SetPosition(80, y)
+		dialog.Add(speedEdits[i])
+		headingEdits[i] = gui.NewEdit(180, "constant")
+		headingEdits[i].SetText(windSources[i].HeadingExpr)
+		headingEdits[i].SetPosition(270, y)
+		dialog.Add(headingEdits[i])
+		y += 30
+	}
+
+	applyBtn := gui.NewButton("Apply")
+	applyBtn.SetPosition(10, height-35)
+	applyBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		// check everything first so a typo doesn't leave half the bindings applied
+		for i := range windSources {
+			for _, edit := range []*gui.Edit{speedEdits[i], headingEdits[i]} {
+				if _, err := compileExpr(edit.Text()); err != nil {
+					overlays.Notify(fmt.Sprintf("Source %d: %v", i+1, err))
+					return
+				}
+			}
+		}
+		for i := range windSources {
+			windSources[i].SpeedExpr = strings.TrimSpace(speedEdits[i].Text())
+			windSources[i].HeadingExpr = strings.TrimSpace(headingEdits[i].Text())
+		}
+		log.Printf("Wind source bindings applied")
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(applyBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(400, height-35)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeBindingsUI(scene *core.Node) {
+	bindingsBtn := gui.NewButton("Bindings...")
+	bindingsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showBindingsDialog(scene)
+	})
+	addSidebarWidget(scene, bindingsBtn)
+}
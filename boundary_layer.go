@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// The boundary-layer tool reads the flow along a line standing on the model's surface,
+// along its normal, where the user clicks. The speed along the wall against the distance
+// from it is the boundary-layer profile; the displacement thickness δ* is how far the
+// wall would have to move out for frictionless flow at the edge speed to carry the same
+// air, the integral of 1 - u/Ue over the line.
+
+const (
+	boundaryLayerSamples = 40
+	boundaryLayerPlotW   = 260
+	boundaryLayerPlotH   = 200
+)
+
+// BoundaryLayerProfile is the flow sampled along one wall-normal line
+type BoundaryLayerProfile struct {
+	Base     math32.Vector3 // on the surface
+	Normal   math32.Vector3 // outward
+	Height   float32        // of the line, scene units
+	Distance []float32      // of each sample from the wall, scene units
+	Speed    []float32      // along the wall, m/s
+
+	EdgeSpeed    float32 // Ue, at the top of the line
+	Displacement float32 // δ*, scene units
+	Thickness    float32 // δ99, where u first reaches 0.99 Ue; Height when it never does
+}
+
+var (
+	boundaryLayerCells float32 = 6 // length of the line in field cells
+	boundaryLayerLine  *graphic.Mesh
+)
+
+// sampleBoundaryLayer reads the profile of f along the line from base along normal
+func sampleBoundaryLayer(f *VectorField, base, normal math32.Vector3, height float32) BoundaryLayerProfile {
+	p := BoundaryLayerProfile{Base: base, Normal: normal, Height: height, Thickness: height}
+	for i := 0; i < boundaryLayerSamples; i++ {
+		d := height * float32(i) / float32(boundaryLayerSamples-1)
+		var u float32
+		if i > 0 { // the air sticks to the wall
+			v := f.SampleVelocity(*base.Clone().Add(normal.Clone().MultiplyScalar(d)))
+			tangential := v.Clone().Sub(normal.Clone().MultiplyScalar(v.Dot(&normal)))
+			u = tangential.Length()
+		}
+		p.Distance = append(p.Distance, d)
+		p.Speed = append(p.Speed, u)
+	}
+	p.EdgeSpeed = p.Speed[len(p.Speed)-1]
+	if p.EdgeSpeed <= 0 {
+		return p
+	}
+	for i := 1; i < len(p.Speed); i++ {
+		lo, hi := 1-p.Speed[i-1]/p.EdgeSpeed, 1-p.Speed[i]/p.EdgeSpeed
+		p.Displacement += (lo + hi) / 2 * (p.Distance[i] - p.Distance[i-1])
+		if p.Thickness == height && p.Speed[i] >= 0.99*p.EdgeSpeed {
+			p.Thickness = p.Distance[i]
+		}
+	}
+	return p
+}
+
+// pickModelPoint returns where the ray through the window position first hits the
+// collision triangles of model and the outward normal there
+func pickModelPoint(cam camera.ICamera, model *core.Node, xpos, ypos float32) (point, normal math32.Vector3, ok bool) {
+	origin, direction, ok := pickRay(cam, xpos, ypos)
+	if !ok || model == nil {
+		return point, normal, false
+	}
+	nearest := float32(math32.Infinity)
+	for _, tri := range cachedTriangles(model) {
+		t, hit := rayTriangleHit(origin, direction, tri)
+		if !hit || t >= nearest {
+			continue
+		}
+		edge1 := tri[1].Clone().Sub(&tri[0])
+		edge2 := tri[2].Clone().Sub(&tri[0])
+		n := edge1.Cross(edge2)
+		if n.LengthSq() == 0 {
+			continue
+		}
+		nearest, normal = t, *n.Normalize()
+	}
+	if nearest == math32.Infinity {
+		return point, normal, false
+	}
+	point = *origin.Clone().Add(direction.Clone().MultiplyScalar(nearest))
+	return point, normal, true
+}
+
+// showBoundaryLayerLine draws the sampled line over the model
+func showBoundaryLayerLine(p BoundaryLayerProfile) {
+	removeBoundaryLayerLine()
+	var q math32.Quaternion
+	q.SetFromUnitVectors(&math32.Vector3{Y: 1}, &p.Normal)
+	boundaryLayerLine = graphic.NewMesh(geometry.NewCylinder(0.01, 1, 6, 1, true, true), material.NewStandard(math32.NewColor("Magenta")))
+	boundaryLayerLine.SetScale(1, p.Height, 1)
+	boundaryLayerLine.SetQuaternionQuat(&q)
+	boundaryLayerLine.SetPositionVec(p.Base.Clone().Add(p.Normal.Clone().MultiplyScalar(p.Height / 2)))
+	objects.Add(boundaryLayerLine, "boundary layer line")
+}
+
+func removeBoundaryLayerLine() {
+	if boundaryLayerLine != nil {
+		objects.Remove(boundaryLayerLine)
+		boundaryLayerLine = nil
+	}
+}
+
+// exportBoundaryLayer writes the profile as CSV in the chosen locale, distances in m
+func exportBoundaryLayer(path string, p BoundaryLayerProfile) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	csv := csvFormat()
+	unit := simConfig.LengthUnit
+	fmt.Fprintf(w, "# wall point %s %s %s, normal %s %s %s\n",
+		csv.Number(p.Base.X), csv.Number(p.Base.Y), csv.Number(p.Base.Z),
+		csv.Number(p.Normal.X), csv.Number(p.Normal.Y), csv.Number(p.Normal.Z))
+	fmt.Fprintf(w, "# edge speed %s m/s, displacement thickness %s m, thickness %s m\n",
+		csv.Number(p.EdgeSpeed), csv.Number(p.Displacement*unit), csv.Number(p.Thickness*unit))
+	csv.WriteRow(w, "distance", "u", "u_over_ue")
+	for i, d := range p.Distance {
+		var ratio float32
+		if p.EdgeSpeed > 0 {
+			ratio = p.Speed[i] / p.EdgeSpeed
+		}
+		csv.WriteNumbers(w, d*unit, p.Speed[i], ratio)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	log.Printf("Boundary layer profile exported to %s", path)
+	return nil
+}
+
+func boundaryLayerFileName() string {
+	return fmt.Sprintf("boundary_layer_%d.csv", time.Now().UnixNano())
+}
+
+// showBoundaryLayerDialog plots u/Ue across, against the distance from the wall up
+func showBoundaryLayerDialog(p BoundaryLayerProfile) {
+	dialog := gui.NewPanel(360, 370)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	unit := simConfig.LengthUnit
+	title := gui.NewLabel(fmt.Sprintf("Boundary layer, Ue %.2f m/s", p.EdgeSpeed))
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	const left, top = 60, 40
+	axisColor := math32.Color4{R: 0.8, G: 0.8, B: 0.8, A: 1}
+	line := func(x, y, w, h float32, color math32.Color4) {
+		l := gui.NewPanel(w, h)
+		l.SetColor4(&color)
+		l.SetPosition(x, y)
+		dialog.Add(l)
+	}
+	label := func(x, y float32, text string) {
+		l := gui.NewLabel(text)
+		l.SetPosition(x, y)
+		dialog.Add(l)
+	}
+	line(left, top, 1, boundaryLayerPlotH, axisColor)
+	line(left, top+boundaryLayerPlotH, boundaryLayerPlotW, 1, axisColor)
+	label(left-8, top+boundaryLayerPlotH+4, "0")
+	label(left+boundaryLayerPlotW/1.1-4, top+boundaryLayerPlotH+4, "1")
+	label(left+boundaryLayerPlotW-30, top+boundaryLayerPlotH+4, "u/Ue")
+	label(5, top-6, fmt.Sprintf("%.3g m", p.Height*unit))
+	label(5, top+boundaryLayerPlotH-8, "wall")
+
+	// u/Ue up to 1.1 across, the wall at the bottom
+	toY := func(d float32) float32 { return top + boundaryLayerPlotH*(1-d/p.Height) }
+	if p.EdgeSpeed > 0 {
+		for i, d := range p.Distance {
+			x := left + boundaryLayerPlotW*math32.Min(p.Speed[i]/p.EdgeSpeed/1.1, 1)
+			line(x-2, toY(d)-2, 4, 4, math32.Color4{R: 0.3, G: 0.8, B: 1, A: 1})
+		}
+		y := toY(p.Displacement)
+		line(left, y, boundaryLayerPlotW, 1, math32.Color4{R: 1, G: 0.6, B: 0.2, A: 0.8})
+		label(left+boundaryLayerPlotW-25, y-16, "δ*")
+	}
+
+	info := gui.NewLabel(fmt.Sprintf("δ* %.3g m, δ99 %.3g m", p.Displacement*unit, p.Thickness*unit))
+	if p.EdgeSpeed <= 0 {
+		info.SetText("No flow along the wall here")
+	}
+	info.SetPosition(10, top+boundaryLayerPlotH+25)
+	dialog.Add(info)
+
+	heightLabel := gui.NewLabel("Line (cells)")
+	heightLabel.SetPosition(10, top+boundaryLayerPlotH+53)
+	dialog.Add(heightLabel)
+	heightInput := NewNumericInput(boundaryLayerCells, 1, 20, 1, "", func(value float32) {
+		boundaryLayerCells = value
+	})
+	heightInput.SetPosition(100, top+boundaryLayerPlotH+50)
+	dialog.Add(heightInput)
+
+	exportBtn := gui.NewButton("Export CSV")
+	exportBtn.SetPosition(10, 335)
+	exportBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path := boundaryLayerFileName()
+		if err := exportBoundaryLayer(path, p); err != nil {
+			overlays.Notify("Could not export profile: " + err.Error())
+			return
+		}
+		overlays.Notify("Profile exported to " + path)
+	})
+	dialog.Add(exportBtn)
+
+	// the flow and the line's length may have changed since
+	resampleBtn := gui.NewButton("Resample")
+	resampleBtn.SetPosition(100, 335)
+	resampleBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		p := sampleBoundaryLayer(&vectorField, p.Base, p.Normal, boundaryLayerCells*vectorField.CellSize())
+		showBoundaryLayerLine(p)
+		showBoundaryLayerDialog(p)
+	})
+	dialog.Add(resampleBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(300, 335)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		removeBoundaryLayerLine()
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeBoundaryLayerUI(scene *core.Node, cam camera.ICamera) {
+	waitingForPlacement := false
+	placeBtn := gui.NewButton("Boundary Layer...")
+	placeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if !surfaceForcesAvailable(mesh) {
+			overlays.Notify("The boundary layer needs a model in the grid solver")
+			return
+		}
+		waitingForPlacement = true
+		overlays.Notify("Click on the model to place the line")
+	})
+	addSidebarWidget(scene, placeBtn)
+
+	app.App().Subscribe(window.OnMouseDown, func(evname string, ev interface{}) {
+		if !waitingForPlacement || overlays.Blocking() {
+			return
+		}
+		mev := ev.(*window.MouseEvent)
+		if mev.Button != window.MouseButtonLeft {
+			return
+		}
+		point, normal, ok := pickModelPoint(cam, mesh, mev.Xpos, mev.Ypos)
+		if !ok {
+			return
+		}
+		waitingForPlacement = false
+		p := sampleBoundaryLayer(&vectorField, point, normal, boundaryLayerCells*vectorField.CellSize())
+		showBoundaryLayerLine(p)
+		showBoundaryLayerDialog(p)
+	})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+)
+
+// A bundle is a zip holding everything needed to reproduce a scenario on another machine:
+//
+//	scene.json     the scene, with the model path pointing inside the bundle
+//	settings.json  render, clip, filter and recording settings
+//	model/         the model file and its material and texture files
+//	model/<n>/     the same for each context model
+//	thumbnail.png  a picture of the scene when it was exported
+const (
+	bundleSceneFile     = "scene.json"
+	bundleSettingsFile  = "settings.json"
+	bundleModelDir      = "model"
+	bundleThumbnailFile = "thumbnail.png"
+	bundleThumbnailSize = 256
+)
+
+// bundleImportDir is where imported bundles are unpacked, one directory per bundle
+const bundleImportDir = "bundles"
+
+// BundleSettings are the settings carried along with the scene in a bundle
+type BundleSettings struct {
+	Render          RenderSettings
+	Clip            ClipRegion
+	Filter          ParticleFilter
+	RecordingLimits RecordingLimits
+}
+
+// textureExts are the image files next to a model that are bundled with it
+var textureExts = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".tga": true, ".bmp": true}
+
+// modelFiles returns the model file together with the sibling files it may reference:
+// files sharing its base name (like the .mtl of an .obj) and textures
+func modelFiles(modelPath string) ([]string, error) {
+	dir := filepath.Dir(modelPath)
+	base := strings.TrimSuffix(filepath.Base(modelPath), filepath.Ext(modelPath))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{modelPath}
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(dir, name)
+		if e.IsDir() || path == filepath.Clean(modelPath) {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		if strings.TrimSuffix(name, filepath.Ext(name)) == base || textureExts[ext] {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// exportBundle writes the current scene, settings, model and thumbnail into a zip at path
+func exportBundle(path string, ml *ModelLoader, thumbnail *image.RGBA) error {
+	sf := currentSceneFile(ml)
+	var files []string
+	if ml.path != "" {
+		var err error
+		if files, err = modelFiles(ml.path); err != nil {
+			return fmt.Errorf("collecting model files: %w", err)
+		}
+		sf.ModelPath = bundleModelDir + "/" + filepath.Base(ml.path)
+	}
+	// context models go in numbered directories so files with the same name don't clash
+	var entries [][2]string // file on disk, name in the zip
+	for _, f := range files {
+		entries = append(entries, [2]string{f, bundleModelDir + "/" + filepath.Base(f)})
+	}
+	sf.ContextModels = nil
+	for i, m := range contextModels {
+		dir := fmt.Sprintf("%s/%d", bundleModelDir, i+1)
+		siblings, err := modelFiles(m.Path)
+		if err != nil {
+			return fmt.Errorf("collecting context model files: %w", err)
+		}
+		for _, f := range siblings {
+			entries = append(entries, [2]string{f, dir + "/" + filepath.Base(f)})
+		}
+		bundled := *m
+		bundled.Path = dir + "/" + filepath.Base(m.Path)
+		sf.ContextModels = append(sf.ContextModels, &bundled)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating bundle: %w", err)
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+
+	writeJSON := func(name string, v interface{}) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	if err := writeJSON(bundleSceneFile, sf); err != nil {
+		return fmt.Errorf("writing bundle scene: %w", err)
+	}
+	settings := BundleSettings{
+		Render:          renderSettings,
+		Clip:            clipRegion,
+		Filter:          particleFilter,
+		RecordingLimits: recordingLimits,
+	}
+	if err := writeJSON(bundleSettingsFile, settings); err != nil {
+		return fmt.Errorf("writing bundle settings: %w", err)
+	}
+
+	for _, e := range entries {
+		if err := addFileToZip(zw, e[0], e[1]); err != nil {
+			return fmt.Errorf("adding %s: %w", e[0], err)
+		}
+	}
+
+	if thumbnail != nil {
+		w, err := zw.Create(bundleThumbnailFile)
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(w, scaleImage(thumbnail, bundleThumbnailSize)); err != nil {
+			return fmt.Errorf("writing thumbnail: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finishing bundle: %w", err)
+	}
+	log.Printf("Bundle exported to %s (%d model files)", path, len(entries))
+	return nil
+}
+
+func addFileToZip(zw *zip.Writer, path, name string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// importBundle unpacks the bundle at path under bundleImportDir, applies its settings
+// and loads its scene
+func importBundle(path string, scene *core.Node, ml *ModelLoader) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("opening bundle: %w", err)
+	}
+	defer zr.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dir := filepath.Join(bundleImportDir, name)
+	for _, f := range zr.File {
+		// refuse entries that would land outside the bundle directory
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("bundle entry %q escapes the bundle directory", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return fmt.Errorf("extracting %s: %w", f.Name, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, bundleSettingsFile))
+	if err == nil {
+		var settings BundleSettings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("parsing bundle settings: %w", err)
+		}
+		renderSettings = settings.Render
+		clipRegion = settings.Clip
+		particleFilter = settings.Filter
+		recordingLimits = settings.RecordingLimits
+		applyRenderSettings()
+		// also rebuilds the fluid particles with the imported detail
+		applyParticleViews()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("reading bundle settings: %w", err)
+	}
+
+	if err := loadScene(filepath.Join(dir, bundleSceneFile), scene, ml); err != nil {
+		return err
+	}
+	log.Printf("Bundle imported from %s into %s", path, dir)
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+func initializeBundleUI(scene *core.Node, ml *ModelLoader) {
+	exportBtn := gui.NewButton("Export Bundle")
+	exportBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path := fmt.Sprintf("bundle_%d.zip", time.Now().UnixNano())
+		// wait for the next frame so the thumbnail shows the scene, not a stale buffer
+		requestFrameCapture(func(img *image.RGBA) {
+			if err := exportBundle(path, ml, img); err != nil {
+				log.Println("Error exporting bundle:", err)
+				overlays.Notify("Could not export bundle: " + err.Error())
+				return
+			}
+			overlays.Notify("Bundle exported to " + path)
+		})
+	})
+	addSidebarWidget(scene, exportBtn)
+
+	importBtn := gui.NewButton("Import Bundle")
+	importBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path, err := chooseFile("Select a scenario bundle", "Bundles", "zip")
+		if err != nil || path == "" {
+			log.Println("No bundle selected or error:", err)
+			return
+		}
+		if err := importBundle(path, scene, ml); err != nil {
+			log.Println("Error importing bundle:", err)
+			overlays.Notify("Could not import bundle: " + err.Error())
+			return
+		}
+		overlays.Notify("Bundle imported from " + filepath.Base(path))
+	})
+	addSidebarWidget(scene, importBtn)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// A bounding-volume hierarchy over a model's triangles answers the questions the
+// distance field of the model is built from in about log T tests instead of T. Whether
+// a point is inside is decided by casting a ray from it and counting the triangles it
+// crosses, odd being inside, which is only right for closed meshes, as exported solids
+// are.
+
+// bvhLeafSize is the most triangles kept at a leaf
+const bvhLeafSize = 4
+
+// bvhNode is a box around some of the triangles; a leaf lists them, an inner node has
+// its two children next to each other starting at child
+type bvhNode struct {
+	box          math32.Box3
+	child        int32
+	start, count int32
+}
+
+// BVH is a bounding-volume hierarchy over triangles in a model's own coordinate frame
+type BVH struct {
+	nodes []bvhNode
+	tris  [][3]math32.Vector3
+}
+
+// buildBVH returns the hierarchy over tris, which it reorders; nil without triangles
+func buildBVH(tris [][3]math32.Vector3) *BVH {
+	if len(tris) == 0 {
+		return nil
+	}
+	b := &BVH{tris: tris}
+	b.nodes = append(b.nodes, bvhNode{})
+	b.split(0, 0, len(tris))
+	return b
+}
+
+// split makes node the box over tris[start:end] and divides them at the median of
+// their centroids along the longest side
+func (b *BVH) split(node, start, end int) {
+	box := math32.NewBox3(nil, nil).MakeEmpty()
+	centers := math32.NewBox3(nil, nil).MakeEmpty()
+	for _, tri := range b.tris[start:end] {
+		box.ExpandByPoint(&tri[0]).ExpandByPoint(&tri[1]).ExpandByPoint(&tri[2])
+		centers.ExpandByPoint(triangleCenter(tri))
+	}
+	b.nodes[node].box = *box
+	if end-start <= bvhLeafSize {
+		b.nodes[node].start, b.nodes[node].count = int32(start), int32(end-start)
+		return
+	}
+
+	size := boxSize(centers)
+	axis := 0
+	if size.Y > size.Component(axis) {
+		axis = 1
+	}
+	if size.Z > size.Component(axis) {
+		axis = 2
+	}
+	part := b.tris[start:end]
+	sort.Slice(part, func(i, j int) bool {
+		return triangleCenter(part[i]).Component(axis) < triangleCenter(part[j]).Component(axis)
+	})
+
+	child := len(b.nodes)
+	b.nodes[node].child = int32(child)
+	b.nodes = append(b.nodes, bvhNode{}, bvhNode{})
+	mid := (start + end) / 2
+	b.split(child, start, mid)
+	b.split(child+1, mid, end)
+}
+
+func triangleCenter(tri [3]math32.Vector3) *math32.Vector3 {
+	return tri[0].Clone().Add(&tri[1]).Add(&tri[2]).DivideScalar(3)
+}
+
+// crossings counts the triangles the ray from origin along dir passes through
+func (b *BVH) crossings(origin, dir math32.Vector3) int {
+	inv := math32.Vector3{X: 1 / dir.X, Y: 1 / dir.Y, Z: 1 / dir.Z}
+	count := 0
+	stack := []int32{0}
+	for len(stack) > 0 {
+		n := &b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if !rayHitsBox(origin, inv, &n.box) {
+			continue
+		}
+		if n.count > 0 {
+			for _, tri := range b.tris[n.start : n.start+n.count] {
+				if rayHitsTriangle(origin, dir, tri) {
+					count++
+				}
+			}
+			continue
+		}
+		stack = append(stack, n.child, n.child+1)
+	}
+	return count
+}
+
+// Contains reports whether p lies inside the closed surface of the triangles
+func (b *BVH) Contains(p math32.Vector3) bool {
+	if !b.nodes[0].box.ContainsPoint(&p) {
+		return false
+	}
+	// a skewed direction rarely runs exactly along an edge of an axis aligned model
+	dir := math32.Vector3{X: 0.8721, Y: 0.3512, Z: 0.3406}
+	return b.crossings(p, dir)%2 == 1
+}
+
+// rayHitsBox is the slab test against box for a ray with direction 1/inv
+func rayHitsBox(origin, inv math32.Vector3, box *math32.Box3) bool {
+	tmin, tmax := float32(0), float32(math32.Infinity)
+	for a := 0; a < 3; a++ {
+		t1 := (box.Min.Component(a) - origin.Component(a)) * inv.Component(a)
+		t2 := (box.Max.Component(a) - origin.Component(a)) * inv.Component(a)
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin = math32.Max(tmin, t1)
+		tmax = math32.Min(tmax, t2)
+		if tmin > tmax {
+			return false
+		}
+	}
+	return true
+}
+
+// rayHitsTriangle reports whether the ray hits tri in front of origin
+func rayHitsTriangle(origin, dir math32.Vector3, tri [3]math32.Vector3) bool {
+	_, hit := rayTriangleHit(origin, dir, tri)
+	return hit
+}
+
+// rayTriangleHit is the Möller-Trumbore test for a hit in front of origin, returning
+// the distance to it in lengths of dir
+func rayTriangleHit(origin, dir math32.Vector3, tri [3]math32.Vector3) (float32, bool) {
+	const eps = 1e-7
+	edge1 := tri[1].Clone().Sub(&tri[0])
+	edge2 := tri[2].Clone().Sub(&tri[0])
+	p := dir.Clone().Cross(edge2)
+	det := edge1.Dot(p)
+	if math32.Abs(det) < eps {
+		return 0, false
+	}
+	s := origin.Clone().Sub(&tri[0])
+	u := s.Dot(p) / det
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := s.Cross(edge1)
+	v := dir.Dot(q) / det
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+	t := edge2.Dot(q) / det
+	return t, t > eps
+}
+
+// modelBVHs are the hierarchies of the loaded models, over their full triangles
+var modelBVHs = map[*core.Node]*BVH{}
+
+// buildModelBVH builds the hierarchy of a newly loaded model
+func buildModelBVH(model *core.Node) {
+	if bvh := buildBVH(localTriangles(model)); bvh != nil {
+		modelBVHs[model] = bvh
+		log.Printf("BVH: %d triangles in %d nodes", len(bvh.tris), len(bvh.nodes))
+	}
+}
+
+// forgetModel drops the hierarchy and the distance field of a removed model
+func forgetModel(model *core.Node) {
+	delete(modelBVHs, model)
+	delete(modelSDFs, model)
+}
+
+// modelBVH returns the hierarchy over model's collision triangles, those of its
+// simplified collision mesh when it has one, nil when it has none
+func modelBVH(model *core.Node) *BVH {
+	if collisionMesh != nil && collisionMesh.model == model && collisionMesh.bvh != nil {
+		return collisionMesh.bvh
+	}
+	return modelBVHs[model]
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/gls"
+)
+
+// frameRequests are served with the next rendered frame; reading the framebuffer
+// from an event handler would return whatever was left in the back buffer
+var frameRequests []func(img *image.RGBA)
+
+// requestFrameCapture calls done with a copy of the next rendered frame
+func requestFrameCapture(done func(img *image.RGBA)) {
+	frameRequests = append(frameRequests, done)
+}
+
+// captureRequestedFrame is called right after rendering the scene
+func captureRequestedFrame() {
+	// a minimized window has nothing to read, the requests wait for it to come back
+	if len(frameRequests) == 0 || windowMinimized() {
+		return
+	}
+	img := readFrame()
+	requests := frameRequests
+	frameRequests = nil
+	for _, done := range requests {
+		done(img)
+	}
+}
+
+// readFrame copies the framebuffer into an image, flipping it so row 0 is the top
+func readFrame() *image.RGBA {
+	w, h := app.App().GetFramebufferSize()
+	pixels := app.App().Gls().ReadPixels(0, 0, w, h, gls.RGBA, gls.UNSIGNED_BYTE)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	stride := w * 4
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*img.Stride:y*img.Stride+stride], pixels[(h-1-y)*stride:(h-y)*stride])
+	}
+	return img
+}
+
+// scaleImage returns a nearest-neighbour copy of img that is width pixels wide
+func scaleImage(img *image.RGBA, width int) *image.RGBA {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return img
+	}
+	height := b.Dy() * width / b.Dx()
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out.SetRGBA(x, y, img.RGBAAt(b.Min.X+x*b.Dx()/width, b.Min.Y+y*b.Dy()/height))
+		}
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// ClipRegion limits which particles are drawn to those inside (or outside) an axis-aligned box.
+// Clipping only affects visibility, particles keep being simulated and recorded.
+type ClipRegion struct {
+	Enabled bool
+	Invert  bool // draw only particles outside the box
+	Center  math32.Vector3
+	Size    math32.Vector3
+}
+
+var clipRegion = ClipRegion{
+	Center: *math32.NewVector3(0, 2.5, 0),
+	Size:   *math32.NewVector3(6, 5, 6),
+}
+
+var clipBoxMesh *graphic.Mesh
+
+// Visible reports whether a particle at pos should be drawn
+func (c *ClipRegion) Visible(pos math32.Vector3) bool {
+	if !c.Enabled {
+		return true
+	}
+	inside := math32.Abs(pos.X-c.Center.X) <= c.Size.X/2 &&
+		math32.Abs(pos.Y-c.Center.Y) <= c.Size.Y/2 &&
+		math32.Abs(pos.Z-c.Center.Z) <= c.Size.Z/2
+	return inside != c.Invert
+}
+
+// updateClipBox shows the outline of the clip box while clipping is enabled
+func updateClipBox(scene *core.Node) {
+	if !clipRegion.Enabled {
+		if clipBoxMesh != nil {
+			clipBoxMesh.SetVisible(false)
+		}
+		return
+	}
+	if clipBoxMesh == nil {
+		boxMat := material.NewStandard(math32.NewColor("White"))
+		boxMat.SetWireframe(true)
+		clipBoxMesh = graphic.NewMesh(geometry.NewBox(1, 1, 1), boxMat)
+		objects.Add(clipBoxMesh, "clip box")
+	}
+	clipBoxMesh.SetPositionVec(&clipRegion.Center)
+	clipBoxMesh.SetScaleVec(&clipRegion.Size)
+	clipBoxMesh.SetVisible(true)
+}
+
+func initializeClipUI(scene *core.Node) {
+	panel := gui.NewPanel(240, 150)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+
+	title := gui.NewLabel("Clip box")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	enableCheck := gui.NewCheckBox("Enabled")
+	enableCheck.SetPosition(10, 25)
+	enableCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		clipRegion.Enabled = enableCheck.Value()
+	})
+	panel.Add(enableCheck)
+
+	invertCheck := gui.NewCheckBox("Show outside")
+	invertCheck.SetPosition(110, 25)
+	invertCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		clipRegion.Invert = invertCheck.Value()
+	})
+	panel.Add(invertCheck)
+
+	centerLabel := gui.NewLabel("Center")
+	centerLabel.SetPosition(10, 50)
+	panel.Add(centerLabel)
+	sizeLabel := gui.NewLabel("Size")
+	sizeLabel.SetPosition(125, 50)
+	panel.Add(sizeLabel)
+
+	axes := []struct {
+		center *float32
+		size   *float32
+	}{
+		{&clipRegion.Center.X, &clipRegion.Size.X},
+		{&clipRegion.Center.Y, &clipRegion.Size.Y},
+		{&clipRegion.Center.Z, &clipRegion.Size.Z},
+	}
+	for i, axis := range axes {
+		center, size := axis.center, axis.size
+		y := 70 + float32(i)*25
+		centerInput := NewNumericInput(*center, -20, 20, 0.5, "m", func(value float32) {
+			*center = value
+		})
+		centerInput.SetPosition(10, y)
+		panel.Add(centerInput)
+		sizeInput := NewNumericInput(*size, 0.1, 40, 0.5, "m", func(value float32) {
+			*size = value
+		})
+		sizeInput.SetPosition(125, y)
+		panel.Add(sizeInput)
+	}
+
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/g3n/engine/math32"
+)
+
+// AeroCoefficients are the loads on the model made dimensionless with the dynamic
+// pressure q = rho V²/2 of the freestream, the reference area S and chord c:
+// Cd = D/(q S), Cl = L/(q S) and Cm = M/(q S c) for the pitching moment about the
+// model's position, nose up positive
+type AeroCoefficients struct {
+	Cd, Cl, Cm float32
+}
+
+// lastCoefficients are those of the last physics update
+var lastCoefficients AeroCoefficients
+
+// freestreamSpeed returns the speed in m/s the coefficients refer to: the configured
+// one, else the ambient wind at the height of pos, else the fastest wind source
+func freestreamSpeed(pos math32.Vector3) float32 {
+	if simConfig.FreestreamSpeed > 0 {
+		return simConfig.FreestreamSpeed
+	}
+	if ambientWind.Enabled {
+		return ambientWind.SpeedAt(pos.Y * simConfig.LengthUnit)
+	}
+	var fastest float32
+	for _, w := range windSources {
+		fastest = math32.Max(fastest, w.Speed)
+	}
+	return fastest
+}
+
+// aeroCoefficients converts drag and lift in N and the moment in N m about the origin
+// into coefficients, for a model at pos in a wind along windDir. They are zero while
+// there is no wind to refer to.
+func aeroCoefficients(drag, lift float32, force, moment, pos, windDir math32.Vector3) AeroCoefficients {
+	speed := freestreamSpeed(pos)
+	qs := 0.5 * simConfig.AirDensity * speed * speed * simConfig.ReferenceArea
+	if qs <= 0 {
+		return AeroCoefficients{}
+	}
+	// move the moment to the model, M_pos = M_origin - r x F
+	arm := pos.Clone().MultiplyScalar(simConfig.LengthUnit)
+	local := moment.Clone().Sub(arm.Cross(&force))
+	// the pitch axis lies across the wind, up x wind turns a nose facing the wind upwards
+	pitchAxis := math32.NewVector3(0, 1, 0).Cross(&windDir)
+	return AeroCoefficients{
+		Cd: drag / qs,
+		Cl: lift / qs,
+		Cm: local.Dot(pitchAxis) / (qs * simConfig.ReferenceChord),
+	}
+}
+
+// reynoldsNumber returns Re = V L / nu of the model: the freestream speed, the reference
+// chord and the configured viscosity. Zero without viscosity.
+func reynoldsNumber() float32 {
+	if simConfig.Viscosity <= 0 {
+		return 0
+	}
+	var pos math32.Vector3
+	if mesh != nil {
+		pos = mesh.Position()
+	}
+	return freestreamSpeed(pos) * simConfig.ReferenceChord / simConfig.Viscosity
+}
+
+func formatReynolds(re float32) string {
+	return strconv.FormatFloat(float64(re), 'g', 3, 32)
+}
+
+// matchReynolds keeps the Reynolds number when the model is made larger or smaller in
+// the physics dialog, by scaling the wind speeds the other way
+var matchReynolds bool
+
+// scaleWindSpeeds multiplies the speed of the wind sources, the ambient wind and the
+// configured freestream by k. Speeds set by expressions follow their expressions.
+func scaleWindSpeeds(k float32) {
+	for i := range windSources {
+		windSources[i].Speed *= k
+		if i < len(windSpeedInputs) {
+			windSpeedInputs[i].SetValue(windSources[i].Speed)
+		}
+	}
+	ambientWind.Speed *= k
+	simConfig.FreestreamSpeed *= k
+	log.Printf("Wind speeds scaled by %.3g to keep the Reynolds number", k)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// CollisionMesh is a simplified copy of the model used by the physics instead of the render
+// mesh. Triangles are stored in the model's local space so it follows the model around.
+type CollisionMesh struct {
+	model     *core.Node
+	full      int // triangle count of the render mesh
+	Triangles [][3]math32.Vector3
+	preview   *graphic.Mesh
+	bvh       *BVH // over a copy of Triangles, the hierarchy reorders them
+}
+
+var collisionMesh *CollisionMesh
+
+// collisionBudget is the fraction of the render mesh triangles kept, 1 uses the full mesh
+var collisionBudget float32 = 1
+
+var showCollisionPreview bool
+
+// localTriangles returns the model's triangles in its own coordinate frame
+func localTriangles(model *core.Node) [][3]math32.Vector3 {
+	model.UpdateMatrixWorld()
+	world := model.MatrixWorld()
+	var inverse math32.Matrix4
+	if err := inverse.GetInverse(&world); err != nil {
+		return nil
+	}
+	var tris [][3]math32.Vector3
+	forEachWorldTriangle(model, func(a, b, c math32.Vector3) {
+		a.ApplyMatrix4(&inverse)
+		b.ApplyMatrix4(&inverse)
+		c.ApplyMatrix4(&inverse)
+		tris = append(tris, [3]math32.Vector3{a, b, c})
+	})
+	return tris
+}
+
+// clusterDecimate simplifies tris by snapping every vertex to the average of the vertices in
+// its cell of a cells^3 grid over the bounding box and dropping the triangles that collapse
+func clusterDecimate(tris [][3]math32.Vector3, cells int) [][3]math32.Vector3 {
+	box := math32.NewBox3(nil, nil).MakeEmpty()
+	for i := range tris {
+		for v := range tris[i] {
+			box.ExpandByPoint(&tris[i][v])
+		}
+	}
+	size := boxSize(box)
+	cell := math32.Max(size.X, math32.Max(size.Y, size.Z)) / float32(cells)
+	if cell == 0 {
+		return tris
+	}
+
+	type cluster struct {
+		sum   math32.Vector3
+		count int
+	}
+	clusters := make(map[[3]int]*cluster)
+	keyOf := func(p math32.Vector3) [3]int {
+		return [3]int{
+			int((p.X - box.Min.X) / cell),
+			int((p.Y - box.Min.Y) / cell),
+			int((p.Z - box.Min.Z) / cell),
+		}
+	}
+	for i := range tris {
+		for _, p := range tris[i] {
+			k := keyOf(p)
+			c, ok := clusters[k]
+			if !ok {
+				c = &cluster{}
+				clusters[k] = c
+			}
+			c.sum.Add(&p)
+			c.count++
+		}
+	}
+
+	seen := make(map[[3][3]int]bool)
+	var out [][3]math32.Vector3
+	for i := range tris {
+		keys := [3][3]int{keyOf(tris[i][0]), keyOf(tris[i][1]), keyOf(tris[i][2])}
+		if keys[0] == keys[1] || keys[1] == keys[2] || keys[0] == keys[2] {
+			continue
+		}
+		if seen[keys] {
+			continue
+		}
+		seen[keys] = true
+		var tri [3]math32.Vector3
+		for v, k := range keys {
+			c := clusters[k]
+			tri[v] = *c.sum.Clone().DivideScalar(float32(c.count))
+		}
+		out = append(out, tri)
+	}
+	return out
+}
+
+// decimateToBudget returns the finest clustering of tris with at most budget triangles
+func decimateToBudget(tris [][3]math32.Vector3, budget int) [][3]math32.Vector3 {
+	if budget >= len(tris) {
+		return tris
+	}
+	best := clusterDecimate(tris, 1)
+	lo, hi := 2, 256
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		candidate := clusterDecimate(tris, mid)
+		if len(candidate) <= budget {
+			best = candidate
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best
+}
+
+// rebuildCollisionMesh simplifies the current model to the collision budget
+func rebuildCollisionMesh(scene *core.Node) {
+	removeCollisionPreview()
+	collisionMesh = nil
+	if mesh == nil {
+		return
+	}
+	tris := localTriangles(mesh)
+	budget := int(collisionBudget * float32(len(tris)))
+	if budget < 4 {
+		budget = 4
+	}
+	collisionMesh = &CollisionMesh{
+		model:     mesh,
+		full:      len(tris),
+		Triangles: decimateToBudget(tris, budget),
+	}
+	collisionMesh.bvh = buildBVH(append([][3]math32.Vector3(nil), collisionMesh.Triangles...))
+	log.Printf("Collision mesh: %d of %d triangles", len(collisionMesh.Triangles), len(tris))
+}
+
+// forEachCollisionTriangle calls cb with the world space triangles of the model's collision
+// mesh, or of the model itself when no simplified mesh was built for it
+func forEachCollisionTriangle(model *core.Node, cb func(a, b, c math32.Vector3)) {
+	for _, tri := range cachedTriangles(model) {
+		cb(tri[0], tri[1], tri[2])
+	}
+}
+
+// collisionCost returns the collision and render triangle counts and an estimate of the
+// box and triangle tests per frame with the collision mesh
+func collisionCost() (int, int, int) {
+	tris, full := 0, 0
+	if collisionMesh != nil {
+		tris, full = len(collisionMesh.Triangles), collisionMesh.full
+	} else if mesh != nil {
+		forEachWorldTriangle(mesh, func(a, b, c math32.Vector3) { full++ })
+		tris = full
+	}
+	return tris, full, bvhQueryCost(tris) * (len(fluidParticles) + windParticles.Len())
+}
+
+// bvhQueryCost estimates the tests a point query on the hierarchy over tris triangles
+// makes: a box per level down to a leaf, then the triangles of the leaf
+func bvhQueryCost(tris int) int {
+	if tris == 0 {
+		return 0
+	}
+	depth := 0
+	for n := tris; n > bvhLeafSize; n = (n + 1) / 2 {
+		depth++
+	}
+	return depth + 1 + bvhLeafSize
+}
+
+func removeCollisionPreview() {
+	if collisionMesh != nil && collisionMesh.preview != nil {
+		objects.Remove(collisionMesh.preview)
+		collisionMesh.preview = nil
+	}
+}
+
+// updateCollisionPreview draws the collision mesh as a wireframe over the model
+func updateCollisionPreview(scene *core.Node) {
+	if collisionMesh != nil && collisionMesh.model != mesh {
+		// the model was replaced, its collision mesh no longer applies
+		removeCollisionPreview()
+		collisionMesh = nil
+	}
+	if collisionMesh == nil || !showCollisionPreview {
+		removeCollisionPreview()
+		return
+	}
+	if collisionMesh.preview == nil {
+		positions := math32.NewArrayF32(0, len(collisionMesh.Triangles)*9)
+		for i := range collisionMesh.Triangles {
+			tri := &collisionMesh.Triangles[i]
+			positions.AppendVector3(&tri[0], &tri[1], &tri[2])
+		}
+		geom := geometry.NewGeometry()
+		geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+		mat := material.NewStandard(math32.NewColor("Yellow"))
+		mat.SetWireframe(true)
+		collisionMesh.preview = graphic.NewMesh(geom, mat)
+		objects.Add(collisionMesh.preview, "collision preview")
+	}
+	// the model is a direct child of the scene, so copying its transform lines them up
+	pos := mesh.Position()
+	collisionMesh.preview.SetPositionVec(&pos)
+	q := mesh.Quaternion()
+	collisionMesh.preview.SetQuaternionQuat(&q)
+	s := mesh.Scale()
+	collisionMesh.preview.SetScaleVec(&s)
+}
+
+func initializeCollisionMeshUI(scene *core.Node) {
+	panel := gui.NewPanel(200, 95)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+
+	title := gui.NewLabel("Collision mesh")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	costLabel := gui.NewLabel("")
+	costLabel.SetPosition(10, 70)
+	panel.Add(costLabel)
+	refreshCost := func() {
+		tris, full, tests := collisionCost()
+		costLabel.SetText(fmt.Sprintf("%d/%d tris, ~%dk BVH tests", tris, full, tests/1000))
+	}
+
+	slider := gui.NewHSlider(180, 20)
+	slider.SetPosition(10, 25)
+	slider.SetValue(collisionBudget)
+	slider.SetText(fmt.Sprintf("%.0f%%", collisionBudget*100))
+	slider.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		collisionBudget = math32.Max(slider.Value(), 0.01)
+		slider.SetText(fmt.Sprintf("%.0f%%", collisionBudget*100))
+		requestFieldUpdate("collision mesh", func() {
+			rebuildCollisionMesh(scene)
+			refreshCost()
+		})
+	})
+	panel.Add(slider)
+
+	previewCheck := gui.NewCheckBox("Wireframe preview")
+	previewCheck.SetPosition(10, 50)
+	previewCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		showCollisionPreview = previewCheck.Value()
+		if showCollisionPreview && collisionMesh == nil {
+			rebuildCollisionMesh(scene)
+		}
+		refreshCost()
+	})
+	panel.Add(previewCheck)
+
+	refreshCost()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// ContextModel is an extra model placed around the main one, like trees, people or
+// furniture for scale. Obstacles block the flow and the wind particles like the main
+// model does; decorative ones are only drawn and cost the physics nothing.
+type ContextModel struct {
+	Path       string
+	Position   math32.Vector3
+	Decorative bool
+
+	node *core.Node
+}
+
+var contextModels []*ContextModel
+
+// modelDecorative makes the main model decorative too: it is drawn, but not pushed
+// around by the wind and not seen by the flow
+var modelDecorative bool
+
+// contextModelSpacing is how far apart newly added context models are placed
+const contextModelSpacing = 3
+
+// obstacleModels returns the models the physics has to take into account
+func obstacleModels() []*core.Node {
+	var obstacles []*core.Node
+	if mesh != nil && !modelDecorative {
+		obstacles = append(obstacles, mesh)
+	}
+	for _, m := range contextModels {
+		if !m.Decorative && m.node != nil {
+			obstacles = append(obstacles, m.node)
+		}
+	}
+	obstacles = append(obstacles, scenarioObstacles...)
+	if validation.cylinder != nil {
+		obstacles = append(obstacles, validation.cylinder)
+	}
+	return obstacles
+}
+
+// addContextModel loads m.Path into the scene at m.Position
+func addContextModel(scene *core.Node, m *ContextModel) error {
+	loader := &ModelLoader{scene: scene}
+	if err := loader.LoadModel(m.Path); err != nil {
+		return err
+	}
+	if len(loader.models) == 0 {
+		return fmt.Errorf("no model in %s", m.Path)
+	}
+	m.node = loader.models[0]
+	m.node.SetPositionVec(&m.Position)
+	contextModels = append(contextModels, m)
+	log.Printf("Context model %s added at %v (decorative: %v)", m.Path, m.Position, m.Decorative)
+	return nil
+}
+
+func removeContextModel(m *ContextModel) {
+	for i, other := range contextModels {
+		if other == m {
+			contextModels = append(contextModels[:i], contextModels[i+1:]...)
+			break
+		}
+	}
+	objects.Remove(m.node)
+	m.node = nil
+}
+
+func clearContextModels() {
+	for _, m := range contextModels {
+		objects.Remove(m.node)
+	}
+	contextModels = nil
+}
+
+// syncContextModels copies the node positions back into the models before saving
+func syncContextModels() {
+	for _, m := range contextModels {
+		if m.node != nil {
+			m.Position = m.node.Position()
+		}
+	}
+}
+
+// showModelsDialog lists the main model and the context models with whether each one is
+// an obstacle or only decorative
+func showModelsDialog(scene *core.Node) {
+	dialog := gui.NewPanel(360, 300)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Models (unchecked ones are decorative)")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	list := gui.NewVList(340, 220)
+	list.SetPosition(10, 35)
+	dialog.Add(list)
+
+	var refresh func()
+	refresh = func() {
+		list.Clear()
+		if mesh != nil {
+			check := gui.NewCheckBox("Main model")
+			check.SetValue(!modelDecorative)
+			check.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+				modelDecorative = !check.Value()
+			})
+			list.Add(check)
+		}
+		for _, m := range contextModels {
+			m := m
+			row := gui.NewPanel(320, 22)
+			check := gui.NewCheckBox(filepath.Base(m.Path))
+			check.SetValue(!m.Decorative)
+			check.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+				m.Decorative = !check.Value()
+			})
+			row.Add(check)
+			removeBtn := gui.NewButton("Remove")
+			removeBtn.SetPosition(250, 0)
+			removeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+				removeContextModel(m)
+				refresh()
+			})
+			row.Add(removeBtn)
+			list.Add(row)
+		}
+	}
+	refresh()
+
+	addBtn := gui.NewButton("Add...")
+	addBtn.SetPosition(10, 265)
+	addBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path, err := openFileDialog()
+		if err != nil || path == "" {
+			log.Println("No model selected or error:", err)
+			return
+		}
+		// line new models up beside the main one, decorative until marked otherwise
+		m := &ContextModel{
+			Path:       path,
+			Position:   math32.Vector3{X: contextModelSpacing * float32(len(contextModels)+1), Y: 0, Z: 0},
+			Decorative: true,
+		}
+		if err := addContextModel(scene, m); err != nil {
+			log.Println("Error loading context model:", err)
+			overlays.Notify("Could not load model: " + err.Error())
+			return
+		}
+		refresh()
+	})
+	dialog.Add(addBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(300, 265)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeContextModelsUI(scene *core.Node) {
+	modelsBtn := gui.NewButton("Models...")
+	modelsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showModelsDialog(scene)
+	})
+	addSidebarWidget(scene, modelsBtn)
+}
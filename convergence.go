@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// ConvergenceMonitor decides when a run has reached a steady state. It keeps the last two
+// windows of drag, lift and flow change samples; the run is converged when the drag and
+// lift averages of the two windows agree within the tolerance and the flow changes by less
+// than the tolerance per second.
+type ConvergenceMonitor struct {
+	Window    int     // samples per moving average window
+	Tolerance float32 // relative, e.g. 0.02 for 2%
+	AutoStop  bool    // stop recording once converged
+
+	drag, lift, change, dt []float32 // the last 2*Window samples, oldest first
+	converged              bool
+}
+
+var convergence = ConvergenceMonitor{
+	Window:    120,
+	Tolerance: 0.02,
+}
+
+// loadFloor keeps tiny loads from never looking converged because of noise around zero
+const loadFloor = 0.01
+
+// convergenceLabel shows the monitor state, nil until the UI is built
+var convergenceLabel *gui.Label
+
+// Reset forgets the samples, e.g. when the setup changed
+func (m *ConvergenceMonitor) Reset() {
+	m.drag, m.lift, m.change, m.dt = nil, nil, nil, nil
+	m.converged = false
+	m.refreshLabel()
+}
+
+// Converged reports whether the last sample completed a converged pair of windows
+func (m *ConvergenceMonitor) Converged() bool {
+	return m.converged
+}
+
+// Sample adds the loads and flow change of one physics step taking dt seconds
+func (m *ConvergenceMonitor) Sample(drag, lift, change, dt float32) {
+	if change <= 0 {
+		// the field is at rest, there is nothing to converge yet
+		return
+	}
+	keep := 2 * m.Window
+	push := func(series []float32, v float32) []float32 {
+		series = append(series, v)
+		if len(series) > keep {
+			series = series[len(series)-keep:]
+		}
+		return series
+	}
+	m.drag = push(m.drag, drag)
+	m.lift = push(m.lift, lift)
+	m.change = push(m.change, change)
+	m.dt = push(m.dt, dt)
+
+	was := m.converged
+	m.converged = len(m.drag) == keep &&
+		m.settled(m.drag) && m.settled(m.lift) && mean(m.change[m.Window:]) < m.Tolerance
+	if m.converged && !was {
+		log.Printf("Run converged: %s", m.windowDescription())
+		overlays.Notify("Run converged, " + m.windowDescription())
+		if m.AutoStop && !recordingStopped {
+			recordingStopped = true
+			log.Println("Recording stopped at convergence")
+		}
+	}
+	m.refreshLabel()
+}
+
+// settled compares the averages of the older and the newer window of series
+func (m *ConvergenceMonitor) settled(series []float32) bool {
+	older, newer := mean(series[:m.Window]), mean(series[m.Window:])
+	scale := math32.Max(math32.Abs(newer), loadFloor)
+	return math32.Abs(newer-older) <= m.Tolerance*scale
+}
+
+// windowDescription reports the averaging window in samples and seconds
+func (m *ConvergenceMonitor) windowDescription() string {
+	var seconds float32
+	if len(m.dt) >= m.Window {
+		for _, dt := range m.dt[len(m.dt)-m.Window:] {
+			seconds += dt
+		}
+	}
+	return fmt.Sprintf("window %d samples (%.1f s)", m.Window, seconds)
+}
+
+func (m *ConvergenceMonitor) refreshLabel() {
+	if convergenceLabel == nil {
+		return
+	}
+	var text string
+	switch {
+	case m.converged:
+		text = "Converged, " + m.windowDescription()
+	case len(m.drag) < 2*m.Window:
+		text = fmt.Sprintf("Collecting %d/%d samples", len(m.drag), 2*m.Window)
+	default:
+		text = fmt.Sprintf("Changing %.1f%%/s", mean(m.change[m.Window:])*100)
+	}
+	if recordingStopped {
+		text += ", not recording"
+	}
+	if convergenceLabel.Text() != text {
+		convergenceLabel.SetText(text)
+	}
+}
+
+func mean(values []float32) float32 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float32
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float32(len(values))
+}
+
+func initializeConvergenceUI(scene *core.Node) {
+	panel := gui.NewPanel(240, 125)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+	registerReadout(panel)
+
+	title := gui.NewLabel("Convergence")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	convergenceLabel = gui.NewLabel("")
+	convergenceLabel.SetPosition(10, 25)
+	panel.Add(convergenceLabel)
+
+	toleranceLabel := gui.NewLabel("Tolerance")
+	toleranceLabel.SetPosition(10, 48)
+	panel.Add(toleranceLabel)
+	toleranceInput := NewNumericInput(convergence.Tolerance*100, 0.1, 50, 0.5, "%", func(value float32) {
+		convergence.Tolerance = value / 100
+		convergence.Reset()
+	})
+	toleranceInput.SetPosition(80, 45)
+	panel.Add(toleranceInput)
+
+	windowLabel := gui.NewLabel("Window")
+	windowLabel.SetPosition(10, 73)
+	panel.Add(windowLabel)
+	windowInput := NewNumericInput(float32(convergence.Window), 10, 10000, 10, "samples", func(value float32) {
+		convergence.Window = int(value)
+		convergence.Reset()
+	})
+	windowInput.SetPosition(80, 70)
+	panel.Add(windowInput)
+
+	autoStopCheck := gui.NewCheckBox("Auto-stop recording")
+	autoStopCheck.SetPosition(10, 100)
+	autoStopCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		convergence.AutoStop = autoStopCheck.Value()
+	})
+	panel.Add(autoStopCheck)
+
+	resumeBtn := gui.NewButton("Record")
+	resumeBtn.SetPosition(170, 97)
+	resumeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		recordingStopped = false
+		convergence.Reset()
+	})
+	panel.Add(resumeBtn)
+
+	convergence.refreshLabel()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// A panic anywhere in a frame is caught, written to a diagnostics zip together with what
+// is needed to reproduce it, and reported in a dialog; the simulation pauses and the app
+// keeps running. The zip holds:
+//
+//	crash.txt        the panic, the stack and the build
+//	scene.json       the scene as Save Scene would write it
+//	settings.json    the simulation config and the bundle settings
+//	recent.log       the last lines of the log
+
+// recentLogLines is how much of the log the diagnostics keep
+const recentLogLines = 2000
+
+// RecentLog keeps the last lines written to it, it is added to the log output
+type RecentLog struct {
+	mu    sync.Mutex
+	lines []string
+	next  int // slot of the next line once the buffer is full
+	part  strings.Builder
+}
+
+var recentLog RecentLog
+
+func (r *RecentLog) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range p {
+		if b != '\n' {
+			r.part.WriteByte(b)
+			continue
+		}
+		line := r.part.String()
+		r.part.Reset()
+		if len(r.lines) < recentLogLines {
+			r.lines = append(r.lines, line)
+		} else {
+			r.lines[r.next] = line
+			r.next = (r.next + 1) % recentLogLines
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, oldest first
+func (r *RecentLog) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := append([]string(nil), r.lines[r.next:]...)
+	return append(lines, r.lines[:r.next]...)
+}
+
+// captureLog sends the log to the recent log as well as to stderr
+func captureLog() {
+	log.SetOutput(io.MultiWriter(os.Stderr, &recentLog))
+}
+
+// crashModelLoader is the loader whose scene goes into the diagnostics
+var crashModelLoader *ModelLoader
+
+// writeDiagnostics writes the diagnostics zip for a panic with value p and stack and
+// returns its path. It is careful about the state it reads, which may be what broke.
+func writeDiagnostics(p interface{}, stack []byte) (string, error) {
+	path := fmt.Sprintf("crash_%s.zip", time.Now().Format("20060102_150405"))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+
+	add := func(name string, write func(w io.Writer) error) {
+		w, err := zw.Create(name)
+		if err == nil {
+			err = safely(func() error { return write(w) })
+		}
+		if err != nil {
+			log.Printf("Diagnostics: writing %s: %v", name, err)
+		}
+	}
+	add("crash.txt", func(w io.Writer) error {
+		fmt.Fprintf(w, "panic: %v\n\n%s\n", p, stack)
+		fmt.Fprintf(w, "time: %s\ngo: %s %s/%s\n", time.Now().Format(time.RFC3339), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		if info, ok := debug.ReadBuildInfo(); ok {
+			fmt.Fprintf(w, "\n%s", info)
+		}
+		return nil
+	})
+	writeJSON := func(v interface{}) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		}
+	}
+	if crashModelLoader != nil {
+		add(bundleSceneFile, func(w io.Writer) error {
+			return writeJSON(currentSceneFile(crashModelLoader))(w)
+		})
+	}
+	add(bundleSettingsFile, writeJSON(struct {
+		Simulation SimulationConfig
+		Bundle     BundleSettings
+	}{simConfig, BundleSettings{
+		Render:          renderSettings,
+		Clip:            clipRegion,
+		Filter:          particleFilter,
+		RecordingLimits: recordingLimits,
+	}}))
+	add("recent.log", func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Join(recentLog.Lines(), "\n")+"\n")
+		return err
+	})
+
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// safely runs fn, turning a panic into an error
+func safely(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn()
+}
+
+// crashed is set once a frame panicked, so a panic on every frame shows one dialog
+var crashed bool
+
+// recoverFrame is deferred around the work of a frame: it reports a panic and pauses the
+// simulation so the user can save their work
+func recoverFrame() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	stack := debug.Stack()
+	log.Printf("panic: %v\n%s", p, stack)
+	setSimulationPaused(true)
+	if crashed {
+		return
+	}
+	crashed = true
+	path, err := writeDiagnostics(p, stack)
+	if err != nil {
+		log.Printf("Could not write the diagnostics: %v", err)
+	}
+	showCrashDialog(p, path)
+}
+
+// recoverMain is deferred in main for panics outside the frames, at startup or in an
+// event handler, when there is no window left to show a dialog in
+func recoverMain() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	stack := debug.Stack()
+	log.Printf("panic: %v\n%s", p, stack)
+	if path, err := writeDiagnostics(p, stack); err == nil {
+		fmt.Fprintf(os.Stderr, "\nThe program crashed. Please attach %s to the bug report.\n", path)
+	}
+	os.Exit(2)
+}
+
+func showCrashDialog(p interface{}, path string) {
+	dialog := gui.NewPanel(440, 130)
+	dialog.SetColor4(&math32.Color4{R: 0.25, G: 0.1, B: 0.1, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	lines := []string{
+		"Something went wrong: " + fmt.Sprint(p),
+		"The simulation is paused, save your scene before going on.",
+	}
+	if path != "" {
+		lines = append(lines, "Please attach "+path+" to the bug report.")
+	}
+	for i, line := range lines {
+		label := gui.NewLabel(line)
+		label.SetPosition(10, float32(10+22*i))
+		dialog.Add(label)
+	}
+
+	continueBtn := gui.NewButton("Continue")
+	continueBtn.SetPosition(10, 95)
+	continueBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		crashed = false
+	})
+	dialog.Add(continueBtn)
+
+	exitBtn := gui.NewButton("Exit")
+	exitBtn.SetPosition(390, 95)
+	exitBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		app.App().Exit()
+	})
+	dialog.Add(exitBtn)
+}
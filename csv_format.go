@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSVLocale picks the decimal separator and the delimiter of CSV exports. Spreadsheets
+// read CSV files by the number format of the system: where the decimal separator is a
+// comma they split columns at semicolons, and "1.5,2.5" turns into a date or one column.
+type CSVLocale int
+
+const (
+	CSVLocaleSystem CSVLocale = iota // from the locale of the environment
+	CSVLocalePoint                   // 1.5,2.5
+	CSVLocaleComma                   // 1,5;2,5
+)
+
+var csvLocaleNames = []string{"system", "1.5 ,", "1,5 ;"}
+
+var csvLocale CSVLocale
+
+// commaDecimalLanguages are the languages whose numbers use a decimal comma
+var commaDecimalLanguages = []string{
+	"bg", "ca", "cs", "da", "de", "el", "es", "et", "fi", "fr", "hr", "hu", "id", "is", "it",
+	"lt", "lv", "nb", "nl", "nn", "no", "pl", "pt", "ro", "ru", "sk", "sl", "sr", "sv", "tr", "uk", "vi",
+}
+
+// systemDecimalComma reports whether the locale of the environment writes numbers with a
+// decimal comma. Only the POSIX variables are looked at, elsewhere the point is assumed.
+func systemDecimalComma() bool {
+	var locale string
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+	// de_CH uses a point, like the other Swiss locales
+	if strings.HasSuffix(strings.SplitN(locale, ".", 2)[0], "_CH") {
+		return false
+	}
+	language := strings.ToLower(strings.SplitN(strings.SplitN(locale, "_", 2)[0], ".", 2)[0])
+	for _, l := range commaDecimalLanguages {
+		if language == l {
+			return true
+		}
+	}
+	return false
+}
+
+// CSVFormat writes the fields of CSV exports in the chosen locale
+type CSVFormat struct {
+	Decimal   string
+	Delimiter string
+}
+
+// csvFormat returns the format of the chosen locale
+func csvFormat() CSVFormat {
+	comma := csvLocale == CSVLocaleComma || (csvLocale == CSVLocaleSystem && systemDecimalComma())
+	if comma {
+		return CSVFormat{Decimal: ",", Delimiter: ";"}
+	}
+	return CSVFormat{Decimal: ".", Delimiter: ","}
+}
+
+// Number formats a value with as many digits as it needs
+func (c CSVFormat) Number(value float32) string {
+	s := strconv.FormatFloat(float64(value), 'g', -1, 32)
+	return strings.Replace(s, ".", c.Decimal, 1)
+}
+
+// Fixed formats a value with the given digits after the separator
+func (c CSVFormat) Fixed(value float32, digits int) string {
+	s := strconv.FormatFloat(float64(value), 'f', digits, 32)
+	return strings.Replace(s, ".", c.Decimal, 1)
+}
+
+// WriteRow writes one line of fields
+func (c CSVFormat) WriteRow(w io.Writer, fields ...string) error {
+	_, err := io.WriteString(w, strings.Join(fields, c.Delimiter)+"\n")
+	return err
+}
+
+// WriteNumbers writes one line of numbers
+func (c CSVFormat) WriteNumbers(w io.Writer, values ...float32) error {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = c.Number(v)
+	}
+	return c.WriteRow(w, fields...)
+}
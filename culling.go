@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/math32"
+)
+
+// Particles outside the camera's view are still simulated, but their meshes are neither
+// moved nor drawn. viewFrustum is refreshed once per frame before the meshes are placed.
+
+const cullMargin = 0.1 // m, larger than a particle mesh so none pops at the edges
+
+var viewFrustum *math32.Frustum
+
+// updateViewFrustum derives the frustum from the camera's current projection and view
+func updateViewFrustum(cam *camera.Camera) {
+	var proj, view math32.Matrix4
+	cam.ProjMatrix(&proj)
+	cam.ViewMatrix(&view)
+	proj.Multiply(&view)
+	viewFrustum = math32.NewFrustumFromMatrix(&proj)
+}
+
+// inView reports whether a particle at pos may be seen by the camera
+func inView(pos math32.Vector3) bool {
+	if viewFrustum == nil {
+		return true
+	}
+	return viewFrustum.IntersectsSphere(math32.NewSphere(&pos, cullMargin))
+}
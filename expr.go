@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled arithmetic expression of the simulated time t, like
+// "5 + 3*sin(2*pi*t/10)". It knows + - * / ^, parentheses, the constants pi and e and
+// the functions in exprFuncs.
+type Expr struct {
+	Source string
+	eval   func(t float64) float64
+}
+
+var exprFuncs = map[string]func(float64) float64{
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"floor": math.Floor,
+	"sign": func(x float64) float64 {
+		if x < 0 {
+			return -1
+		}
+		if x > 0 {
+			return 1
+		}
+		return 0
+	},
+}
+
+var exprConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// ParseExpr compiles src
+func ParseExpr(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	p.next()
+	eval, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected %q at %d", p.tok, p.pos)
+	}
+	return &Expr{Source: src, eval: eval}, nil
+}
+
+// Eval returns the value at time t, zero where the expression is undefined
+func (e *Expr) Eval(t float32) float32 {
+	v := e.eval(float64(t))
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return float32(v)
+}
+
+// exprParser is a recursive descent parser over the tokens of the source, tok is the
+// current one and empty at the end
+type exprParser struct {
+	src string
+	pos int // just past tok
+	tok string
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	c := rune(p.src[p.pos])
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(c):
+		for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+// sum parses terms joined by + and -
+func (p *exprParser) sum() (func(float64) float64, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(t float64) float64 { return l(t) + right(t) }
+		} else {
+			left = func(t float64) float64 { return l(t) - right(t) }
+		}
+	}
+	return left, nil
+}
+
+// product parses factors joined by * and /
+func (p *exprParser) product() (func(float64) float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "*" {
+			left = func(t float64) float64 { return l(t) * right(t) }
+		} else {
+			left = func(t float64) float64 { return l(t) / right(t) }
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (func(float64) float64, error) {
+	if p.tok == "-" {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(t float64) float64 { return -operand(t) }, nil
+	}
+	if p.tok == "+" {
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power parses a ^ b, right associative and binding tighter than the unary minus
+func (p *exprParser) power() (func(float64) float64, error) {
+	base, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(t float64) float64 { return math.Pow(base(t), exponent(t)) }, nil
+}
+
+func (p *exprParser) atom() (func(float64) float64, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end")
+	case tok == "(":
+		p.next()
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("missing ) at %d", p.pos)
+		}
+		p.next()
+		return inner, nil
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", tok)
+		}
+		p.next()
+		return func(float64) float64 { return v }, nil
+	case unicode.IsLetter(rune(tok[0])):
+		name := strings.ToLower(tok)
+		p.next()
+		if name == "t" {
+			return func(t float64) float64 { return t }, nil
+		}
+		if v, ok := exprConstants[name]; ok {
+			return func(float64) float64 { return v }, nil
+		}
+		fn, ok := exprFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown name %q", tok)
+		}
+		if p.tok != "(" {
+			return nil, fmt.Errorf("%s needs an argument in parentheses", name)
+		}
+		arg, err := p.atom()
+		if err != nil {
+			return nil, err
+		}
+		return func(t float64) float64 { return fn(arg(t)) }, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", tok, p.pos)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/g3n/engine/math32"
+)
+
+// FieldAverage accumulates the time average of the flow velocity and pressure at the cell
+// centers, next to the instantaneous field. Turbulent snapshots differ from frame to frame,
+// averages can be compared between designs. The variance of the velocity is accumulated
+// too, giving the turbulent kinetic energy and the turbulence intensity of each cell.
+type FieldAverage struct {
+	Enabled  bool
+	Duration float32 // seconds accumulated so far
+
+	velocity   []math32.Vector3 // per cell, velocity integrated over time
+	velocitySq []math32.Vector3 // per cell, squared velocity components integrated over time
+	pressure   []float32
+}
+
+var fieldAverage FieldAverage
+
+// Reset throws the accumulated averages away
+func (a *FieldAverage) Reset() {
+	a.Duration = 0
+	a.velocity = nil
+	a.velocitySq = nil
+	a.pressure = nil
+}
+
+// Accumulate adds the current state of f, weighted by the dt it lasted
+func (a *FieldAverage) Accumulate(f *VectorField, dt float32) {
+	if !a.Enabled || dt <= 0 {
+		return
+	}
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	if len(a.velocity) != n {
+		a.velocity = make([]math32.Vector3, n)
+		a.velocitySq = make([]math32.Vector3, n)
+		a.pressure = make([]float32, n)
+		a.Duration = 0
+	}
+	f.forEachCell(func(c [3]int) {
+		i := f.index(c[0], c[1], c[2])
+		v := f.cellVelocity(c)
+		a.velocitySq[i].Add(&math32.Vector3{X: v.X * v.X * dt, Y: v.Y * v.Y * dt, Z: v.Z * v.Z * dt})
+		a.velocity[i].Add(v.MultiplyScalar(dt))
+		a.pressure[i] += f.PressureAt(c) * dt
+	})
+	a.Duration += dt
+}
+
+// Velocity returns the average velocity of cell i
+func (a *FieldAverage) Velocity(i int) math32.Vector3 {
+	if a.Duration == 0 || i >= len(a.velocity) {
+		return math32.Vector3{}
+	}
+	return *a.velocity[i].Clone().DivideScalar(a.Duration)
+}
+
+// Pressure returns the average pressure of cell i in Pa
+func (a *FieldAverage) Pressure(i int) float32 {
+	if a.Duration == 0 || i >= len(a.pressure) {
+		return 0
+	}
+	return a.pressure[i] / a.Duration
+}
+
+// TKE returns the turbulent kinetic energy of cell i in m²/s², half the summed variance
+// of the velocity components
+func (a *FieldAverage) TKE(i int) float32 {
+	if a.Duration == 0 || i >= len(a.velocitySq) {
+		return 0
+	}
+	mean := a.Velocity(i)
+	sq := a.velocitySq[i]
+	variance := sq.X/a.Duration - mean.X*mean.X +
+		sq.Y/a.Duration - mean.Y*mean.Y +
+		sq.Z/a.Duration - mean.Z*mean.Z
+	return math32.Max(variance, 0) / 2
+}
+
+// Intensity returns the turbulence intensity of cell i: the RMS velocity fluctuation
+// sqrt(2k/3) relative to the mean speed, zero where the air is still on average
+func (a *FieldAverage) Intensity(i int) float32 {
+	mean := a.Velocity(i)
+	speed := mean.Length()
+	if speed < 1e-3 {
+		return 0
+	}
+	return math32.Sqrt(2*a.TKE(i)/3) / speed
+}
+
+// exportFieldAverage writes the averages as CSV in the chosen locale, one row per cell
+// center
+func exportFieldAverage(path string, f *VectorField) error {
+	if fieldAverage.Duration == 0 {
+		return fmt.Errorf("no averages accumulated yet")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	csv := csvFormat()
+	fmt.Fprintf(w, "# time average over %s s\n", csv.Fixed(fieldAverage.Duration, 2))
+	csv.WriteRow(w, "x", "y", "z", "u", "v", "w", "speed", "pressure", "tke", "intensity")
+	f.forEachCell(func(c [3]int) {
+		if !f.isInterior(c) || f.isSolid(c) {
+			return
+		}
+		i := f.index(c[0], c[1], c[2])
+		pos := f.cellCenter(c[0], c[1], c[2])
+		v := fieldAverage.Velocity(i)
+		csv.WriteNumbers(w, pos.X, pos.Y, pos.Z, v.X, v.Y, v.Z, v.Length(),
+			fieldAverage.Pressure(i), fieldAverage.TKE(i), fieldAverage.Intensity(i))
+	})
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	log.Printf("Field averages over %.1f s exported to %s", fieldAverage.Duration, path)
+	return nil
+}
+
+func fieldAverageFileName() string {
+	return fmt.Sprintf("field_average_%d.csv", time.Now().UnixNano())
+}
@@ -0,0 +1,446 @@
+package main
+
+// #include <stdint.h>
+//
+// #ifdef _WIN32
+// #define GLAPIENTRY __stdcall
+// #else
+// #define GLAPIENTRY
+// #endif
+//
+// typedef void (GLAPIENTRY *dispatchComputeProc)(unsigned int x, unsigned int y, unsigned int z);
+// typedef void (GLAPIENTRY *memoryBarrierProc)(unsigned int barriers);
+// typedef void (GLAPIENTRY *bindBufferBaseProc)(unsigned int target, unsigned int index, unsigned int buffer);
+// typedef void (GLAPIENTRY *getBufferSubDataProc)(unsigned int target, intptr_t offset, intptr_t size, void *data);
+// typedef void (GLAPIENTRY *useProgramProc)(unsigned int program);
+// typedef void (GLAPIENTRY *getIntegervProc)(unsigned int pname, int *data);
+//
+// static void dispatchCompute(void *p, unsigned int x, unsigned int y, unsigned int z) { ((dispatchComputeProc)p)(x, y, z); }
+// static void memoryBarrier(void *p, unsigned int barriers) { ((memoryBarrierProc)p)(barriers); }
+// static void bindBufferBase(void *p, unsigned int target, unsigned int index, unsigned int buffer) { ((bindBufferBaseProc)p)(target, index, buffer); }
+// static void getBufferSubData(void *p, unsigned int target, intptr_t offset, intptr_t size, void *data) { ((getBufferSubDataProc)p)(target, offset, size, data); }
+// static void useProgram(void *p, unsigned int program) { ((useProgramProc)p)(program); }
+// static void getIntegerv(void *p, unsigned int pname, int *data) { ((getIntegervProc)p)(pname, data); }
+import "C"
+
+import (
+	"fmt"
+	"log"
+	"unsafe"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/math32"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// The source injection, advection and diffusion passes touch every open face of the grid
+// several times per substep and dominate the step at 128³ cells. When the driver offers
+// OpenGL 4.3 they run as compute shaders over shader storage buffers instead of on the
+// CPU. g3n asks for a 3.3 core context and its gls package has no compute bindings, so
+// the few entry points beyond 3.3 are looked up through glfw, and the passes only run on
+// the GPU when the context the driver handed out turned out to be 4.3 or newer. Without
+// one, or with SimulationConfig.GPUCompute off, the CPU passes in fluid_solver.go run as
+// before. The other passes and the projection stay on the CPU, so the velocities go up
+// to the GPU and back down around each group of passes.
+
+// GL 4.3 names gls doesn't have
+const (
+	glComputeShader          = 0x91B9
+	glShaderStorageBuffer    = 0x90D2
+	glShaderStorageBarrier   = 0x2000
+	glBufferUpdateBarrierBit = 0x0200
+)
+
+// computeGroupSize is the local size of the shaders, maxComputeGroups the number of work
+// groups every 4.3 driver dispatches along one dimension
+const (
+	computeGroupSize = 256
+	maxComputeGroups = 65535
+)
+
+// the storage buffer binding points, as declared in fieldComputeHeader
+const (
+	bindCurrent = iota
+	bindPrevious
+	bindFaces
+	bindScratch
+	bindAlpha
+	bindSources
+	bindingCount
+)
+
+// FieldCompute holds the compute programs and buffers of the GPU passes
+type FieldCompute struct {
+	gs *gls.GLS
+
+	// entry points beyond GL 3.3
+	dispatchCompute  unsafe.Pointer
+	memoryBarrier    unsafe.Pointer
+	bindBufferBase   unsafe.Pointer
+	getBufferSubData unsafe.Pointer
+	useProgram       unsafe.Pointer
+	getIntegerv      unsafe.Pointer
+
+	sources, save, advect, jacobi, store *gls.Program
+	buffers                              [bindingCount]uint32
+
+	topology   *fieldTopology // the one the faces were uploaded for
+	faceCount  int
+	cells      int
+	velocities []float32 // staging for the upload and download, three per cell
+}
+
+// fieldCompute is nil when the GPU passes are unavailable
+var fieldCompute *FieldCompute
+
+// initializeFieldCompute sets up the GPU passes if the GL context allows them
+func initializeFieldCompute() {
+	fc, err := newFieldCompute(app.App().Gls())
+	if err != nil {
+		log.Printf("Flow field passes run on the CPU: %v", err)
+		return
+	}
+	fieldCompute = fc
+	log.Printf("Flow field passes run on the GPU (%s)", fc.gs.GetString(gls.RENDERER))
+}
+
+func newFieldCompute(gs *gls.GLS) (*FieldCompute, error) {
+	fc := &FieldCompute{gs: gs}
+	for _, p := range []struct {
+		proc *unsafe.Pointer
+		name string
+	}{
+		{&fc.dispatchCompute, "glDispatchCompute"},
+		{&fc.memoryBarrier, "glMemoryBarrier"},
+		{&fc.bindBufferBase, "glBindBufferBase"},
+		{&fc.getBufferSubData, "glGetBufferSubData"},
+		{&fc.useProgram, "glUseProgram"},
+		{&fc.getIntegerv, "glGetIntegerv"},
+	} {
+		if *p.proc = glfw.GetProcAddress(p.name); *p.proc == nil {
+			return nil, fmt.Errorf("the driver has no %s", p.name)
+		}
+	}
+	var major, minor C.int
+	C.getIntegerv(fc.getIntegerv, gls.MAJOR_VERSION, &major)
+	C.getIntegerv(fc.getIntegerv, gls.MINOR_VERSION, &minor)
+	if major < 4 || major == 4 && minor < 3 {
+		return nil, fmt.Errorf("compute shaders need OpenGL 4.3, the context is %d.%d", major, minor)
+	}
+
+	var err error
+	build := func(main string) *gls.Program {
+		if err != nil {
+			return nil
+		}
+		prog := gs.NewProgram()
+		prog.AddShader(glComputeShader, fieldComputeHeader+main)
+		err = prog.Build()
+		return prog
+	}
+	fc.sources = build(sourcesShader)
+	fc.save = build(saveShader)
+	fc.advect = build(advectShader)
+	fc.jacobi = build(jacobiShader)
+	fc.store = build(storeShader)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fc.buffers {
+		fc.buffers[i] = gs.GenBuffer()
+	}
+	return fc, nil
+}
+
+// usable reports whether the passes of f run on the GPU
+func (fc *FieldCompute) usable(f *VectorField) bool {
+	return fc != nil && simConfig.GPUCompute && len(f.topology.openFaces) > 0
+}
+
+// applySourcesAndAdvect does what applySources and advect do on the CPU
+func (fc *FieldCompute) applySourcesAndAdvect(f *VectorField, sources []WindSource, dt float32) {
+	defer fc.begin(f)()
+	fc.uploadVelocities(f)
+	if len(sources) > 0 {
+		// position and radius, then the target velocity, per source
+		data := make([]float32, 0, 8*len(sources))
+		for s := range sources {
+			wind := &sources[s]
+			target := wind.Direction.Clone().Normalize().MultiplyScalar(math32.Min(wind.Speed, maxFieldSpeed))
+			data = append(data, wind.Position.X, wind.Position.Y, wind.Position.Z, wind.Radius, target.X, target.Y, target.Z, 0)
+		}
+		fc.upload(bindSources, data)
+		fc.use(fc.sources, f, fc.faceCount)
+		fc.gs.Uniform1i(fc.sources.GetUniformLocation("sourceCount"), int32(len(sources)))
+		fc.dispatch(fc.faceCount)
+	}
+	fc.use(fc.save, f, 3*fc.cells)
+	fc.dispatch(3 * fc.cells)
+	fc.use(fc.advect, f, fc.faceCount)
+	fc.gs.Uniform1f(fc.advect.GetUniformLocation("dt"), dt)
+	fc.dispatch(fc.faceCount)
+	fc.downloadVelocities(f)
+}
+
+// diffuse does the Jacobi iterations of VectorField.diffuse with the face weights it set
+func (fc *FieldCompute) diffuse(f *VectorField) {
+	defer fc.begin(f)()
+	fc.uploadVelocities(f)
+	fc.upload(bindAlpha, f.faceAlpha)
+	fc.use(fc.save, f, 3*fc.cells)
+	fc.dispatch(3 * fc.cells)
+	for iter := 0; iter < simConfig.DiffusionIters; iter++ {
+		fc.use(fc.jacobi, f, fc.faceCount)
+		fc.dispatch(fc.faceCount)
+		fc.use(fc.store, f, fc.faceCount)
+		fc.dispatch(fc.faceCount)
+	}
+	fc.downloadVelocities(f)
+}
+
+// begin binds the buffers and uploads the faces when the topology changed. It returns
+// the function that gives the renderer back the program it had bound.
+func (fc *FieldCompute) begin(f *VectorField) func() {
+	var current C.int
+	C.getIntegerv(fc.getIntegerv, gls.CURRENT_PROGRAM, &current)
+
+	if cells := len(f.Field); cells != fc.cells {
+		fc.cells = cells
+		fc.velocities = make([]float32, 3*cells)
+		fc.allocate(bindPrevious, 4*3*cells)
+	}
+	if fc.topology != f.topology {
+		fc.topology = f.topology
+		faces := f.topology.openFaces
+		fc.faceCount = len(faces)
+		packed := make([]uint32, len(faces))
+		for n, face := range faces {
+			packed[n] = uint32(f.index(face.c[0], face.c[1], face.c[2]))<<2 | uint32(face.axis)
+		}
+		fc.upload(bindFaces, packed)
+		fc.allocate(bindScratch, 4*len(faces))
+	}
+	for i, buf := range fc.buffers {
+		C.bindBufferBase(fc.bindBufferBase, glShaderStorageBuffer, C.uint(i), C.uint(buf))
+	}
+	return func() {
+		C.useProgram(fc.useProgram, C.uint(current))
+	}
+}
+
+func (fc *FieldCompute) allocate(binding, bytes int) {
+	fc.gs.BindBuffer(glShaderStorageBuffer, fc.buffers[binding])
+	fc.gs.BufferData(glShaderStorageBuffer, bytes, nil, gls.DYNAMIC_DRAW)
+}
+
+// upload replaces the contents of a buffer with data, a non-empty slice
+func (fc *FieldCompute) upload(binding int, data interface{}) {
+	var bytes int
+	switch d := data.(type) {
+	case []float32:
+		bytes = 4 * len(d)
+	case []uint32:
+		bytes = 4 * len(d)
+	}
+	fc.gs.BindBuffer(glShaderStorageBuffer, fc.buffers[binding])
+	fc.gs.BufferData(glShaderStorageBuffer, bytes, data, gls.DYNAMIC_DRAW)
+}
+
+func (fc *FieldCompute) uploadVelocities(f *VectorField) {
+	for i := range f.Field {
+		v := &f.Field[i]
+		fc.velocities[3*i], fc.velocities[3*i+1], fc.velocities[3*i+2] = v.VX, v.VY, v.VZ
+	}
+	fc.upload(bindCurrent, fc.velocities)
+}
+
+func (fc *FieldCompute) downloadVelocities(f *VectorField) {
+	C.memoryBarrier(fc.memoryBarrier, glBufferUpdateBarrierBit)
+	fc.gs.BindBuffer(glShaderStorageBuffer, fc.buffers[bindCurrent])
+	C.getBufferSubData(fc.getBufferSubData, glShaderStorageBuffer, 0, C.intptr_t(4*len(fc.velocities)), unsafe.Pointer(&fc.velocities[0]))
+	for i := range f.Field {
+		v := &f.Field[i]
+		v.VX, v.VY, v.VZ = fc.velocities[3*i], fc.velocities[3*i+1], fc.velocities[3*i+2]
+	}
+}
+
+// use binds prog and sets the uniforms all the shaders share, for count work items
+func (fc *FieldCompute) use(prog *gls.Program, f *VectorField, count int) {
+	C.useProgram(fc.useProgram, C.uint(prog.Handle()))
+	o := f.origin()
+	fc.gs.Uniform1i(prog.GetUniformLocation("nx"), int32(f.AreaWidth))
+	fc.gs.Uniform1i(prog.GetUniformLocation("ny"), int32(f.AreaHeight))
+	fc.gs.Uniform1i(prog.GetUniformLocation("nz"), int32(f.AreaDepth))
+	fc.gs.Uniform1i(prog.GetUniformLocation("count"), int32(count))
+	fc.gs.Uniform3f(prog.GetUniformLocation("origin"), o.X, o.Y, o.Z)
+	fc.gs.Uniform1f(prog.GetUniformLocation("h"), f.CellSize())
+	fc.gs.Uniform1f(prog.GetUniformLocation("maxSpeed"), maxFieldSpeed)
+}
+
+// dispatch runs the bound program over count work items, in rows of work groups when
+// there are more groups than one dimension takes, and waits for its writes
+func (fc *FieldCompute) dispatch(count int) {
+	groups := (count + computeGroupSize - 1) / computeGroupSize
+	x, y := groups, 1
+	if groups > maxComputeGroups {
+		x = maxComputeGroups
+		y = (groups + maxComputeGroups - 1) / maxComputeGroups
+	}
+	C.dispatchCompute(fc.dispatchCompute, C.uint(x), C.uint(y), 1)
+	C.memoryBarrier(fc.memoryBarrier, glShaderStorageBarrier)
+}
+
+// fieldComputeHeader declares the buffers and the grid helpers of fluid_solver.go. The
+// velocities are three floats per cell in the order of VectorField.index, a face is its
+// cell index shifted left by two with the axis in the low bits.
+const fieldComputeHeader = `#version 430
+layout(local_size_x = 256) in;
+
+layout(std430, binding = 0) buffer Current { float cur[]; };
+layout(std430, binding = 1) buffer Previous { float prev[]; };
+layout(std430, binding = 2) readonly buffer Faces { uint faces[]; };
+layout(std430, binding = 3) buffer Scratch { float scratch[]; };
+layout(std430, binding = 4) readonly buffer Alpha { float alpha[]; };
+layout(std430, binding = 5) readonly buffer Sources { vec4 sources[]; };
+
+uniform int nx, ny, nz;
+uniform int count;
+uniform vec3 origin;
+uniform float h;
+uniform float maxSpeed;
+
+uint item() {
+	return (gl_WorkGroupID.y * gl_NumWorkGroups.x + gl_WorkGroupID.x) * gl_WorkGroupSize.x + gl_LocalInvocationID.x;
+}
+
+int index(ivec3 c) {
+	return (c.x * ny + c.y) * nz + c.z;
+}
+
+ivec3 clampCell(ivec3 c) {
+	return clamp(c, ivec3(0), ivec3(nx, ny, nz) - 1);
+}
+
+ivec3 faceCell(uint face) {
+	int i = int(face >> 2);
+	return ivec3(i / (ny * nz), (i / nz) % ny, i % nz);
+}
+
+int faceAxis(uint face) {
+	return int(face & 3u);
+}
+
+vec3 cellCenter(ivec3 c) {
+	return origin + (vec3(c) + 0.5) * h;
+}
+
+float readCur(int axis, ivec3 c) {
+	return cur[3 * index(clampCell(c)) + axis];
+}
+
+float readPrev(int axis, ivec3 c) {
+	return prev[3 * index(clampCell(c)) + axis];
+}
+
+// sampleComponent of fluid_solver.go reading the saved velocities
+float samplePrev(int axis, vec3 pos) {
+	vec3 g = (pos - origin) / h - 0.5;
+	g[axis] += 0.5;
+	vec3 fl = floor(g);
+	ivec3 base = ivec3(fl);
+	vec3 t = g - fl;
+	float sum = 0.0;
+	for (int corner = 0; corner < 8; corner++) {
+		ivec3 c = base;
+		float w = 1.0;
+		for (int a = 0; a < 3; a++) {
+			if (((corner >> a) & 1) == 1) {
+				c[a]++;
+				w *= t[a];
+			} else {
+				w *= 1.0 - t[a];
+			}
+		}
+		sum += w * readPrev(axis, c);
+	}
+	return sum;
+}
+`
+
+const sourcesShader = `
+uniform int sourceCount;
+
+void main() {
+	uint n = item();
+	if (n >= uint(count)) {
+		return;
+	}
+	ivec3 c = faceCell(faces[n]);
+	int axis = faceAxis(faces[n]);
+	vec3 center = cellCenter(c);
+	// the sources are tried in order, so where they overlap the last one wins
+	for (int s = 0; s < sourceCount; s++) {
+		vec4 source = sources[2 * s];
+		if (distance(center, source.xyz) <= source.w) {
+			cur[3 * index(c) + axis] = sources[2 * s + 1][axis];
+		}
+	}
+}
+`
+
+const saveShader = `
+void main() {
+	uint n = item();
+	if (n < uint(count)) {
+		prev[n] = cur[n];
+	}
+}
+`
+
+const advectShader = `
+uniform float dt;
+
+void main() {
+	uint n = item();
+	if (n >= uint(count)) {
+		return;
+	}
+	ivec3 c = faceCell(faces[n]);
+	int axis = faceAxis(faces[n]);
+	vec3 pos = cellCenter(c);
+	pos[axis] -= h / 2.0;
+	vec3 vel = vec3(samplePrev(0, pos), samplePrev(1, pos), samplePrev(2, pos));
+	pos -= vel * dt;
+	cur[3 * index(c) + axis] = clamp(samplePrev(axis, pos), -maxSpeed, maxSpeed);
+}
+`
+
+const jacobiShader = `
+void main() {
+	uint n = item();
+	if (n >= uint(count)) {
+		return;
+	}
+	ivec3 c = faceCell(faces[n]);
+	int axis = faceAxis(faces[n]);
+	float sum = 0.0;
+	for (int a = 0; a < 3; a++) {
+		ivec3 below = c;
+		ivec3 above = c;
+		below[a]--;
+		above[a]++;
+		sum += readCur(axis, below) + readCur(axis, above);
+	}
+	scratch[n] = (readPrev(axis, c) + alpha[n] * sum) / (1.0 + 6.0 * alpha[n]);
+}
+`
+
+const storeShader = `
+void main() {
+	uint n = item();
+	if (n < uint(count)) {
+		cur[3 * index(faceCell(faces[n])) + faceAxis(faces[n])] = scratch[n];
+	}
+}
+`
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// SliceQuantity is the value a field slice is colored by
+type SliceQuantity int
+
+const (
+	SliceSpeed SliceQuantity = iota
+	SlicePressure
+	// the turbulence statistics only exist as accumulated values, "Show average" is implied
+	SliceTKE
+	SliceIntensity
+)
+
+var sliceQuantityNames = []string{"speed", "pressure", "TKE", "intensity"}
+
+// FieldSlice is a horizontal cut through the flow field colored by one quantity, either
+// of the instantaneous field or of its time average
+type FieldSlice struct {
+	Enabled  bool
+	Height   float32 // meters above the ground
+	Quantity SliceQuantity
+	Averaged bool
+}
+
+var fieldSlice = FieldSlice{Height: 1}
+
+var (
+	sliceMesh      *graphic.Mesh
+	sliceColors    *gls.VBO
+	sliceDims      [2]int
+	sliceRangeText string // the color scale of the last update
+
+	// fieldSliceInfo shows the averaging time and the color scale, nil until the UI is built
+	fieldSliceInfo *gui.Label
+)
+
+// sliceValue returns the quantity shown by the slice in cell c
+func sliceValue(f *VectorField, c [3]int) float32 {
+	i := f.index(c[0], c[1], c[2])
+	switch fieldSlice.Quantity {
+	case SliceTKE:
+		return fieldAverage.TKE(i)
+	case SliceIntensity:
+		return fieldAverage.Intensity(i)
+	case SlicePressure:
+		if fieldSlice.Averaged {
+			return fieldAverage.Pressure(i)
+		}
+		return f.PressureAt(c)
+	default:
+		if fieldSlice.Averaged {
+			v := fieldAverage.Velocity(i)
+			return v.Length()
+		}
+		v := f.cellVelocity(c)
+		return v.Length()
+	}
+}
+
+// heatColor maps t in [0, 1] from blue through green to red
+func heatColor(t float32) math32.Color {
+	t = math32.Clamp(t, 0, 1)
+	switch {
+	case t < 0.25:
+		return math32.Color{R: 0, G: 4 * t, B: 1}
+	case t < 0.5:
+		return math32.Color{R: 0, G: 1, B: 1 - 4*(t-0.25)}
+	case t < 0.75:
+		return math32.Color{R: 4 * (t - 0.5), G: 1, B: 0}
+	}
+	return math32.Color{R: 1, G: 1 - 4*(t-0.75), B: 0}
+}
+
+// buildSliceMesh creates one flat colored quad per cell column of f
+func buildSliceMesh(f *VectorField) {
+	removeSliceMesh()
+	nx, nz := f.AreaWidth, f.AreaDepth
+	h := f.CellSize()
+	o := f.origin()
+	positions := math32.NewArrayF32(0, nx*nz*18)
+	for i := 0; i < nx; i++ {
+		for k := 0; k < nz; k++ {
+			x0, z0 := o.X+float32(i)*h, o.Z+float32(k)*h
+			x1, z1 := x0+h, z0+h
+			positions.Append(
+				x0, 0, z0, x0, 0, z1, x1, 0, z1,
+				x0, 0, z0, x1, 0, z1, x1, 0, z0,
+			)
+		}
+	}
+	colors := math32.NewArrayF32(nx*nz*18, nx*nz*18)
+	sliceColors = gls.NewVBO(colors).AddAttrib(gls.VertexColor)
+	geom := geometry.NewGeometry()
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(sliceColors)
+	mat := material.NewBasic()
+	mat.SetSide(material.SideDouble)
+	sliceMesh = graphic.NewMesh(geom, mat)
+	sliceDims = [2]int{nx, nz}
+	objects.Add(sliceMesh, "field slice")
+}
+
+func removeSliceMesh() {
+	if sliceMesh != nil {
+		objects.Remove(sliceMesh)
+		sliceMesh = nil
+		sliceColors = nil
+	}
+}
+
+// updateFieldSlice recolors the slice from the current field, called once per frame
+func updateFieldSlice() {
+	defer refreshFieldSliceInfo()
+	f := &vectorField
+	if !fieldSlice.Enabled || f.Field == nil {
+		if sliceMesh != nil {
+			sliceMesh.SetVisible(false)
+		}
+		return
+	}
+	if sliceMesh == nil || sliceDims != [2]int{f.AreaWidth, f.AreaDepth} {
+		buildSliceMesh(f)
+	}
+	sliceMesh.SetVisible(true)
+
+	_, j, _, _ := f.cellAt(math32.Vector3{X: 0, Y: fieldSlice.Height, Z: 0})
+	j = int(math32.Clamp(float32(j), 0, float32(f.AreaHeight-1)))
+	center := f.cellCenter(0, j, 0)
+	sliceMesh.SetPosition(0, center.Y, 0)
+
+	values := make([]float32, f.AreaWidth*f.AreaDepth)
+	min, max := math32.Inf(1), math32.Inf(-1)
+	for i := 0; i < f.AreaWidth; i++ {
+		for k := 0; k < f.AreaDepth; k++ {
+			v := sliceValue(f, [3]int{i, j, k})
+			values[i*f.AreaDepth+k] = v
+			min = math32.Min(min, v)
+			max = math32.Max(max, v)
+		}
+	}
+	span := max - min
+	if span < 1e-6 {
+		span = 1
+	}
+	colors := sliceColors.Buffer()
+	for n, v := range values {
+		c := heatColor((v - min) / span)
+		for vert := 0; vert < 6; vert++ {
+			colors.SetColor(n*18+vert*3, &c)
+		}
+	}
+	sliceColors.Update()
+	sliceRangeText = fmt.Sprintf("%.2f .. %.2f", min, max)
+}
+
+func refreshFieldSliceInfo() {
+	if fieldSliceInfo == nil {
+		return
+	}
+	text := fmt.Sprintf("avg %.1f s", fieldAverage.Duration)
+	if fieldSlice.Enabled {
+		text += ", scale " + sliceRangeText
+	}
+	if fieldSliceInfo.Text() != text {
+		fieldSliceInfo.SetText(text)
+	}
+}
+
+func initializeFieldSliceUI(scene *core.Node) {
+	panel := gui.NewPanel(240, 150)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+	registerReadout(panel)
+
+	title := gui.NewLabel("Field slice")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	enableCheck := gui.NewCheckBox("Show")
+	enableCheck.SetPosition(110, 5)
+	enableCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		fieldSlice.Enabled = enableCheck.Value()
+	})
+	panel.Add(enableCheck)
+
+	heightLabel := gui.NewLabel("Height")
+	heightLabel.SetPosition(10, 30)
+	panel.Add(heightLabel)
+	heightInput := NewNumericInput(fieldSlice.Height, 0, 50, 0.5, "m", func(value float32) {
+		fieldSlice.Height = value
+	})
+	heightInput.SetPosition(65, 27)
+	panel.Add(heightInput)
+
+	quantityBtn := gui.NewButton(sliceQuantityNames[fieldSlice.Quantity])
+	quantityBtn.SetPosition(10, 52)
+	quantityBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		fieldSlice.Quantity = (fieldSlice.Quantity + 1) % SliceQuantity(len(sliceQuantityNames))
+		quantityBtn.Label.SetText(sliceQuantityNames[fieldSlice.Quantity])
+	})
+	panel.Add(quantityBtn)
+
+	averagedCheck := gui.NewCheckBox("Show average")
+	averagedCheck.SetPosition(110, 55)
+	averagedCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		fieldSlice.Averaged = averagedCheck.Value()
+	})
+	panel.Add(averagedCheck)
+
+	accumulateCheck := gui.NewCheckBox("Accumulate average")
+	accumulateCheck.SetPosition(10, 80)
+	accumulateCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		fieldAverage.Enabled = accumulateCheck.Value()
+	})
+	panel.Add(accumulateCheck)
+
+	resetBtn := gui.NewButton("Reset")
+	resetBtn.SetPosition(10, 102)
+	resetBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		fieldAverage.Reset()
+	})
+	panel.Add(resetBtn)
+
+	exportBtn := gui.NewButton("Export CSV")
+	exportBtn.SetPosition(70, 102)
+	exportBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path := fieldAverageFileName()
+		if err := exportFieldAverage(path, &vectorField); err != nil {
+			overlays.Notify("Could not export averages: " + err.Error())
+			return
+		}
+		overlays.Notify("Averages exported to " + path)
+	})
+	panel.Add(exportBtn)
+
+	fieldSliceInfo = gui.NewLabel("")
+	fieldSliceInfo.SetPosition(10, 128)
+	panel.Add(fieldSliceInfo)
+}
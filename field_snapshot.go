@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+)
+
+// FieldSnapshot is the state of the flow and temperature fields at the end of a run, saved with the recording
+// so a later run can start from the developed flow instead of from rest
+type FieldSnapshot struct {
+	Width      int
+	Height     int
+	Depth      int
+	AreaWidth  int
+	AreaHeight int
+	AreaDepth  int
+	VX         []float32 // face velocities in cell order, see VectorField.index
+	VY         []float32
+	VZ         []float32
+	// Temperature is per cell in °C, empty when the run had no temperature field yet
+	Temperature []float32 `json:",omitempty"`
+	Pressure    []float32 `json:",omitempty"` // Pa
+}
+
+// Snapshot copies the current velocities and pressures of the field
+func (f *VectorField) Snapshot() *FieldSnapshot {
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	s := &FieldSnapshot{
+		Width: f.Width, Height: f.Height, Depth: f.Depth,
+		AreaWidth: f.AreaWidth, AreaHeight: f.AreaHeight, AreaDepth: f.AreaDepth,
+		VX: make([]float32, n), VY: make([]float32, n), VZ: make([]float32, n),
+		Pressure: make([]float32, n),
+	}
+	f.forEachCell(func(c [3]int) {
+		v := f.cell(c)
+		i := f.index(c[0], c[1], c[2])
+		s.VX[i], s.VY[i], s.VZ[i] = v.VX, v.VY, v.VZ
+		s.Pressure[i] = v.P
+	})
+	if f.temperature != nil {
+		s.Temperature = append([]float32(nil), f.temperature...)
+	}
+	return s
+}
+
+// Restore replaces the velocities and pressures of the field with those of s, which must have been
+// taken from a field of the same size
+func (f *VectorField) Restore(s *FieldSnapshot) error {
+	if s.Width != f.Width || s.Height != f.Height || s.Depth != f.Depth ||
+		s.AreaWidth != f.AreaWidth || s.AreaHeight != f.AreaHeight || s.AreaDepth != f.AreaDepth {
+		return fmt.Errorf("saved field is %dx%dx%d cells over %dx%dx%d m, the current one %dx%dx%d over %dx%dx%d",
+			s.AreaWidth, s.AreaHeight, s.AreaDepth, s.Width, s.Height, s.Depth,
+			f.AreaWidth, f.AreaHeight, f.AreaDepth, f.Width, f.Height, f.Depth)
+	}
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	if len(s.VX) != n || len(s.VY) != n || len(s.VZ) != n {
+		return fmt.Errorf("saved field has %d values, expected %d", len(s.VX), n)
+	}
+	f.forEachCell(func(c [3]int) {
+		v := f.cell(c)
+		i := f.index(c[0], c[1], c[2])
+		v.VX, v.VY, v.VZ = s.VX[i], s.VY[i], s.VZ[i]
+		v.P = 0
+		if len(s.Pressure) == n {
+			v.P = s.Pressure[i]
+		}
+	})
+	if len(s.Temperature) == n {
+		f.resetTemperature()
+		copy(f.temperature, s.Temperature)
+	}
+	return nil
+}
+
+// startFromRun initializes the flow field with the final state of the recording at path
+func startFromRun(path string) error {
+	rec, err := loadSimulationData(path)
+	if err != nil {
+		return err
+	}
+	if rec.FinalField == nil {
+		return fmt.Errorf("%s has no saved field state", filepath.Base(path))
+	}
+	if err := vectorField.Restore(rec.FinalField); err != nil {
+		return err
+	}
+	log.Printf("Flow field initialized from %s", path)
+	return nil
+}
+
+func initializeFieldSnapshotUI(scene *core.Node) {
+	startBtn := gui.NewButton("Start From Run...")
+	startBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path, err := chooseFile("Select a saved run", "Simulation data", "json")
+		if err != nil || path == "" {
+			log.Println("No run selected or error:", err)
+			return
+		}
+		if err := startFromRun(path); err != nil {
+			log.Println("Error initializing field:", err)
+			overlays.Notify("Could not start from run: " + err.Error())
+			return
+		}
+		overlays.Notify("Flow field taken from " + filepath.Base(path))
+	})
+	addSidebarWidget(scene, startBtn)
+}
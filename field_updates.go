@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Edits in the UI that rebuild part of the flow field, like the collision mesh or the
+// seeded ambient wind, don't run in the event handler: they are queued and run from the
+// frame loop, a few per frame. Requests under the same key replace each other, and one
+// only runs once its key has had no new request for fieldUpdateSettle, so dragging a
+// slider rebuilds once when it stops instead of at every step of the drag.
+
+const (
+	fieldUpdateSettle = 150 * time.Millisecond
+	fieldUpdateBudget = 8 * time.Millisecond // of a frame spent on queued updates, after the first
+)
+
+// fieldUpdate is a queued rebuild
+type fieldUpdate struct {
+	key       string
+	run       func()
+	requested time.Time
+}
+
+var fieldUpdates []fieldUpdate
+
+// requestFieldUpdate queues run under key, replacing a request for the same key that
+// hasn't run yet
+func requestFieldUpdate(key string, run func()) {
+	now := time.Now()
+	for i := range fieldUpdates {
+		if fieldUpdates[i].key == key {
+			fieldUpdates[i].run, fieldUpdates[i].requested = run, now
+			return
+		}
+	}
+	fieldUpdates = append(fieldUpdates, fieldUpdate{key: key, run: run, requested: now})
+}
+
+// processFieldUpdates runs the settled requests in the order they were made, until the
+// frame's budget is spent; called once per frame
+func processFieldUpdates(now time.Time) {
+	start := time.Now()
+	// what the updates request while running waits for the next frame
+	queue := fieldUpdates
+	fieldUpdates = nil
+	var pending []fieldUpdate
+	for _, u := range queue {
+		if now.Sub(u.requested) < fieldUpdateSettle || time.Since(start) > fieldUpdateBudget {
+			pending = append(pending, u)
+			continue
+		}
+		began := time.Now()
+		u.run()
+		log.Printf("Field update %q took %v", u.key, time.Since(began))
+	}
+	fieldUpdates = append(pending, fieldUpdates...)
+}
+
+// fieldUpdatesPending reports whether rebuilds are waiting to run
+func fieldUpdatesPending() bool {
+	return len(fieldUpdates) > 0
+}
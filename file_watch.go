@@ -0,0 +1,131 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// The scene and config files in use are watched for changes made outside the app, by
+// hand or by a script, and a reload is offered when one changes. The files are polled
+// by modification time, at most once per fileWatchInterval.
+
+const fileWatchInterval = time.Second
+
+type watchedFile struct {
+	path    string
+	modTime time.Time
+	reload  func() error
+	auto    bool       // reload without asking
+	prompt  *gui.Panel // the reload prompt, while it is open
+}
+
+var (
+	watchedFiles  []*watchedFile
+	lastFileCheck time.Time
+)
+
+// watchFile starts watching path, calling reload when it changes. Watching a file again
+// replaces its reload function and takes its current state as unchanged.
+func watchFile(path string, reload func() error) {
+	path = filepath.Clean(path)
+	for _, w := range watchedFiles {
+		if w.path == path {
+			w.reload = reload
+			w.modTime = fileModTime(path)
+			return
+		}
+	}
+	watchedFiles = append(watchedFiles, &watchedFile{path: path, modTime: fileModTime(path), reload: reload})
+}
+
+// markFileWritten takes the current state of a watched file as unchanged, called after
+// the app writes it itself
+func markFileWritten(path string) {
+	path = filepath.Clean(path)
+	for _, w := range watchedFiles {
+		if w.path == path {
+			w.modTime = fileModTime(path)
+		}
+	}
+}
+
+// fileModTime returns the modification time of path, zero when it doesn't exist
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// updateFileWatch checks the watched files, called once per frame
+func updateFileWatch(now time.Time) {
+	if now.Sub(lastFileCheck) < fileWatchInterval {
+		return
+	}
+	lastFileCheck = now
+	for _, w := range watchedFiles {
+		modTime := fileModTime(w.path)
+		if modTime.Equal(w.modTime) || modTime.IsZero() || (w.prompt != nil && overlays.Showing(w.prompt)) {
+			continue
+		}
+		w.modTime = modTime
+		log.Printf("%s changed on disk", w.path)
+		if w.auto {
+			reloadWatchedFile(w)
+		} else {
+			showReloadPrompt(w)
+		}
+	}
+}
+
+func reloadWatchedFile(w *watchedFile) {
+	if err := w.reload(); err != nil {
+		log.Printf("Reloading %s: %v", w.path, err)
+		overlays.Notify("Could not reload " + w.path + ": " + err.Error())
+		return
+	}
+	overlays.Notify("Reloaded " + w.path)
+}
+
+// showReloadPrompt asks whether to reload a changed file
+func showReloadPrompt(w *watchedFile) {
+	dialog := gui.NewPanel(320, 80)
+	w.prompt = dialog
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	text := gui.NewLabel(filepath.Base(w.path) + " changed on disk. Reload it?")
+	text.SetPosition(10, 10)
+	dialog.Add(text)
+
+	reloadBtn := gui.NewButton("Reload")
+	reloadBtn.SetPosition(10, 45)
+	reloadBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		reloadWatchedFile(w)
+	})
+	dialog.Add(reloadBtn)
+
+	alwaysBtn := gui.NewButton("Always reload")
+	alwaysBtn.SetPosition(80, 45)
+	alwaysBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		w.auto = true
+		overlays.CloseModal(dialog)
+		reloadWatchedFile(w)
+	})
+	dialog.Add(alwaysBtn)
+
+	ignoreBtn := gui.NewButton("Ignore")
+	ignoreBtn.SetPosition(255, 45)
+	ignoreBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(ignoreBtn)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// ParticleFilter hides particles whose attributes fall outside the configured ranges.
+// A zero maximum means the range is unbounded and a negative Source means every source.
+type ParticleFilter struct {
+	Enabled            bool
+	MinSpeed, MaxSpeed float32
+	MinTemp, MaxTemp   float32
+	MaxAge             float32
+	Source             int
+}
+
+var particleFilter = ParticleFilter{
+	MinTemp: -50,
+	MaxTemp: 100,
+	Source:  -1,
+}
+
+// Accept reports whether a particle with the given attributes passes the filter
+func (f *ParticleFilter) Accept(speed, temperature, age float32, source int) bool {
+	if !f.Enabled {
+		return true
+	}
+	if speed < f.MinSpeed || (f.MaxSpeed > 0 && speed > f.MaxSpeed) {
+		return false
+	}
+	if temperature < f.MinTemp || temperature > f.MaxTemp {
+		return false
+	}
+	if f.MaxAge > 0 && age > f.MaxAge {
+		return false
+	}
+	return f.Source < 0 || f.Source == source
+}
+
+// fluidParticleAccepted applies the attribute filter to a fluid particle
+func fluidParticleAccepted(p *Particle) bool {
+	speed := calcMagnitude3D(p.VX, p.VY, p.VZ)
+	return particleFilter.Accept(speed, p.Temperature, p.Age, p.Source)
+}
+
+func windParticleAccepted(i int) bool {
+	w := &windParticles
+	return particleFilter.Accept(w.Velocity[i].Length(), w.Temperature[i], w.Elapsed[i], w.Source[i])
+}
+
+// fluidParticleVisible combines the clip region and the attribute filter
+func fluidParticleVisible(p *Particle) bool {
+	return clipRegion.Visible(math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}) && fluidParticleAccepted(p)
+}
+
+func windParticleVisible(i int) bool {
+	return clipRegion.Visible(windParticles.Position[i]) && windParticleAccepted(i)
+}
+
+// The history keeps only the positions, so the attribute filter is applied when a frame
+// is recorded and its result is kept with the frame; the clip region is applied to the
+// positions whenever a frame is shown or exported. Replaying and the exports thus show
+// the particles that were drawn, cut by the current clip region.
+
+// recordedParticleVisible reports whether particle i of a recorded frame is drawn.
+// accepted holds the filter results recorded with the frame, nil when the frame has
+// none, from a playback file older than them, and every particle passes.
+func recordedParticleVisible(positions []math32.Vector3, accepted []bool, i int) bool {
+	return clipRegion.Visible(positions[i]) && (i >= len(accepted) || accepted[i])
+}
+
+// visibleRecorded returns the positions of a recorded frame that are drawn
+func visibleRecorded(positions []math32.Vector3, accepted []bool) []math32.Vector3 {
+	visible := make([]math32.Vector3, 0, len(positions))
+	for i := range positions {
+		if recordedParticleVisible(positions, accepted, i) {
+			visible = append(visible, positions[i])
+		}
+	}
+	return visible
+}
+
+// applyParticleVisibility shows or hides every particle mesh for the current frame,
+// particles outside the camera's view are hidden too
+func applyParticleVisibility() {
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		if p.Mesh != nil {
+			p.Mesh.SetVisible(fluidParticleVisible(p) && inView(math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}))
+		}
+	}
+	for i, m := range windParticles.Mesh {
+		m.SetVisible(windParticleVisible(i) && inView(windParticles.Position[i]))
+	}
+}
+
+func initializeFilterUI(scene *core.Node) {
+	panel := gui.NewPanel(240, 175)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+
+	title := gui.NewLabel("Particle filter")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	enableCheck := gui.NewCheckBox("Enabled")
+	enableCheck.SetPosition(110, 5)
+	enableCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		particleFilter.Enabled = enableCheck.Value()
+	})
+	panel.Add(enableCheck)
+
+	rows := []struct {
+		label      string
+		min, max   *float32
+		lower, top float32
+	}{
+		{"Speed", &particleFilter.MinSpeed, &particleFilter.MaxSpeed, 0, 100},
+		{"Temp °C", &particleFilter.MinTemp, &particleFilter.MaxTemp, -50, 150},
+	}
+	for i, row := range rows {
+		min, max := row.min, row.max
+		y := 30 + float32(i)*25
+		label := gui.NewLabel(row.label)
+		label.SetPosition(10, y+3)
+		panel.Add(label)
+		minInput := NewNumericInput(*min, row.lower, row.top, 0.5, "", func(value float32) { *min = value })
+		minInput.SetPosition(65, y)
+		panel.Add(minInput)
+		maxInput := NewNumericInput(*max, row.lower, row.top, 0.5, "", func(value float32) { *max = value })
+		maxInput.SetPosition(150, y)
+		panel.Add(maxInput)
+	}
+
+	ageLabel := gui.NewLabel("Max age")
+	ageLabel.SetPosition(10, 83)
+	panel.Add(ageLabel)
+	ageInput := NewNumericInput(particleFilter.MaxAge, 0, 60, 0.5, "s", func(value float32) {
+		particleFilter.MaxAge = value
+	})
+	ageInput.SetPosition(65, 80)
+	panel.Add(ageInput)
+
+	sourceLabel := gui.NewLabel("Source")
+	sourceLabel.SetPosition(10, 108)
+	panel.Add(sourceLabel)
+	sourceInput := NewNumericInput(float32(particleFilter.Source), -1, 99, 1, "", func(value float32) {
+		particleFilter.Source = int(value)
+	})
+	sourceInput.SetPosition(65, 105)
+	panel.Add(sourceInput)
+
+	hint := gui.NewLabel("0 max = no limit, source -1 = all")
+	hint.SetPosition(10, 140)
+	panel.Add(hint)
+
+}
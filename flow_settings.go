@@ -0,0 +1,171 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// showFlowSettingsDialog edits the flow solver configuration
+func showFlowSettingsDialog(scene *core.Node) {
+	dialog := gui.NewPanel(320, 345)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Flow settings")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	solverLabel := gui.NewLabel("Solver")
+	solverLabel.SetPosition(10, 38)
+	dialog.Add(solverLabel)
+	solverBtn := gui.NewButton(solverModeNames[simConfig.Solver])
+	solverBtn.SetPosition(140, 35)
+	solverBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		simConfig.Solver = (simConfig.Solver + 1) % SolverMode(len(solverModeNames))
+		solverBtn.Label.SetText(solverModeNames[simConfig.Solver])
+		potentialFor = nil
+		clearVortexParticles()
+	})
+	dialog.Add(solverBtn)
+
+	symmetryLabel := gui.NewLabel("Symmetry plane")
+	symmetryLabel.SetPosition(10, 68)
+	dialog.Add(symmetryLabel)
+	symmetryBtn := gui.NewButton(domainFaceNames[simConfig.SymmetryPlane])
+	symmetryBtn.SetPosition(140, 65)
+	dialog.Add(symmetryBtn)
+
+	periodicLabel := gui.NewLabel("Periodic axis")
+	periodicLabel.SetPosition(10, 98)
+	dialog.Add(periodicLabel)
+	periodicBtn := gui.NewButton(periodicAxisNames[simConfig.Periodic])
+	periodicBtn.SetPosition(140, 95)
+	dialog.Add(periodicBtn)
+
+	vorticityLabel := gui.NewLabel("Vorticity conf.")
+	vorticityLabel.SetPosition(10, 128)
+	dialog.Add(vorticityLabel)
+	vorticityInput := NewNumericInput(simConfig.VorticityEpsilon, 0, 2, 0.05, "", func(value float32) {
+		simConfig.VorticityEpsilon = value
+	})
+	vorticityInput.SetPosition(140, 125)
+	dialog.Add(vorticityInput)
+
+	turbulenceLabel := gui.NewLabel("Turbulence")
+	turbulenceLabel.SetPosition(10, 158)
+	dialog.Add(turbulenceLabel)
+	turbulenceBtn := gui.NewButton(turbulenceModelNames[simConfig.TurbulenceModel])
+	turbulenceBtn.SetPosition(140, 155)
+	turbulenceBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		simConfig.TurbulenceModel = (simConfig.TurbulenceModel + 1) % TurbulenceModel(len(turbulenceModelNames))
+		turbulenceBtn.Label.SetText(turbulenceModelNames[simConfig.TurbulenceModel])
+		// start the model from the ambient level rather than a stale state
+		vectorField.tke = nil
+	})
+	dialog.Add(turbulenceBtn)
+
+	integratorLabel := gui.NewLabel("Particle steps")
+	integratorLabel.SetPosition(10, 188)
+	dialog.Add(integratorLabel)
+	integratorBtn := gui.NewButton(integratorNames[simConfig.Integrator])
+	integratorBtn.SetPosition(140, 185)
+	integratorBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		simConfig.Integrator = (simConfig.Integrator + 1) % Integrator(len(integratorNames))
+		integratorBtn.Label.SetText(integratorNames[simConfig.Integrator])
+	})
+	dialog.Add(integratorBtn)
+
+	buoyancyCheck := gui.NewCheckBox("Buoyancy")
+	buoyancyCheck.SetPosition(10, 218)
+	buoyancyCheck.SetValue(simConfig.Buoyancy)
+	buoyancyCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		simConfig.Buoyancy = buoyancyCheck.Value()
+	})
+	dialog.Add(buoyancyCheck)
+
+	noSlipCheck := gui.NewCheckBox("No-slip walls")
+	noSlipCheck.SetPosition(140, 218)
+	noSlipCheck.SetValue(simConfig.NoSlip)
+	noSlipCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		simConfig.NoSlip = noSlipCheck.Value()
+	})
+	dialog.Add(noSlipCheck)
+
+	collisionCheck := gui.NewCheckBox("Particle collisions")
+	collisionCheck.SetPosition(10, 248)
+	collisionCheck.SetValue(simConfig.ParticleCollisions)
+	collisionCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		simConfig.ParticleCollisions = collisionCheck.Value()
+	})
+	dialog.Add(collisionCheck)
+	radiusInput := NewNumericInput(simConfig.ParticleRadius, 0.005, 1, 0.005, "m", func(value float32) {
+		simConfig.ParticleRadius = value
+	})
+	radiusInput.SetPosition(180, 245)
+	dialog.Add(radiusInput)
+
+	frictionCheck := gui.NewCheckBox("Ground friction")
+	frictionCheck.SetPosition(10, 278)
+	frictionCheck.SetValue(simConfig.GroundFriction)
+	frictionCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		simConfig.GroundFriction = frictionCheck.Value()
+	})
+	dialog.Add(frictionCheck)
+	roughnessInput := NewNumericInput(simConfig.GroundRoughness, 0.0001, 2, 0.005, "m z0", func(value float32) {
+		simConfig.GroundRoughness = value
+	})
+	roughnessInput.SetPosition(180, 275)
+	dialog.Add(roughnessInput)
+
+	// a face can't be both a symmetry plane and periodic, choosing one clears the other
+	conflicting := func() bool {
+		periodic, ok := simConfig.Periodic.axis()
+		symmetry, _ := simConfig.SymmetryPlane.axis()
+		return ok && simConfig.SymmetryPlane != FaceNone && periodic == symmetry
+	}
+	symmetryBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		simConfig.SymmetryPlane = (simConfig.SymmetryPlane + 1) % DomainFace(len(domainFaceNames))
+		if conflicting() {
+			simConfig.Periodic = PeriodicNone
+		}
+		symmetryBtn.Label.SetText(domainFaceNames[simConfig.SymmetryPlane])
+		periodicBtn.Label.SetText(periodicAxisNames[simConfig.Periodic])
+		vectorField.topology = nil
+	})
+	periodicBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		simConfig.Periodic = (simConfig.Periodic + 1) % PeriodicAxis(len(periodicAxisNames))
+		if conflicting() {
+			simConfig.SymmetryPlane = FaceNone
+		}
+		symmetryBtn.Label.SetText(domainFaceNames[simConfig.SymmetryPlane])
+		periodicBtn.Label.SetText(periodicAxisNames[simConfig.Periodic])
+		vectorField.topology = nil
+	})
+
+	// the check stays off and can't be set when the driver has no compute shaders
+	gpuCheck := gui.NewCheckBox("GPU field passes")
+	gpuCheck.SetPosition(10, 313)
+	gpuCheck.SetValue(simConfig.GPUCompute && fieldCompute != nil)
+	gpuCheck.SetEnabled(fieldCompute != nil)
+	gpuCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		simConfig.GPUCompute = gpuCheck.Value()
+	})
+	dialog.Add(gpuCheck)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(260, 310)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeFlowSettingsUI(scene *core.Node) {
+	settingsBtn := gui.NewButton("Flow Settings...")
+	settingsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showFlowSettingsDialog(scene)
+	})
+	addSidebarWidget(scene, settingsBtn)
+}
@@ -0,0 +1,1068 @@
+package main
+
+import (
+	"github.com/g3n/demos/hellog3n/voxel"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// SimulationConfig holds the parameters of the flow solver and the physical constants
+// of the simulation. It is read from simulationConfigFile at startup when present.
+type SimulationConfig struct {
+	Solver           SolverMode
+	SolverIterations int     // Gauss-Seidel iterations of the pressure projection
+	Viscosity        float32 // kinematic viscosity in m²/s
+	DiffusionIters   int     // Jacobi iterations of the viscous diffusion
+	SymmetryPlane    DomainFace
+	Periodic         PeriodicAxis
+	VorticityEpsilon float32 // strength of the vorticity confinement, 0 turns it off
+	TurbulenceModel  TurbulenceModel
+	Buoyancy         bool       // warm air rises, cool air sinks
+	NoSlip           bool       // the flow sticks to obstacle surfaces instead of sliding along them
+	Integrator       Integrator // how particles are moved through the flow
+	StepRate         float32    // fixed simulation steps per simulated second
+	FieldRate        float32    // flow field updates per simulated second, at most StepRate
+
+	AirDensity    float32 // kg/m³
+	Gravity       float32 // m/s² along Y, negative is down
+	ReferenceArea float32 // m², the frontal area the model's drag coefficient refers to
+	LengthUnit    float32 // meters per scene unit
+
+	// ReferenceChord is the length in m the moment coefficient refers to, and
+	// FreestreamSpeed the speed in m/s all coefficients do, 0 taking it from the wind
+	ReferenceChord  float32
+	FreestreamSpeed float32
+
+	// ParticleCollisions keeps particles from overlapping, each a sphere of
+	// ParticleRadius m, see particle_collisions.go
+	ParticleCollisions bool
+	ParticleRadius     float32
+
+	// GroundFriction slows the flow along the floor by the log law over ground of
+	// roughness length GroundRoughness in m, see ground_friction.go
+	GroundFriction  bool
+	GroundRoughness float32
+
+	// GPUCompute runs the source, advection and diffusion passes as compute shaders
+	// when the GL driver offers them, see field_compute.go
+	GPUCompute bool
+}
+
+// SolverMode selects what moves the air
+type SolverMode int
+
+const (
+	SolverGrid      SolverMode = iota // the staggered grid below
+	SolverSPH                         // smoothed particle hydrodynamics on the wind particles, see sph.go
+	SolverPotential                   // steady potential flow for quick layouts, see potential_flow.go
+	SolverVortex                      // vortex particles for sharp wakes, see vortex_particles.go
+)
+
+var solverModeNames = []string{"grid", "SPH", "potential", "vortex"}
+
+// DomainFace names one of the faces of the domain other than the ground
+type DomainFace int
+
+const (
+	FaceNone DomainFace = iota
+	FaceMinX
+	FaceMaxX
+	FaceMinZ
+	FaceMaxZ
+	FaceTop
+)
+
+var domainFaceNames = []string{"none", "-X", "+X", "-Z", "+Z", "top"}
+
+// PeriodicAxis selects a pair of opposite side faces that are coupled, so flow leaving
+// through one re-enters through the other
+type PeriodicAxis int
+
+const (
+	PeriodicNone PeriodicAxis = iota
+	PeriodicX
+	PeriodicZ
+)
+
+var periodicAxisNames = []string{"none", "X", "Z"}
+
+// axis returns the grid axis of the coupling, ok is false when there is none
+func (p PeriodicAxis) axis() (int, bool) {
+	switch p {
+	case PeriodicX:
+		return axisX, true
+	case PeriodicZ:
+		return axisZ, true
+	}
+	return 0, false
+}
+
+// axis returns the grid axis the face is normal to and whether it is the high end
+func (d DomainFace) axis() (int, bool) {
+	switch d {
+	case FaceMinX:
+		return axisX, false
+	case FaceMaxX:
+		return axisX, true
+	case FaceMinZ:
+		return axisZ, false
+	case FaceMaxZ:
+		return axisZ, true
+	}
+	return axisY, true
+}
+
+var simConfig = SimulationConfig{
+	SolverIterations: 20,
+	Viscosity:        0.01,
+	DiffusionIters:   10,
+	VorticityEpsilon: 0.2,
+	Buoyancy:         true,
+	NoSlip:           true,
+	StepRate:         120,
+	FieldRate:        30,
+	AirDensity:       1.225,
+	Gravity:          -9.8,
+	ReferenceArea:    1,
+	LengthUnit:       1,
+	ReferenceChord:   1,
+	ParticleRadius:   0.1,
+	GroundFriction:   true,
+	GroundRoughness:  0.01,
+	GPUCompute:       true,
+}
+
+// maxSubsteps bounds the work per frame when the flow gets fast, maxFieldSpeed keeps
+// the explicit steps stable when even that is not enough. maxTraceCells is how far the
+// advection may trace back in one substep.
+const (
+	maxSubsteps   = 8
+	maxFieldSpeed = 30
+	maxTraceCells = 2
+)
+
+// sorOmega over-relaxes the pressure iterations; between 1 (plain Gauss-Seidel) and 2
+const sorOmega = 1.7
+
+// The field is a staggered (MAC) grid with cubic cells. VX is stored on the low x face of
+// a cell, VY on its low y face and VZ on its low z face. The outermost layer of cells on
+// the four sides and the top is an open boundary that copies the flow next to it and holds
+// zero pressure; the ground below the first layer is a wall. Cells covered by the model
+// are solid and no flow passes through their faces; with no-slip the faces running along
+// them hold no flow either.
+//
+// Along a periodic axis the boundary cells are ghosts of the cells at the opposite end of
+// the interior instead: the flow and pressure wrap around, as in an endless channel.
+//
+// One of the open faces can be made a symmetry plane instead: the flow mirrors there, so
+// nothing passes through it and the flow along it slips freely. A symmetric model can then
+// be cut in half at the plane and simulated in half the domain.
+
+// axis indexes the three grid directions
+const (
+	axisX = iota
+	axisY
+	axisZ
+)
+
+// CellSize returns the edge length of a cell in meters
+func (f *VectorField) CellSize() float32 {
+	return float32(f.Width) / float32(f.AreaWidth)
+}
+
+// origin is the world position of the low corner of the domain. The domain is centered
+// on the origin in x and z and starts at the ground.
+func (f *VectorField) origin() math32.Vector3 {
+	return math32.Vector3{X: -float32(f.Width) / 2, Y: 0, Z: -float32(f.Depth) / 2}
+}
+
+func (f *VectorField) dims() [3]int {
+	return [3]int{f.AreaWidth, f.AreaHeight, f.AreaDepth}
+}
+
+// index is where cell (i, j, k) sits in Field and in the per cell slices
+func (f *VectorField) index(i, j, k int) int {
+	return (i*f.AreaHeight+j)*f.AreaDepth + k
+}
+
+// cell returns the values of cell c
+func (f *VectorField) cell(c [3]int) *Vector {
+	return &f.Field[f.index(c[0], c[1], c[2])]
+}
+
+// interior returns the range of cells along axis that are solved, the rest is boundary
+func (f *VectorField) interior(axis int) (int, int) {
+	n := f.dims()[axis]
+	if axis == axisY {
+		return 0, n - 2
+	}
+	return 1, n - 2
+}
+
+// cellAt returns the cell containing pos and whether pos is inside the domain
+func (f *VectorField) cellAt(pos math32.Vector3) (int, int, int, bool) {
+	o := f.origin()
+	h := f.CellSize()
+	i := int(math32.Floor((pos.X - o.X) / h))
+	j := int(math32.Floor((pos.Y - o.Y) / h))
+	k := int(math32.Floor((pos.Z - o.Z) / h))
+	inside := i >= 0 && i < f.AreaWidth && j >= 0 && j < f.AreaHeight && k >= 0 && k < f.AreaDepth
+	return i, j, k, inside
+}
+
+// cellCenter returns the world position of the center of a cell
+func (f *VectorField) cellCenter(i, j, k int) math32.Vector3 {
+	o := f.origin()
+	h := f.CellSize()
+	return math32.Vector3{X: o.X + (float32(i)+0.5)*h, Y: o.Y + (float32(j)+0.5)*h, Z: o.Z + (float32(k)+0.5)*h}
+}
+
+// SampleVelocity returns the flow velocity at pos, trilinearly interpolated between the
+// surrounding faces of each component, zero outside the domain
+func (f *VectorField) SampleVelocity(pos math32.Vector3) math32.Vector3 {
+	if _, _, _, inside := f.cellAt(pos); !inside {
+		return math32.Vector3{}
+	}
+	current := func(axis int, c [3]int) float32 {
+		return f.face(axis, c[0], c[1], c[2])
+	}
+	// along the floor the flow follows the boundary layer below the first cell centers
+	ground := f.groundProfile(pos.Y - f.origin().Y)
+	return math32.Vector3{
+		X: f.sampleComponent(axisX, pos, current) * ground,
+		Y: f.sampleComponent(axisY, pos, current),
+		Z: f.sampleComponent(axisZ, pos, current) * ground,
+	}
+}
+
+// sampleComponent trilinearly interpolates the velocity component along axis at pos from
+// the eight nearest faces. The component sits on the low faces, so along axis its samples
+// are half a cell below the cell centers. read returns the component of a cell and must
+// clamp indices outside the grid.
+func (f *VectorField) sampleComponent(axis int, pos math32.Vector3, read func(axis int, c [3]int) float32) float32 {
+	o := f.origin()
+	h := f.CellSize()
+	g := [3]float32{(pos.X-o.X)/h - 0.5, (pos.Y-o.Y)/h - 0.5, (pos.Z-o.Z)/h - 0.5}
+	g[axis] += 0.5
+	var base [3]int
+	var t [3]float32
+	for a := 0; a < 3; a++ {
+		fl := math32.Floor(g[a])
+		base[a] = int(fl)
+		t[a] = g[a] - fl
+	}
+	var sum float32
+	for corner := 0; corner < 8; corner++ {
+		c := base
+		w := float32(1)
+		for a := 0; a < 3; a++ {
+			if corner>>a&1 == 1 {
+				c[a]++
+				w *= t[a]
+			} else {
+				w *= 1 - t[a]
+			}
+		}
+		sum += w * read(axis, c)
+	}
+	return sum
+}
+
+// clampCell clamps cell indices into the grid
+func (f *VectorField) clampCell(c [3]int) [3]int {
+	d := f.dims()
+	for a := 0; a < 3; a++ {
+		if c[a] < 0 {
+			c[a] = 0
+		} else if c[a] >= d[a] {
+			c[a] = d[a] - 1
+		}
+	}
+	return c
+}
+
+// face returns the current velocity component on the low face along axis of a cell,
+// indices outside the grid are clamped
+func (f *VectorField) face(axis, i, j, k int) float32 {
+	c := f.clampCell([3]int{i, j, k})
+	v := f.cell(c)
+	switch axis {
+	case axisX:
+		return v.VX
+	case axisY:
+		return v.VY
+	}
+	return v.VZ
+}
+
+// prev is like face but reads the copy made by saveVelocities
+func (f *VectorField) prev(axis int, c [3]int) float32 {
+	c = f.clampCell(c)
+	v := f.cell(c)
+	switch axis {
+	case axisX:
+		return v.VX_
+	case axisY:
+		return v.VY_
+	}
+	return v.VZ_
+}
+
+func (f *VectorField) setFace(axis int, c [3]int, value float32) {
+	v := f.cell(c)
+	switch axis {
+	case axisX:
+		v.VX = value
+	case axisY:
+		v.VY = value
+	default:
+		v.VZ = value
+	}
+}
+
+// saveVelocities copies the velocities into the VX_/VY_/VZ_ scratch values
+func (f *VectorField) saveVelocities() {
+	for i := range f.Field {
+		v := &f.Field[i]
+		v.VX_, v.VY_, v.VZ_ = v.VX, v.VY, v.VZ
+	}
+}
+
+func (f *VectorField) forEachCell(fn func(c [3]int)) {
+	for i := 0; i < f.AreaWidth; i++ {
+		for j := 0; j < f.AreaHeight; j++ {
+			for k := 0; k < f.AreaDepth; k++ {
+				fn([3]int{i, j, k})
+			}
+		}
+	}
+}
+
+func (f *VectorField) isInterior(c [3]int) bool {
+	for a := 0; a < 3; a++ {
+		lo, hi := f.interior(a)
+		if c[a] < lo || c[a] > hi {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *VectorField) isSolid(c [3]int) bool {
+	return f.solid != nil && f.isInterior(c) && f.solid[f.index(c[0], c[1], c[2])]
+}
+
+// faceActive reports whether the low face along axis of cell c lies between solved cells
+// (or a solved cell and the open boundary), i.e. whether the solver updates it
+func (f *VectorField) faceActive(axis int, c [3]int) bool {
+	for a := 0; a < 3; a++ {
+		lo, hi := f.faceRange(axis, a)
+		if c[a] < lo || c[a] > hi {
+			return false
+		}
+	}
+	return true
+}
+
+// faceRange is the range of cell indices along a whose low faces along axis are active
+func (f *VectorField) faceRange(axis, a int) (int, int) {
+	lo, hi := f.interior(a)
+	if a == axis {
+		if axis == axisY {
+			lo = 1 // the ground face is a wall
+		}
+		hi++
+	}
+	return lo, hi
+}
+
+// onSymmetryPlane reports whether the low face along axis of cell c lies on the symmetry plane
+func (f *VectorField) onSymmetryPlane(axis int, c [3]int) bool {
+	if simConfig.SymmetryPlane == FaceNone {
+		return false
+	}
+	planeAxis, high := simConfig.SymmetryPlane.axis()
+	if axis != planeAxis {
+		return false
+	}
+	lo, hi := f.interior(axis)
+	if high {
+		return c[axis] == hi+1
+	}
+	return c[axis] == lo
+}
+
+// beyondSymmetryPlane reports whether cell c lies on the far side of the symmetry plane
+func (f *VectorField) beyondSymmetryPlane(c [3]int) bool {
+	if simConfig.SymmetryPlane == FaceNone {
+		return false
+	}
+	axis, high := simConfig.SymmetryPlane.axis()
+	lo, hi := f.interior(axis)
+	if high {
+		return c[axis] > hi
+	}
+	return c[axis] < lo
+}
+
+// periodicGhost reports whether c is a boundary cell on the periodic axis. All its faces are
+// copies of the cell at the other end of the interior, including the face closing the
+// interior at the high end, which is the same face as the one opening it at the low end.
+func (f *VectorField) periodicGhost(c [3]int) bool {
+	a, ok := simConfig.Periodic.axis()
+	if !ok {
+		return false
+	}
+	lo, hi := f.interior(a)
+	return c[a] < lo || c[a] > hi
+}
+
+// wrapPeriodic maps a cell outside the interior along the periodic axis onto the interior
+// cell it stands for
+func (f *VectorField) wrapPeriodic(c [3]int) [3]int {
+	a, ok := simConfig.Periodic.axis()
+	if !ok {
+		return c
+	}
+	lo, hi := f.interior(a)
+	length := hi - lo + 1
+	for c[a] < lo {
+		c[a] += length
+	}
+	for c[a] > hi {
+		c[a] -= length
+	}
+	return c
+}
+
+// wrapParticle moves a particle that left the domain along the periodic axis back in at
+// the other end
+func (f *VectorField) wrapParticle(pos *math32.Vector3) {
+	a, ok := simConfig.Periodic.axis()
+	if !ok {
+		return
+	}
+	lo, hi := f.interior(a)
+	o := f.origin()
+	h := f.CellSize()
+	start := o.Component(a) + float32(lo)*h
+	length := float32(hi-lo+1) * h
+	p := pos.Component(a)
+	for p < start {
+		p += length
+	}
+	for p >= start+length {
+		p -= length
+	}
+	pos.SetComponent(a, p)
+}
+
+// symmetryPlane returns the axis of the symmetry plane, its position along that axis and
+// whether it is at the high end of the domain; ok is false when there is none
+func (f *VectorField) symmetryPlane() (axis int, at float32, high, ok bool) {
+	if simConfig.SymmetryPlane == FaceNone {
+		return 0, 0, false, false
+	}
+	axis, high = simConfig.SymmetryPlane.axis()
+	lo, hi := f.interior(axis)
+	index := lo
+	if high {
+		index = hi + 1
+	}
+	o := f.origin()
+	start := [3]float32{o.X, o.Y, o.Z}[axis]
+	return axis, start + float32(index)*f.CellSize(), high, true
+}
+
+// mirrorAtSymmetryPlane reflects a particle that crossed the symmetry plane back into the
+// domain, as its mirror image on the other side would have come in
+func (f *VectorField) mirrorAtSymmetryPlane(pos, vel *math32.Vector3) {
+	axis, at, high, ok := f.symmetryPlane()
+	if !ok {
+		return
+	}
+	p := pos.Component(axis)
+	if (high && p > at) || (!high && p < at) {
+		pos.SetComponent(axis, 2*at-p)
+		vel.SetComponent(axis, -vel.Component(axis))
+	}
+}
+
+// faceBlocked reports whether a face touches a solid cell
+func (f *VectorField) faceBlocked(axis int, c [3]int) bool {
+	below := c
+	below[axis]--
+	return f.isSolid(c) || f.isSolid(below)
+}
+
+// alongSolid reports whether one of the two cells sharing a face has a solid neighbor
+// across the face's tangential directions, so the face runs along an obstacle surface
+func (f *VectorField) alongSolid(axis int, c [3]int) bool {
+	below := c
+	below[axis]--
+	for a := 0; a < 3; a++ {
+		if a == axis {
+			continue
+		}
+		for _, cell := range [][3]int{c, below} {
+			for _, d := range []int{-1, 1} {
+				nb := cell
+				nb[a] += d
+				if f.isSolid(nb) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// faceRef names the low face along axis of cell c
+type faceRef struct {
+	axis int
+	c    [3]int
+}
+
+// Neighbour markers in fieldTopology.neighbors
+const (
+	neighborWall = -1 // no flow through it, so no pressure gradient across it
+	neighborOpen = -2 // open boundary, zero pressure
+)
+
+// fieldTopology is what the solver loops over, derived from the grid size and the solid
+// cells and rebuilt whenever those change
+type fieldTopology struct {
+	openFaces     []faceRef    // faces the solver updates
+	closedFaces   []faceRef    // active faces touching a solid cell, held at zero
+	wallFaces     []faceRef    // open faces running along a solid cell, zero with no-slip
+	boundaryFaces [][2]faceRef // boundary face and the active face it copies
+	groundFaces   []faceRef
+	floorFaces    []faceRef // open faces along the ground, slowed by ground friction
+	fluidCells    []int     // flat indices of the solved cells
+	fluidCoords   [][3]int  // the same cells as grid coordinates
+	neighbors     [][6]int  // per fluid cell: flat neighbour index or a neighbor marker
+}
+
+func (f *VectorField) buildTopology() {
+	t := &fieldTopology{}
+	f.forEachCell(func(c [3]int) {
+		for axis := 0; axis < 3; axis++ {
+			ref := faceRef{axis, c}
+			switch {
+			case axis == axisY && c[1] == 0:
+				t.groundFaces = append(t.groundFaces, ref)
+			case f.periodicGhost(c):
+				src := f.wrapPeriodic(c)
+				for a := 0; a < 3; a++ {
+					lo, hi := f.faceRange(axis, a)
+					src[a] = int(clamp(float32(src[a]), float32(lo), float32(hi)))
+				}
+				t.boundaryFaces = append(t.boundaryFaces, [2]faceRef{ref, {axis, src}})
+			case !f.faceActive(axis, c):
+				src := c
+				for a := 0; a < 3; a++ {
+					lo, hi := f.faceRange(axis, a)
+					src[a] = int(clamp(float32(src[a]), float32(lo), float32(hi)))
+				}
+				t.boundaryFaces = append(t.boundaryFaces, [2]faceRef{ref, {axis, src}})
+			case f.faceBlocked(axis, c) || f.onSymmetryPlane(axis, c):
+				t.closedFaces = append(t.closedFaces, ref)
+			default:
+				t.openFaces = append(t.openFaces, ref)
+				if f.alongSolid(axis, c) {
+					t.wallFaces = append(t.wallFaces, ref)
+				}
+				if axis != axisY && c[1] == 0 {
+					t.floorFaces = append(t.floorFaces, ref)
+				}
+			}
+		}
+		if f.isInterior(c) && !f.isSolid(c) {
+			t.fluidCells = append(t.fluidCells, f.index(c[0], c[1], c[2]))
+			t.fluidCoords = append(t.fluidCoords, c)
+		}
+	})
+	t.neighbors = make([][6]int, len(t.fluidCells))
+	for n, c := range t.fluidCoords {
+		for a := 0; a < 3; a++ {
+			for s, d := range []int{-1, 1} {
+				nb := c
+				nb[a] += d
+				switch {
+				case nb[a] < 0 || f.isSolid(nb) || f.beyondSymmetryPlane(nb):
+					t.neighbors[n][a*2+s] = neighborWall
+				case f.periodicGhost(nb):
+					w := f.wrapPeriodic(nb)
+					if f.isSolid(w) {
+						t.neighbors[n][a*2+s] = neighborWall
+					} else {
+						t.neighbors[n][a*2+s] = f.index(w[0], w[1], w[2])
+					}
+				case !f.isInterior(nb):
+					t.neighbors[n][a*2+s] = neighborOpen
+				default:
+					t.neighbors[n][a*2+s] = f.index(nb[0], nb[1], nb[2])
+				}
+			}
+		}
+	}
+	f.topology = t
+}
+
+// Step advances the flow by dt: wind sources, advection, buoyancy, diffusion and the
+// pressure projection that keeps the flow incompressible, then the temperature
+func (f *VectorField) Step(dt float32, sources []WindSource) {
+	f.prepare()
+	h := f.CellSize()
+
+	// semi-Lagrangian advection is stable at any step, substeps only keep the back
+	// traces short enough to stay accurate
+	steps := int(math32.Ceil(f.maxSpeed() * dt / (maxTraceCells * h)))
+	if steps < 1 {
+		steps = 1
+	} else if steps > maxSubsteps {
+		steps = maxSubsteps
+	}
+	sub := dt / float32(steps)
+	f.substep = sub
+	f.keepStepStart()
+	for s := 0; s < steps; s++ {
+		if fieldCompute.usable(f) {
+			fieldCompute.applySourcesAndAdvect(f, sources, sub)
+		} else {
+			f.applySources(sources)
+			f.advect(sub)
+		}
+		f.confineVorticity(sub)
+		f.applyBuoyancy(sub)
+		if simConfig.TurbulenceModel == TurbulenceLES {
+			f.updateSubgridViscosity()
+		}
+		f.diffuse(sub)
+		f.applyGroundFriction(sub)
+		f.applyNoSlip()
+		f.project(simConfig.SolverIterations)
+		f.applyBoundaries()
+		f.applyAmbientWind()
+		if simConfig.TurbulenceModel == TurbulenceKEpsilon {
+			f.updateTurbulence(sub, sources)
+		}
+		f.updateTemperature(sub, sources)
+	}
+	f.storePressure()
+	f.measureChange(dt)
+}
+
+// prepare builds the topology and the pressure buffers when they are missing
+func (f *VectorField) prepare() {
+	if f.topology == nil {
+		f.buildTopology()
+	}
+	if f.pressure == nil {
+		n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+		f.pressure = make([]float32, n)
+		f.divergence = make([]float32, n)
+	}
+}
+
+// keepStepStart remembers the velocities at the start of a step for measureChange
+func (f *VectorField) keepStepStart() {
+	faces := f.topology.openFaces
+	if len(f.stepStart) != len(faces) {
+		f.stepStart = make([]float32, len(faces))
+	}
+	for n, face := range faces {
+		f.stepStart[n] = f.face(face.axis, face.c[0], face.c[1], face.c[2])
+	}
+}
+
+// measureChange sets changeRate to how fast the flow changed during the last step: the RMS
+// change of the face velocities per second relative to their RMS value
+func (f *VectorField) measureChange(dt float32) {
+	var delta, speed float32
+	for n, face := range f.topology.openFaces {
+		v := f.face(face.axis, face.c[0], face.c[1], face.c[2])
+		d := v - f.stepStart[n]
+		delta += d * d
+		speed += v * v
+	}
+	if speed < 1e-6 || dt <= 0 {
+		f.changeRate = 0
+		return
+	}
+	f.changeRate = math32.Sqrt(delta/speed) / dt
+}
+
+// ChangeRate is the relative change of the flow per second during the last step, it goes
+// to zero as the flow settles
+func (f *VectorField) ChangeRate() float32 {
+	return f.changeRate
+}
+
+func (f *VectorField) maxSpeed() float32 {
+	var max float32
+	for i := range f.Field {
+		v := &f.Field[i]
+		for _, s := range []float32{v.VX, v.VY, v.VZ} {
+			if math32.Abs(s) > max {
+				max = math32.Abs(s)
+			}
+		}
+	}
+	return max
+}
+
+// applySources sets the flow in the cells inside each wind source to the source velocity
+func (f *VectorField) applySources(sources []WindSource) {
+	if len(sources) == 0 {
+		return
+	}
+	targets := make([][3]float32, len(sources))
+	for s := range sources {
+		wind := &sources[s]
+		target := wind.Direction.Clone().Normalize().MultiplyScalar(math32.Min(wind.Speed, maxFieldSpeed))
+		targets[s] = [3]float32{target.X, target.Y, target.Z}
+	}
+	faces := f.topology.openFaces
+	// every face only writes itself, so the faces are split among the cores; where sources
+	// overlap the last one still wins, as the sources are tried in order for each face
+	parallelRange(len(faces), func(lo, hi int) {
+		for _, face := range faces[lo:hi] {
+			center := f.cellCenter(face.c[0], face.c[1], face.c[2])
+			for s := range sources {
+				if center.DistanceTo(&sources[s].Position) <= sources[s].Radius {
+					f.setFace(face.axis, face.c, targets[s][face.axis])
+				}
+			}
+		}
+	})
+}
+
+// advect transports the velocities through the grid semi-Lagrangian style: every face
+// traces the flow back over dt and takes the interpolated velocity it finds there
+func (f *VectorField) advect(dt float32) {
+	f.saveVelocities()
+	h := f.CellSize()
+	faces := f.topology.openFaces
+	// every face only reads the saved velocities and writes itself, so the faces are
+	// split among the cores
+	parallelRange(len(faces), func(lo, hi int) {
+		for _, face := range faces[lo:hi] {
+			axis, c := face.axis, face.c
+			pos := f.cellCenter(c[0], c[1], c[2])
+			switch axis {
+			case axisX:
+				pos.X -= h / 2
+			case axisY:
+				pos.Y -= h / 2
+			default:
+				pos.Z -= h / 2
+			}
+			vel := math32.Vector3{
+				X: f.sampleComponent(axisX, pos, f.prev),
+				Y: f.sampleComponent(axisY, pos, f.prev),
+				Z: f.sampleComponent(axisZ, pos, f.prev),
+			}
+			pos.Sub(vel.MultiplyScalar(dt))
+			f.setFace(axis, c, clamp(f.sampleComponent(axis, pos, f.prev), -maxFieldSpeed, maxFieldSpeed))
+		}
+	})
+}
+
+// cellVelocity is the flow at the center of a cell, averaged from its faces
+func (f *VectorField) cellVelocity(c [3]int) math32.Vector3 {
+	return math32.Vector3{
+		X: (f.face(axisX, c[0], c[1], c[2]) + f.face(axisX, c[0]+1, c[1], c[2])) / 2,
+		Y: (f.face(axisY, c[0], c[1], c[2]) + f.face(axisY, c[0], c[1]+1, c[2])) / 2,
+		Z: (f.face(axisZ, c[0], c[1], c[2]) + f.face(axisZ, c[0], c[1], c[2]+1)) / 2,
+	}
+}
+
+// velocityGradient returns the derivatives of the flow at the center of a cell by central
+// differences, d[a][b] is the derivative of component b along axis a
+func (f *VectorField) velocityGradient(c [3]int) [3][3]float32 {
+	h := f.CellSize()
+	var d [3][3]float32
+	for a := 0; a < 3; a++ {
+		lo, hi := c, c
+		lo[a]--
+		hi[a]++
+		vlo, vhi := f.cellVelocity(lo), f.cellVelocity(hi)
+		d[a] = [3]float32{(vhi.X - vlo.X) / (2 * h), (vhi.Y - vlo.Y) / (2 * h), (vhi.Z - vlo.Z) / (2 * h)}
+	}
+	return d
+}
+
+// confineVorticity puts back the small swirls the advection smears out numerically: every
+// cell is pushed around the vortex it sits next to, with the force epsilon*h*(N x w) where
+// w is the vorticity and N points towards stronger vorticity
+func (f *VectorField) confineVorticity(dt float32) {
+	eps := simConfig.VorticityEpsilon
+	if eps <= 0 {
+		return
+	}
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	if len(f.vorticity) != n {
+		f.vorticity = make([]math32.Vector3, n)
+		f.confinement = make([]math32.Vector3, n)
+	}
+	for i := range f.vorticity {
+		f.vorticity[i] = math32.Vector3{}
+		f.confinement[i] = math32.Vector3{}
+	}
+	h := f.CellSize()
+	t := f.topology
+
+	// vorticity at the cell centers by central differences
+	for n, c := range t.fluidCoords {
+		d := f.velocityGradient(c)
+		f.vorticity[t.fluidCells[n]] = math32.Vector3{
+			X: d[axisY][axisZ] - d[axisZ][axisY],
+			Y: d[axisZ][axisX] - d[axisX][axisZ],
+			Z: d[axisX][axisY] - d[axisY][axisX],
+		}
+	}
+
+	magnitude := func(c [3]int) float32 {
+		c = f.clampCell(c)
+		return f.vorticity[f.index(c[0], c[1], c[2])].Length()
+	}
+	for n, c := range t.fluidCoords {
+		var grad [3]float32
+		for a := 0; a < 3; a++ {
+			lo, hi := c, c
+			lo[a]--
+			hi[a]++
+			grad[a] = (magnitude(hi) - magnitude(lo)) / (2 * h)
+		}
+		N := math32.Vector3{X: grad[0], Y: grad[1], Z: grad[2]}
+		length := N.Length()
+		if length < 1e-6 {
+			continue
+		}
+		N.DivideScalar(length)
+		idx := t.fluidCells[n]
+		w := f.vorticity[idx]
+		f.confinement[idx] = *N.Cross(&w).MultiplyScalar(eps * h)
+	}
+
+	// the faces take the average force of the two cells they separate
+	for _, face := range t.openFaces {
+		below := face.c
+		below[face.axis]--
+		below = f.clampCell(below)
+		a := f.confinement[f.index(face.c[0], face.c[1], face.c[2])].Component(face.axis)
+		b := f.confinement[f.index(below[0], below[1], below[2])].Component(face.axis)
+		value := f.face(face.axis, face.c[0], face.c[1], face.c[2]) + dt*(a+b)/2
+		f.setFace(face.axis, face.c, clamp(value, -maxFieldSpeed, maxFieldSpeed))
+	}
+}
+
+// diffuse spreads momentum to the neighbouring faces according to the viscosity. It is
+// implicit, solving (1 - alpha*laplacian) u = u0 with Jacobi iterations, so it stays stable
+// however viscous the flow.
+func (f *VectorField) diffuse(dt float32) {
+	h := f.CellSize()
+	turbulent := simConfig.TurbulenceModel != TurbulenceToy
+	if (simConfig.Viscosity <= 0 && !turbulent) || simConfig.DiffusionIters <= 0 {
+		return
+	}
+	faces := f.topology.openFaces
+	if len(f.diffused) != len(faces) {
+		f.diffused = make([]float32, len(faces))
+		f.faceAlpha = make([]float32, len(faces))
+	}
+	// with a turbulence model the eddies add their viscosity to that of the air
+	for n, face := range faces {
+		nu := simConfig.Viscosity
+		if turbulent {
+			below := face.c
+			below[face.axis]--
+			nu += (f.eddyViscosity(face.c) + f.eddyViscosity(below)) / 2
+		}
+		f.faceAlpha[n] = nu * dt / (h * h)
+	}
+	if fieldCompute.usable(f) {
+		fieldCompute.diffuse(f)
+		return
+	}
+	f.saveVelocities()
+	// Jacobi iterations: each pass reads the faces and writes the scratch, then copies the
+	// scratch back, so both halves split among the cores
+	for iter := 0; iter < simConfig.DiffusionIters; iter++ {
+		parallelRange(len(faces), func(lo, hi int) {
+			for n := lo; n < hi; n++ {
+				axis, c := faces[n].axis, faces[n].c
+				alpha := f.faceAlpha[n]
+				var sum float32
+				for a := 0; a < 3; a++ {
+					below, above := c, c
+					below[a]--
+					above[a]++
+					sum += f.face(axis, below[0], below[1], below[2]) + f.face(axis, above[0], above[1], above[2])
+				}
+				f.diffused[n] = (f.prev(axis, c) + alpha*sum) / (1 + 6*alpha)
+			}
+		})
+		parallelRange(len(faces), func(lo, hi int) {
+			for n := lo; n < hi; n++ {
+				f.setFace(faces[n].axis, faces[n].c, f.diffused[n])
+			}
+		})
+	}
+}
+
+// applyNoSlip stops the flow on the faces along obstacle surfaces. On the grid the wall
+// sits up to half a cell off the true surface, which is as close as the cells resolve it.
+func (f *VectorField) applyNoSlip() {
+	if !simConfig.NoSlip {
+		return
+	}
+	for _, face := range f.topology.wallFaces {
+		f.setFace(face.axis, face.c, 0)
+	}
+}
+
+// project removes the divergence of the flow by solving for pressure with the given number
+// of Gauss-Seidel iterations and subtracting its gradient. The pressure absorbs the time
+// step and density.
+func (f *VectorField) project(iterations int) {
+	t := f.topology
+	h := f.CellSize()
+	// the divergence next to the boundary needs the boundary faces of this substep,
+	// on a periodic axis they are the faces at the other end
+	f.applyBoundaries()
+	for _, face := range t.closedFaces {
+		f.setFace(face.axis, face.c, 0)
+	}
+	for i := range f.pressure {
+		f.pressure[i] = 0
+	}
+	for n, c := range t.fluidCoords {
+		var div float32
+		for a := 0; a < 3; a++ {
+			next := c
+			next[a]++
+			div += f.face(a, next[0], next[1], next[2]) - f.face(a, c[0], c[1], c[2])
+		}
+		f.divergence[t.fluidCells[n]] = div / h
+	}
+
+	// Gauss-Seidel with over-relaxation: each cell uses the neighbours already updated in
+	// this sweep, which converges several times faster than Jacobi iterations
+	for iter := 0; iter < iterations; iter++ {
+		for n, idx := range t.fluidCells {
+			var sum float32
+			count := 0
+			for _, nb := range t.neighbors[n] {
+				switch nb {
+				case neighborWall:
+				case neighborOpen:
+					count++
+				default:
+					sum += f.pressure[nb]
+					count++
+				}
+			}
+			if count > 0 {
+				solved := (sum - h*h*f.divergence[idx]) / float32(count)
+				f.pressure[idx] += sorOmega * (solved - f.pressure[idx])
+			}
+		}
+	}
+
+	for _, face := range t.openFaces {
+		below := face.c
+		below[face.axis]--
+		gradient := (f.pressureAt(face.c) - f.pressureAt(below)) / h
+		f.setFace(face.axis, face.c, f.face(face.axis, face.c[0], face.c[1], face.c[2])-gradient)
+	}
+}
+
+// PressureAt returns the pressure of a cell in Pa relative to the open boundary, as of
+// the last step
+func (f *VectorField) PressureAt(c [3]int) float32 {
+	c = f.wrapPeriodic(c)
+	if !f.isInterior(c) {
+		return 0
+	}
+	return f.cell(c).P
+}
+
+// storePressure converts the projection pressure of the last substep into Pa and keeps
+// it in the cells. The projection solves for pressure*dt/density, which is undone here.
+func (f *VectorField) storePressure() {
+	f.forEachCell(func(c [3]int) {
+		p := float32(0)
+		if f.pressure != nil && f.substep != 0 {
+			p = f.pressureAt(c) * simConfig.AirDensity / f.substep
+		}
+		f.cell(c).P = p
+	})
+}
+
+// pressureAt returns the pressure of a cell, zero on the open boundary
+func (f *VectorField) pressureAt(c [3]int) float32 {
+	c = f.wrapPeriodic(c)
+	if !f.isInterior(c) {
+		return 0
+	}
+	return f.pressure[f.index(c[0], c[1], c[2])]
+}
+
+// PressureForce returns the pressure force of the air on the solid cells in N, summed
+// over the cell faces between solid and fluid cells. The viscous stresses are left out,
+// they are small next to the pressure on bluff bodies.
+func (f *VectorField) PressureForce() math32.Vector3 {
+	var force [3]float32
+	if f.solid == nil || f.pressure == nil {
+		return math32.Vector3{}
+	}
+	h := f.CellSize() * simConfig.LengthUnit
+	area := h * h
+	f.forEachCell(func(c [3]int) {
+		if !f.isSolid(c) {
+			return
+		}
+		for axis := 0; axis < 3; axis++ {
+			for _, side := range []int{-1, 1} {
+				nb := c
+				nb[axis] += side
+				if !f.isInterior(nb) || f.isSolid(nb) {
+					continue
+				}
+				// the air on the low side pushes towards +axis
+				force[axis] -= float32(side) * f.PressureAt(nb) * area
+			}
+		}
+	})
+	return math32.Vector3{X: force[0], Y: force[1], Z: force[2]}
+}
+
+// applyBoundaries copies the flow next to the open boundary onto the boundary faces and
+// keeps the ground face closed
+func (f *VectorField) applyBoundaries() {
+	for _, pair := range f.topology.boundaryFaces {
+		dst, src := pair[0], pair[1]
+		f.setFace(dst.axis, dst.c, f.face(src.axis, src.c[0], src.c[1], src.c[2]))
+	}
+	for _, face := range f.topology.groundFaces {
+		f.setFace(face.axis, face.c, 0)
+	}
+}
+
+// markSolids voxelizes the obstacle models into the solid cells, see package voxel. The
+// ground is the floor of the grid, so a model standing open side down on it is solid
+// inside too. The cells are only recomputed when a model moves or the set of models changes.
+func (f *VectorField) markSolids(models []*core.Node) {
+	if f.voxelizer == nil {
+		f.voxelizer = voxel.NewVoxelizer(voxel.NewGrid(f.origin(), f.CellSize(), f.dims()))
+		f.voxelizer.Floor = true
+		f.voxelizer.Triangles = forEachCollisionTriangle
+		f.voxelizer.Fill = fillFromSDF
+	}
+	if f.voxelizer.Update(models) {
+		f.solid = f.voxelizer.Grid.Cells
+		f.topology = nil
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/g3n/engine/math32"
+)
+
+// benchmarkField returns the default 20x5x20 m domain at 4 cells per m, with the flow of
+// a wind source already running through it
+func benchmarkField(sources []WindSource) VectorField {
+	f := initVectorField(20, 5, 20, 80, 20, 80)
+	f.Step(1.0/30, sources)
+	return f
+}
+
+func benchmarkSources() []WindSource {
+	return []WindSource{{
+		Position:    math32.Vector3{X: -6, Y: 2},
+		Radius:      1.5,
+		Speed:       8,
+		Direction:   math32.Vector3{X: 1},
+		Temperature: defaultTemperature,
+	}}
+}
+
+// BenchmarkUpdateVectorField times a whole solver step, which walks the cells many times
+// over; it only uses what the nested layout had too, so it runs on both sides of the
+// change to the flat one
+func BenchmarkUpdateVectorField(b *testing.B) {
+	sources := benchmarkSources()
+	f := benchmarkField(sources)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		f.Step(1.0/30, sources)
+	}
+}
+
+// BenchmarkSampleVelocity times the interpolation the particles do every frame
+func BenchmarkSampleVelocity(b *testing.B) {
+	f := benchmarkField(benchmarkSources())
+	pos := math32.Vector3{X: -4, Y: 2, Z: 0.3}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		pos.X = -9 + float32(n%1800)/100
+		f.SampleVelocity(pos)
+	}
+}
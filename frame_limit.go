@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/window"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// windowInBackground reports whether the window is minimized or lost the input focus
+func windowInBackground() bool {
+	w, ok := app.App().IWindow.(*window.GlfwWindow)
+	if !ok {
+		return false
+	}
+	return w.GetAttrib(glfw.Focused) == 0 || w.GetAttrib(glfw.Iconified) == 1
+}
+
+// frameBudget is the shortest a frame may take, zero when the frame rate is not capped
+func frameBudget() time.Duration {
+	fps := renderSettings.MaxFPS
+	if windowInBackground() && renderSettings.BackgroundFPS > 0 &&
+		(fps == 0 || renderSettings.BackgroundFPS < fps) {
+		fps = renderSettings.BackgroundFPS
+	}
+	if fps <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(fps))
+}
+
+// limitFrameRate sleeps out what is left of the frame budget, called at the end of a
+// frame that started at frameStart. The next frame's delta time includes the sleep, so
+// the simulation still advances with the wall clock, in fewer and longer steps.
+func limitFrameRate(frameStart time.Time) {
+	if rest := frameBudget() - time.Since(frameStart); rest > 0 {
+		time.Sleep(rest)
+	}
+}
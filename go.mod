@@ -4,11 +4,11 @@ go 1.17
 
 require (
 	github.com/g3n/engine v0.2.0
+	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20240506104042-037f3cc74f2a
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
 )
 
 require (
-	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20240506104042-037f3cc74f2a // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	golang.org/x/image v0.24.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
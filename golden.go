@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// Golden image runs render fixed scenes after a fixed number of steps from a fixed seed
+// and compare the pictures with stored ones, so a change that alters what the
+// visualization shows is noticed before a release:
+//
+//	hellog3n -golden testdata/golden                  compare, exit status 1 on a mismatch
+//	hellog3n -golden testdata/golden -update-golden   store the rendered pictures
+//
+// The directory holds goldenCasesFile and one <name>.png per case. A mismatch leaves
+// <name>_actual.png and <name>_diff.png next to it. The window stays hidden and the
+// user interface is left out of the pictures. On a machine without a display run it
+// under xvfb-run.
+//
+// Drivers rasterize differently enough to fail the comparison, so the stored pictures
+// come from one reference setup, Mesa's llvmpipe software renderer under Xvfb:
+//
+//	LIBGL_ALWAYS_SOFTWARE=1 xvfb-run -s "-screen 0 1024x768x24" hellog3n -golden testdata/golden -update-golden
+//
+// Storing the pictures also writes goldenRendererFile with the GL vendor, renderer and
+// version they were made with; a comparison on a different one warns that mismatches
+// may come from the driver rather than from the change.
+
+var (
+	goldenDir    = flag.String("golden", "", "render the golden image cases in `dir`, compare them with the stored images and exit")
+	goldenUpdate = flag.Bool("update-golden", false, "with -golden, store the rendered images as the new golden images")
+)
+
+const (
+	goldenCasesFile    = "cases.json"
+	goldenRendererFile = "renderer.txt"
+)
+
+// goldenWidth and goldenHeight are the size of the pictures, whatever the screen
+const goldenWidth, goldenHeight = 800, 600
+
+// goldenChannelTolerance is how far a color channel may be off before the pixel counts
+// as different, drivers round a little differently
+const goldenChannelTolerance = 8
+
+// GoldenCase is one picture to render
+type GoldenCase struct {
+	Name   string
+	Scene  string // scene file, relative to the directory
+	Config string // simulation config, relative to the directory, the built-in one if empty
+	Seed   int64
+	Steps  int // fixed simulation steps before the picture is taken
+	Camera struct {
+		Position math32.Vector3
+		Target   math32.Vector3
+	}
+	Tolerance float32 // fraction of the pixels that may differ, goldenDefaultTolerance if 0
+}
+
+const goldenDefaultTolerance = 0.001
+
+// goldenSimConfig is the built-in config, a config file in the working directory
+// mustn't change the pictures
+var goldenSimConfig = simConfig
+
+// runGoldenCases renders and compares every case in dir and returns the exit status
+func runGoldenCases(dir string, scene *core.Node, ml *ModelLoader, cam *camera.Camera) int {
+	data, err := os.ReadFile(filepath.Join(dir, goldenCasesFile))
+	if err != nil {
+		log.Printf("Golden: %v", err)
+		return 2
+	}
+	var cases []GoldenCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		log.Printf("Golden: parsing %s: %v", goldenCasesFile, err)
+		return 2
+	}
+
+	a := app.App()
+	a.IWindow.(*window.GlfwWindow).Hide()
+	a.IWindow.(*window.GlfwWindow).SetSize(goldenWidth, goldenHeight)
+	hideUserInterface(scene)
+	checkGoldenRenderer(dir)
+
+	failed := 0
+	for _, c := range cases {
+		img, err := renderGoldenCase(dir, c, scene, ml, cam)
+		if err == nil {
+			err = compareGolden(dir, c, img)
+		}
+		if err != nil {
+			log.Printf("Golden %s: FAIL: %v", c.Name, err)
+			failed++
+			continue
+		}
+		log.Printf("Golden %s: ok", c.Name)
+	}
+	log.Printf("Golden: %d of %d cases passed", len(cases)-failed, len(cases))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// goldenRenderer describes the GL implementation the pictures are rendered with
+func goldenRenderer() string {
+	gs := app.App().Gls()
+	return fmt.Sprintf("%s\n%s\n%s\n", gs.GetString(gls.VENDOR), gs.GetString(gls.RENDERER), gs.GetString(gls.VERSION))
+}
+
+// checkGoldenRenderer records the renderer in dir when storing the pictures, and warns
+// when comparing on another renderer than the one they were stored with
+func checkGoldenRenderer(dir string) {
+	path := filepath.Join(dir, goldenRendererFile)
+	current := goldenRenderer()
+	if *goldenUpdate {
+		if err := os.WriteFile(path, []byte(current), 0644); err != nil {
+			log.Printf("Golden: %v", err)
+		}
+		return
+	}
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Golden: %v, the renderer of the stored pictures is unknown", err)
+		return
+	}
+	if string(stored) != current {
+		log.Printf("Golden: the pictures were stored with\n%sthis run renders with\n%smismatches may come from the driver", stored, current)
+	}
+}
+
+// hideUserInterface hides the panels in the scene, they show times and frame rates
+func hideUserInterface(scene *core.Node) {
+	for _, child := range scene.Children() {
+		if panel, ok := child.(gui.IPanel); ok {
+			panel.SetVisible(false)
+		}
+	}
+}
+
+// renderGoldenCase sets up a case from scratch, steps it and renders it
+func renderGoldenCase(dir string, c GoldenCase, scene *core.Node, ml *ModelLoader, cam *camera.Camera) (*image.RGBA, error) {
+	simConfig = goldenSimConfig
+	if c.Config != "" {
+		if err := loadSimulationConfig(filepath.Join(dir, c.Config)); err != nil {
+			return nil, err
+		}
+	}
+	newScene(scene, ml)
+	if err := loadScene(filepath.Join(dir, c.Scene), scene, ml); err != nil {
+		return nil, err
+	}
+	rand.Seed(c.Seed)
+	stepAccumulator, stepCount, fieldBacklog, spawnClock = 0, 0, 0, 0
+
+	windEnabled = true
+	dt := stepLength()
+	for i := 0; i < c.Steps; i++ {
+		stepSimulation(dt, scene)
+	}
+	drawParticles()
+	interpolateParticles(1)
+	updateFieldSlice()
+	applyParticleVisibility()
+	updateShadows(scene)
+	hideUserInterface(scene)
+
+	cam.SetPositionVec(&c.Camera.Position)
+	cam.LookAt(&c.Camera.Target, &math32.Vector3{Y: 1})
+	cam.SetAspect(float32(goldenWidth) / float32(goldenHeight))
+
+	a := app.App()
+	a.Gls().Viewport(0, 0, goldenWidth, goldenHeight)
+	a.Gls().Clear(gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT | gls.COLOR_BUFFER_BIT)
+	if err := a.Renderer().Render(scene, cam); err != nil {
+		return nil, err
+	}
+	return readFrame(), nil
+}
+
+// compareGolden compares img with the stored picture of the case, or stores it
+func compareGolden(dir string, c GoldenCase, img *image.RGBA) error {
+	path := filepath.Join(dir, c.Name+".png")
+	if *goldenUpdate {
+		return saveScreenshot(path, img)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("no golden image, run with -update-golden to store one: %w", err)
+	}
+	defer file.Close()
+	golden, err := png.Decode(file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if golden.Bounds().Size() != img.Bounds().Size() {
+		return fmt.Errorf("size %v, the golden image is %v", img.Bounds().Size(), golden.Bounds().Size())
+	}
+
+	b := img.Bounds()
+	diff := image.NewRGBA(b)
+	differing := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			got := img.RGBAAt(x, y)
+			want := color.RGBAModel.Convert(golden.At(golden.Bounds().Min.X+x-b.Min.X, golden.Bounds().Min.Y+y-b.Min.Y)).(color.RGBA)
+			if channelDistance(got, want) > goldenChannelTolerance {
+				differing++
+				diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				// the picture faintly, so the red stands out where it is
+				diff.SetRGBA(x, y, color.RGBA{R: got.R / 4, G: got.G / 4, B: got.B / 4, A: 255})
+			}
+		}
+	}
+	tolerance := c.Tolerance
+	if tolerance <= 0 {
+		tolerance = goldenDefaultTolerance
+	}
+	fraction := float32(differing) / float32(b.Dx()*b.Dy())
+	if fraction <= tolerance {
+		return nil
+	}
+	saveScreenshot(filepath.Join(dir, c.Name+"_actual.png"), img)
+	saveScreenshot(filepath.Join(dir, c.Name+"_diff.png"), diff)
+	return fmt.Errorf("%.2f%% of the pixels differ, at most %.2f%% may", fraction*100, tolerance*100)
+}
+
+// channelDistance is the largest difference between the color channels of two pixels
+func channelDistance(a, b color.RGBA) int {
+	d := 0
+	for _, pair := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}} {
+		v := int(pair[0]) - int(pair[1])
+		if v < 0 {
+			v = -v
+		}
+		if v > d {
+			d = v
+		}
+	}
+	return d
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+
+	"github.com/g3n/engine/math32"
+)
+
+// Ground friction models the boundary layer over the floor with the log-law wall
+// function: the first layer of cells is much thicker than the layer where the air slows
+// down, so instead of resolving it the floor takes the shear stress the log law gives
+// for the flow in that layer, tau = rho (kappa u / ln(y/z0))^2. Below the first cell
+// centers the sampled flow follows the same law down to still air at the roughness
+// height, so particles near the floor slow down instead of sliding along it.
+
+// vonKarman is the von Kármán constant of the log law
+const vonKarman = 0.41
+
+// minLogRatio keeps the wall function finite when the first cell center is no higher
+// than the roughness, which happens for rough ground on a fine grid
+const minLogRatio = 1
+
+// groundLogRatio returns ln(y/z0) for a height y in m above the floor
+func groundLogRatio(y float32) float32 {
+	ratio := float32(math.Log(float64(y / simConfig.GroundRoughness)))
+	return math32.Max(ratio, minLogRatio)
+}
+
+// applyGroundFriction slows the flow in the first layer of cells by the wall shear of the
+// floor. It is applied implicitly, u' = u / (1 + dt cf |u| / h), so a large step can stop
+// the flow but never turn it around.
+func (f *VectorField) applyGroundFriction(dt float32) {
+	if !simConfig.GroundFriction || simConfig.GroundRoughness <= 0 {
+		return
+	}
+	h := f.CellSize() * simConfig.LengthUnit
+	cf := vonKarman / groundLogRatio(h/2)
+	cf *= cf
+	for _, face := range f.topology.floorFaces {
+		// the speed along the floor at the face, from the two cells sharing it
+		behind := face.c
+		behind[face.axis]--
+		v := f.cellVelocity(face.c)
+		w := f.cellVelocity(behind)
+		v.Add(&w).MultiplyScalar(0.5)
+		speed := math32.Sqrt(v.X*v.X + v.Z*v.Z)
+		u := f.face(face.axis, face.c[0], face.c[1], face.c[2])
+		f.setFace(face.axis, face.c, u/(1+dt*cf*speed/h))
+	}
+}
+
+// groundProfile returns how much of the flow of the first layer of cells remains at
+// height y in scene units above the floor, 1 from the first cell centers up
+func (f *VectorField) groundProfile(y float32) float32 {
+	center := f.CellSize() / 2
+	if !simConfig.GroundFriction || simConfig.GroundRoughness <= 0 || y >= center {
+		return 1
+	}
+	meters := y * simConfig.LengthUnit
+	if meters <= simConfig.GroundRoughness {
+		return 0
+	}
+	scale := float32(math.Log(float64(meters/simConfig.GroundRoughness))) / groundLogRatio(center*simConfig.LengthUnit)
+	return clamp(scale, 0, 1)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// GustKind selects how a gust varies the speed of a wind source
+type GustKind int
+
+const (
+	GustOff    GustKind = iota
+	GustSine            // a regular swell at Frequency
+	GustRandom          // an Ornstein-Uhlenbeck process with its correlation time set by Frequency
+)
+
+var gustKindNames = []string{"off", "sine", "random"}
+
+// Gust modulates the speed of a wind source around Mean, so the recorded drag and lift
+// show unsteady loading. The random gusts have a standard deviation of Amplitude.
+type Gust struct {
+	Kind      GustKind
+	Mean      float32 // m/s
+	Amplitude float32 // m/s
+	Frequency float32 // Hz
+
+	state float32 // of the random process, in units of Amplitude
+}
+
+// Speed returns the speed at the simulated time t, advancing the random process by dt
+func (g *Gust) Speed(t, dt float32) float32 {
+	switch g.Kind {
+	case GustSine:
+		return g.Mean + g.Amplitude*math32.Sin(2*math32.Pi*g.Frequency*t)
+	case GustRandom:
+		// dx = -x/tau dt + sqrt(2 dt/tau) N(0,1) keeps x at unit variance
+		tau := 1 / (2 * math32.Pi * math32.Max(g.Frequency, 1e-3))
+		g.state += -g.state/tau*dt + math32.Sqrt(2*dt/tau)*float32(rand.NormFloat64())
+		return g.Mean + g.Amplitude*g.state
+	}
+	return g.Mean
+}
+
+// applyGusts sets the speed of the wind sources with a gust for the simulated time t
+func applyGusts(t, dt float32) {
+	for i := range windSources {
+		wind := &windSources[i]
+		if wind.Gust.Kind == GustOff || wind.SpeedExpr != "" {
+			continue
+		}
+		wind.Speed = math32.Max(wind.Gust.Speed(t, dt), 0)
+	}
+}
+
+// showGustDialog edits the gusts of every wind source
+func showGustDialog(scene *core.Node) {
+	height := float32(100 + 30*len(windSources))
+	dialog := gui.NewPanel(490, height)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Gusts")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+	for _, h := range []struct {
+		x    float32
+		name string
+	}{{80, "Kind"}, {155, "Mean"}, {270, "Amplitude"}, {385, "Frequency"}} {
+		label := gui.NewLabel(h.name)
+		label.SetPosition(h.x, 32)
+		dialog.Add(label)
+	}
+
+	y := float32(55)
+	for i := range windSources {
+		gust := &windSources[i].Gust
+		if gust.Kind == GustOff && gust.Mean == 0 {
+			gust.Mean = windSources[i].Speed
+			gust.Frequency = 0.2
+		}
+		label := gui.NewLabel(fmt.Sprintf("Source %d", i+1))
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+
+		kindBtn := gui.NewButton(gustKindNames[gust.Kind])
+		kindBtn.SetPosition(80, y)
+		kindBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+			gust.Kind = (gust.Kind + 1) % GustKind(len(gustKindNames))
+			gust.state = 0
+			kindBtn.Label.SetText(gustKindNames[gust.Kind])
+		})
+		dialog.Add(kindBtn)
+
+		meanInput := NewNumericInput(gust.Mean, 0, 100, 0.5, "m/s", func(value float32) {
+			gust.Mean = value
+		})
+		meanInput.SetPosition(155, y)
+		dialog.Add(meanInput)
+		amplitudeInput := NewNumericInput(gust.Amplitude, 0, 50, 0.5, "m/s", func(value float32) {
+			gust.Amplitude = value
+		})
+		amplitudeInput.SetPosition(270, y)
+		dialog.Add(amplitudeInput)
+		frequencyInput := NewNumericInput(gust.Frequency, 0.01, 10, 0.05, "Hz", func(value float32) {
+			gust.Frequency = value
+		})
+		frequencyInput.SetPosition(385, y)
+		dialog.Add(frequencyInput)
+		y += 30
+	}
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(430, height-35)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeGustUI(scene *core.Node) {
+	gustBtn := gui.NewButton("Gusts...")
+	gustBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showGustDialog(scene)
+	})
+	addSidebarWidget(scene, gustBtn)
+}
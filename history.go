@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// historySeconds is how far back the live run can be scrubbed
+const historySeconds = 10
+
+// historySampleRate is the number of frames kept per second of history
+const historySampleRate = 30
+
+// HistoryFrame is a snapshot of the particle positions at one moment of the live run
+type HistoryFrame struct {
+	Time          float32
+	FluidPosition []math32.Vector3
+	WindPosition  []math32.Vector3
+	ModelPosition math32.Vector3
+
+	// whether each particle passed the attribute filter when the frame was recorded,
+	// nil when that isn't known
+	FluidAccepted []bool
+	WindAccepted  []bool
+}
+
+// HistoryBuffer is a fixed-size ring buffer of the most recent frames
+type HistoryBuffer struct {
+	frames []HistoryFrame
+	start  int // index of the oldest frame
+	count  int
+}
+
+func NewHistoryBuffer(capacity int) *HistoryBuffer {
+	return &HistoryBuffer{frames: make([]HistoryFrame, capacity)}
+}
+
+// Next returns the slot for a new frame, overwriting the oldest one when full.
+// Slots are reused so their position slices don't have to be reallocated.
+func (h *HistoryBuffer) Next() *HistoryFrame {
+	idx := (h.start + h.count) % len(h.frames)
+	if h.count < len(h.frames) {
+		h.count++
+	} else {
+		h.start = (h.start + 1) % len(h.frames)
+	}
+	return &h.frames[idx]
+}
+
+// At returns the i-th frame, 0 being the oldest
+func (h *HistoryBuffer) At(i int) *HistoryFrame {
+	return &h.frames[(h.start+i)%len(h.frames)]
+}
+
+func (h *HistoryBuffer) Len() int {
+	return h.count
+}
+
+func (h *HistoryBuffer) Clear() {
+	h.start = 0
+	h.count = 0
+}
+
+var history = NewHistoryBuffer(historySeconds * historySampleRate)
+var historyClock float32
+var lastHistorySample float32 = -1
+
+var simulationPaused bool
+
+// syncHistoryControls shows the pause state and the scrub position on the history panel
+var syncHistoryControls = func() {}
+
+// scrubPosition selects the displayed frame while paused, 1 being the newest
+var scrubPosition float32 = 1
+
+// scrubMeshes display recorded wind particles while scrubbing, live ones are hidden
+var scrubMeshes []*graphic.Mesh
+
+func recordHistoryFrame(dt float32) {
+	historyClock += dt
+	if lastHistorySample >= 0 && historyClock-lastHistorySample < 1.0/historySampleRate {
+		return
+	}
+	lastHistorySample = historyClock
+
+	frame := history.Next()
+	frame.Time = historyClock
+	frame.FluidPosition = frame.FluidPosition[:0]
+	frame.FluidAccepted = frame.FluidAccepted[:0]
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		frame.FluidPosition = append(frame.FluidPosition, math32.Vector3{X: p.X, Y: p.Y, Z: p.Z})
+		frame.FluidAccepted = append(frame.FluidAccepted, fluidParticleAccepted(p))
+	}
+	frame.WindPosition = frame.WindPosition[:0]
+	frame.WindPosition = append(frame.WindPosition, windParticles.Position...)
+	frame.WindAccepted = frame.WindAccepted[:0]
+	for i := range windParticles.Position {
+		frame.WindAccepted = append(frame.WindAccepted, windParticleAccepted(i))
+	}
+	if mesh != nil {
+		frame.ModelPosition = mesh.Position()
+	}
+	writePlaybackFrame(frame)
+}
+
+// scrubFrame returns the frame selected by the scrub slider, or nil when showing the live
+// state. With a playback file open the slider runs over its frames instead.
+func scrubFrame() *HistoryFrame {
+	if playback != nil && playback.Len() > 0 {
+		return playback.Frame(int(scrubPosition * float32(playback.Len()-1)))
+	}
+	if !simulationPaused || scrubPosition >= 1 || history.Len() == 0 {
+		return nil
+	}
+	idx := int(scrubPosition * float32(history.Len()-1))
+	return history.At(idx)
+}
+
+// showHistoryFrame moves the particle meshes to the scrubbed frame, or back to the live state
+func showHistoryFrame(scene *core.Node) {
+	frame := scrubFrame()
+	if frame == nil {
+		for _, m := range scrubMeshes {
+			m.SetVisible(false)
+		}
+		return
+	}
+
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		if p.Mesh == nil {
+			continue
+		}
+		if i < len(frame.FluidPosition) {
+			pos := frame.FluidPosition[i]
+			p.Mesh.SetPositionVec(&pos)
+			p.Mesh.SetVisible(recordedParticleVisible(frame.FluidPosition, frame.FluidAccepted, i))
+		} else {
+			p.Mesh.SetVisible(false)
+		}
+	}
+
+	for _, m := range windParticles.Mesh {
+		m.SetVisible(false)
+	}
+	for len(scrubMeshes) < len(frame.WindPosition) {
+		m := graphic.NewMesh(geometry.NewSphere(0.05, 6, 6), material.NewStandard(math32.NewColor("Cyan")))
+		objects.Add(m, "scrub particle")
+		scrubMeshes = append(scrubMeshes, m)
+	}
+	for i, m := range scrubMeshes {
+		if i < len(frame.WindPosition) {
+			m.SetPositionVec(&frame.WindPosition[i])
+			m.SetVisible(recordedParticleVisible(frame.WindPosition, frame.WindAccepted, i))
+		} else {
+			m.SetVisible(false)
+		}
+	}
+
+	if mesh != nil {
+		mesh.SetPositionVec(&frame.ModelPosition)
+	}
+}
+
+// markerNear returns the name of a marker close to t, formatted for the timeline label
+func markerNear(t float32) string {
+	for _, m := range eventMarkers {
+		if math32.Abs(m.SimTime-t) < 0.25 {
+			return " " + m.Name
+		}
+	}
+	return ""
+}
+
+func setSimulationPaused(paused bool) {
+	if simulationPaused && !paused && mesh != nil && history.Len() > 0 {
+		// Put the model back where the live run left it
+		latest := history.At(history.Len() - 1)
+		mesh.SetPositionVec(&latest.ModelPosition)
+	}
+	if !paused {
+		closePlayback()
+	}
+	simulationPaused = paused
+	scrubPosition = 1
+	log.Printf("Simulation paused: %v", paused)
+}
+
+func initializeHistoryUI(scene *core.Node) {
+	panel := gui.NewPanel(320, 60)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	scene.Add(panel)
+
+	pauseBtn := gui.NewButton("Pause")
+	pauseBtn.SetPosition(10, 10)
+	pauseBtn.SetSize(70, 40)
+	panel.Add(pauseBtn)
+
+	slider := gui.NewHSlider(150, 20)
+	slider.SetPosition(90, 20)
+	slider.SetValue(1)
+	slider.SetEnabled(false)
+	panel.Add(slider)
+
+	timeLabel := gui.NewLabel("live")
+	timeLabel.SetPosition(250, 22)
+	panel.Add(timeLabel)
+
+	// Event markers inside the history window are drawn as ticks above the slider
+	var markerTicks []*gui.Panel
+	refreshMarkerTicks := func() {
+		for _, tick := range markerTicks {
+			panel.Remove(tick)
+			tick.Dispose()
+		}
+		markerTicks = nil
+		if !simulationPaused || history.Len() < 2 {
+			return
+		}
+		oldest := history.At(0).Time
+		newest := history.At(history.Len() - 1).Time
+		for _, m := range eventMarkers {
+			if m.SimTime < oldest || m.SimTime > newest {
+				continue
+			}
+			frac := (m.SimTime - oldest) / (newest - oldest)
+			tick := gui.NewPanel(2, 8)
+			tick.SetColor(math32.NewColor("Red"))
+			tick.SetPosition(slider.Position().X+frac*slider.Width(), 10)
+			panel.Add(tick)
+			markerTicks = append(markerTicks, tick)
+		}
+	}
+
+	syncHistoryControls = func() {
+		slider.SetValue(scrubPosition)
+		slider.SetEnabled(simulationPaused)
+		timeLabel.SetText("live")
+		refreshMarkerTicks()
+		if simulationPaused {
+			pauseBtn.Label.SetText("Resume")
+		} else {
+			pauseBtn.Label.SetText("Pause")
+		}
+	}
+	pauseBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		setSimulationPaused(!simulationPaused)
+		syncHistoryControls()
+	})
+
+	slider.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		if !simulationPaused {
+			return
+		}
+		scrubPosition = slider.Value()
+		if frame := scrubFrame(); frame != nil && playback != nil {
+			// a playback file has no live end to count back from
+			timeLabel.SetText(fmt.Sprintf("%.1f s%s", frame.Time, markerNear(frame.Time)))
+		} else if frame != nil {
+			timeLabel.SetText(fmt.Sprintf("%.1f s%s", frame.Time-historyClock, markerNear(frame.Time)))
+		} else {
+			timeLabel.SetText("live")
+		}
+	})
+
+	onLayout(func(w, h int) {
+		panel.SetPosition((float32(w)-panel.Width())/2, 10)
+	})
+}
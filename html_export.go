@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// The HTML export writes the playback history as a single web page with a small WebGL
+// player, so a run can be sent to people who won't install the app. It holds the frames
+// the history keeps, the model as triangles and the wind sources; the page needs nothing
+// but a browser.
+
+// htmlPlayback is the data embedded in the page. Positions are flattened x, y, z lists.
+type htmlPlayback struct {
+	Title       string
+	Times       []float32
+	Wind        [][]float32 // per frame
+	Fluid       [][]float32 // per frame
+	ModelOffset [][3]float32
+	Model       []float32 // triangles of the model, relative to its position
+	Obstacles   []float32 // triangles of the other obstacles
+	Sources     []float32
+}
+
+// roundMM keeps positions to the millimetre, which is plenty on screen and keeps the
+// page small
+func roundMM(v float32) float32 {
+	return math32.Round(v*1000) / 1000
+}
+
+func appendPosition(list []float32, v math32.Vector3) []float32 {
+	return append(list, roundMM(v.X), roundMM(v.Y), roundMM(v.Z))
+}
+
+// buildHTMLPlayback collects the history and the scene
+func buildHTMLPlayback(title string) (*htmlPlayback, error) {
+	if history.Len() == 0 {
+		return nil, fmt.Errorf("nothing recorded yet, run the simulation first")
+	}
+	p := &htmlPlayback{Title: title}
+	for i := 0; i < history.Len(); i++ {
+		frame := history.At(i)
+		p.Times = append(p.Times, frame.Time)
+		// the particles that were drawn, see visibleRecorded
+		var wind, fluid []float32
+		for _, pos := range visibleRecorded(frame.WindPosition, frame.WindAccepted) {
+			wind = appendPosition(wind, pos)
+		}
+		for _, pos := range visibleRecorded(frame.FluidPosition, frame.FluidAccepted) {
+			fluid = appendPosition(fluid, pos)
+		}
+		p.Wind = append(p.Wind, wind)
+		p.Fluid = append(p.Fluid, fluid)
+		p.ModelOffset = append(p.ModelOffset, [3]float32{frame.ModelPosition.X, frame.ModelPosition.Y, frame.ModelPosition.Z})
+	}
+
+	if mesh != nil {
+		origin := mesh.Position()
+		forEachWorldTriangle(mesh, func(a, b, c math32.Vector3) {
+			for _, v := range []math32.Vector3{a, b, c} {
+				p.Model = appendPosition(p.Model, *v.Sub(&origin))
+			}
+		})
+	}
+	for _, node := range obstacleModels() {
+		if node == mesh {
+			continue
+		}
+		forEachWorldTriangle(node, func(a, b, c math32.Vector3) {
+			p.Obstacles = appendPosition(appendPosition(appendPosition(p.Obstacles, a), b), c)
+		})
+	}
+	for _, w := range windSources {
+		p.Sources = appendPosition(p.Sources, w.Position)
+	}
+	return p, nil
+}
+
+// exportHTML writes the page to path
+func exportHTML(path string) error {
+	p, err := buildHTMLPlayback(fmt.Sprintf("Airflow run, %s", time.Now().Format("2006-01-02 15:04")))
+	if err != nil {
+		return err
+	}
+	// json escapes <, > and &, so the data can't close the script element
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding playback: %w", err)
+	}
+	page := strings.Replace(htmlPlayerPage, "/*PLAYBACK*/null", string(data), 1)
+	if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	log.Printf("Exported %d frames to %s (%d KB)", len(p.Times), path, len(page)/1024)
+	return nil
+}
+
+func initializeHTMLExportUI(scene *core.Node) {
+	exportBtn := gui.NewButton("Export HTML")
+	exportBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path := fmt.Sprintf("airflow_%d.html", time.Now().UnixNano())
+		if err := exportHTML(path); err != nil {
+			log.Println("Error exporting HTML:", err)
+			overlays.Notify("Could not export HTML: " + err.Error())
+			return
+		}
+		overlays.Notify("Run exported to " + path)
+	})
+	addSidebarWidget(scene, exportBtn)
+}
+
+// htmlPlayerPage is the page the playback is embedded into. Drag to orbit, scroll to
+// zoom, space to pause.
+const htmlPlayerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Airflow run</title>
+<style>
+html, body { margin: 0; height: 100%; overflow: hidden; background: #1a1a1a; font: 13px sans-serif; color: #ddd; }
+canvas { display: block; width: 100%; height: 100%; }
+#bar { position: absolute; left: 0; right: 0; bottom: 0; padding: 8px; background: rgba(0,0,0,0.5); display: flex; gap: 10px; align-items: center; }
+#slider { flex: 1; }
+#title { position: absolute; left: 10px; top: 8px; }
+</style>
+</head>
+<body>
+<canvas id="view"></canvas>
+<div id="title"></div>
+<div id="bar">
+<button id="play">Pause</button>
+<input id="slider" type="range" min="0" value="0">
+<span id="time"></span>
+</div>
+<script>
+"use strict";
+const data = /*PLAYBACK*/null;
+const canvas = document.getElementById("view");
+const gl = canvas.getContext("webgl");
+const slider = document.getElementById("slider");
+const playBtn = document.getElementById("play");
+const timeLabel = document.getElementById("time");
+document.getElementById("title").textContent = data.Title;
+slider.max = data.Times.length - 1;
+
+function compile(type, src) {
+	const s = gl.createShader(type);
+	gl.shaderSource(s, src);
+	gl.compileShader(s);
+	return s;
+}
+const program = gl.createProgram();
+gl.attachShader(program, compile(gl.VERTEX_SHADER,
+	"attribute vec3 pos; attribute vec3 normal; uniform mat4 mvp; uniform vec3 offset; uniform float size; varying float shade;" +
+	"void main() { gl_Position = mvp * vec4(pos + offset, 1.0); gl_PointSize = size;" +
+	" shade = length(normal) > 0.0 ? 0.4 + 0.6 * abs(dot(normalize(normal), normalize(vec3(0.4, 1.0, 0.3)))) : 1.0; }"));
+gl.attachShader(program, compile(gl.FRAGMENT_SHADER,
+	"precision mediump float; uniform vec3 color; varying float shade;" +
+	"void main() { gl_FragColor = vec4(color * shade, 1.0); }"));
+gl.linkProgram(program);
+gl.useProgram(program);
+const loc = {
+	pos: gl.getAttribLocation(program, "pos"),
+	normal: gl.getAttribLocation(program, "normal"),
+	mvp: gl.getUniformLocation(program, "mvp"),
+	offset: gl.getUniformLocation(program, "offset"),
+	size: gl.getUniformLocation(program, "size"),
+	color: gl.getUniformLocation(program, "color"),
+};
+
+function buffer(values) {
+	const b = gl.createBuffer();
+	gl.bindBuffer(gl.ARRAY_BUFFER, b);
+	gl.bufferData(gl.ARRAY_BUFFER, new Float32Array(values), gl.STATIC_DRAW);
+	return { buffer: b, count: values.length / 3 };
+}
+function normals(tris) {
+	const n = [];
+	for (let i = 0; i < tris.length; i += 9) {
+		const ux = tris[i+3]-tris[i], uy = tris[i+4]-tris[i+1], uz = tris[i+5]-tris[i+2];
+		const vx = tris[i+6]-tris[i], vy = tris[i+7]-tris[i+1], vz = tris[i+8]-tris[i+2];
+		const nx = uy*vz-uz*vy, ny = uz*vx-ux*vz, nz = ux*vy-uy*vx;
+		n.push(nx, ny, nz, nx, ny, nz, nx, ny, nz);
+	}
+	return n;
+}
+const ground = [];
+for (let i = -10; i <= 10; i++) {
+	ground.push(i, 0, -10, i, 0, 10, -10, 0, i, 10, 0, i);
+}
+const meshes = {
+	ground: buffer(ground),
+	model: buffer(data.Model || []), modelNormals: buffer(normals(data.Model || [])),
+	obstacles: buffer(data.Obstacles || []), obstacleNormals: buffer(normals(data.Obstacles || [])),
+	sources: buffer(data.Sources || []),
+};
+const dynamic = gl.createBuffer();
+
+function draw(mode, mesh, normalMesh, color, offset, size) {
+	if (mesh.count === 0) return;
+	gl.bindBuffer(gl.ARRAY_BUFFER, mesh.buffer);
+	gl.enableVertexAttribArray(loc.pos);
+	gl.vertexAttribPointer(loc.pos, 3, gl.FLOAT, false, 0, 0);
+	if (normalMesh) {
+		gl.bindBuffer(gl.ARRAY_BUFFER, normalMesh.buffer);
+		gl.enableVertexAttribArray(loc.normal);
+		gl.vertexAttribPointer(loc.normal, 3, gl.FLOAT, false, 0, 0);
+	} else {
+		gl.disableVertexAttribArray(loc.normal);
+		gl.vertexAttrib3f(loc.normal, 0, 0, 0);
+	}
+	gl.uniform3fv(loc.color, color);
+	gl.uniform3fv(loc.offset, offset || [0, 0, 0]);
+	gl.uniform1f(loc.size, size || 1);
+	gl.drawArrays(mode, 0, mesh.count);
+}
+function drawPoints(values, color, size) {
+	if (!values || values.length === 0) return;
+	gl.bindBuffer(gl.ARRAY_BUFFER, dynamic);
+	gl.bufferData(gl.ARRAY_BUFFER, new Float32Array(values), gl.DYNAMIC_DRAW);
+	draw(gl.POINTS, { buffer: dynamic, count: values.length / 3 }, null, color, null, size);
+}
+
+// camera orbiting the origin
+let yaw = 0.8, pitch = 0.5, distance = 25, dragging = null;
+canvas.addEventListener("mousedown", e => dragging = [e.clientX, e.clientY]);
+window.addEventListener("mouseup", () => dragging = null);
+window.addEventListener("mousemove", e => {
+	if (!dragging) return;
+	yaw += (e.clientX - dragging[0]) * 0.01;
+	pitch = Math.max(-1.5, Math.min(1.5, pitch + (e.clientY - dragging[1]) * 0.01));
+	dragging = [e.clientX, e.clientY];
+});
+canvas.addEventListener("wheel", e => {
+	distance = Math.max(2, Math.min(200, distance * Math.exp(e.deltaY * 0.001)));
+	e.preventDefault();
+});
+function viewProjection() {
+	const aspect = canvas.width / canvas.height, f = 1 / Math.tan(0.5), near = 0.1, far = 500;
+	const eye = [distance*Math.cos(pitch)*Math.cos(yaw), distance*Math.sin(pitch), distance*Math.cos(pitch)*Math.sin(yaw)];
+	const len = v => Math.hypot(v[0], v[1], v[2]);
+	const z = eye.map(c => c / len(eye));
+	let x = [z[2], 0, -z[0]];
+	const lx = len(x) || 1;
+	x = x.map(c => c / lx);
+	const y = [z[1]*x[2]-z[2]*x[1], z[2]*x[0]-z[0]*x[2], z[0]*x[1]-z[1]*x[0]];
+	const dot = (a, b) => a[0]*b[0] + a[1]*b[1] + a[2]*b[2];
+	// column major view matrix, then the projection applied on the left
+	const view = [x[0], y[0], z[0], 0, x[1], y[1], z[1], 0, x[2], y[2], z[2], 0, -dot(x, eye), -dot(y, eye), -dot(z, eye), 1];
+	const proj = [f/aspect, 0, 0, 0, 0, f, 0, 0, 0, 0, (far+near)/(near-far), -1, 0, 0, 2*far*near/(near-far), 0];
+	const m = new Array(16).fill(0);
+	for (let c = 0; c < 4; c++) for (let r = 0; r < 4; r++) for (let k = 0; k < 4; k++) m[c*4+r] += proj[k*4+r] * view[c*4+k];
+	return m;
+}
+
+let playing = true, frame = 0, clock = data.Times[0], last = null;
+playBtn.onclick = () => { playing = !playing; playBtn.textContent = playing ? "Pause" : "Play"; };
+window.addEventListener("keydown", e => { if (e.code === "Space") playBtn.onclick(); });
+slider.oninput = () => { frame = +slider.value; clock = data.Times[frame]; };
+
+function render(now) {
+	if (last !== null && playing) {
+		clock += (now - last) / 1000;
+		if (clock > data.Times[data.Times.length - 1]) clock = data.Times[0];
+		while (frame < data.Times.length - 1 && data.Times[frame + 1] <= clock) frame++;
+		while (frame > 0 && data.Times[frame] > clock) frame--;
+		slider.value = frame;
+	}
+	last = now;
+	timeLabel.textContent = data.Times[frame].toFixed(1) + " s";
+
+	if (canvas.width !== canvas.clientWidth || canvas.height !== canvas.clientHeight) {
+		canvas.width = canvas.clientWidth;
+		canvas.height = canvas.clientHeight;
+	}
+	gl.viewport(0, 0, canvas.width, canvas.height);
+	gl.clearColor(0.1, 0.1, 0.1, 1);
+	gl.clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT);
+	gl.enable(gl.DEPTH_TEST);
+	gl.uniformMatrix4fv(loc.mvp, false, viewProjection());
+
+	draw(gl.LINES, meshes.ground, null, [0.3, 0.3, 0.3]);
+	draw(gl.TRIANGLES, meshes.model, meshes.modelNormals, [0.8, 0.8, 0.8], data.ModelOffset[frame]);
+	draw(gl.TRIANGLES, meshes.obstacles, meshes.obstacleNormals, [0.6, 0.6, 0.6]);
+	draw(gl.POINTS, meshes.sources, null, [1, 0.2, 0.2], null, 10);
+	drawPoints(data.Fluid[frame], [0.3, 0.5, 1], 2);
+	drawPoints(data.Wind[frame], [0, 1, 1], 4);
+	requestAnimationFrame(render);
+}
+requestAnimationFrame(render);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,37 @@
+package main
+
+import "github.com/g3n/engine/math32"
+
+// Integrator selects how particles are moved through the flow. Euler follows the
+// velocity at the start of the step and spirals out of vortices; the midpoint and RK4
+// methods sample the field again along the step and keep to the streamlines.
+type Integrator int
+
+const (
+	IntegratorEuler Integrator = iota
+	IntegratorMidpoint
+	IntegratorRK4
+)
+
+var integratorNames = []string{"Euler", "midpoint", "RK4"}
+
+// integrate moves pos by dt through the velocity given by sample and returns the new
+// position and the mean velocity of the step. v0 is the velocity sampled at pos.
+func integrate(pos, v0 math32.Vector3, dt float32, sample func(math32.Vector3) math32.Vector3) (math32.Vector3, math32.Vector3) {
+	at := func(k math32.Vector3, h float32) math32.Vector3 {
+		return *pos.Clone().Add(k.MultiplyScalar(h))
+	}
+	var v math32.Vector3
+	switch simConfig.Integrator {
+	case IntegratorMidpoint:
+		v = sample(at(v0, dt/2))
+	case IntegratorRK4:
+		k2 := sample(at(v0, dt/2))
+		k3 := sample(at(k2, dt/2))
+		k4 := sample(at(k3, dt))
+		v = *v0.Clone().Add(k2.MultiplyScalar(2)).Add(k3.MultiplyScalar(2)).Add(&k4).DivideScalar(6)
+	default:
+		v = v0
+	}
+	return *pos.Add(v.Clone().MultiplyScalar(dt)), v
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/window"
+)
+
+// layoutPasses reposition the panels for a window size, they all run on every resize
+var layoutPasses []func(w, h int)
+
+// bottomDockPanels are lined up left to right along the bottom edge and wrap into
+// another row above when the window gets too narrow
+var bottomDockPanels []gui.IPanel
+
+// controlWidgets are stacked top to bottom in the left-hand column and continue in
+// a new column when they would run into the bottom dock
+var controlWidgets []gui.IPanel
+
+const layoutMargin = 10
+
+// initializeLayout subscribes the single layout pass to window resizes.
+// It must run before any panel registers itself.
+func initializeLayout() {
+	app.App().Subscribe(window.OnWindowSize, func(evname string, ev interface{}) {
+		relayout()
+	})
+	onLayout(layoutBottomDock)
+	onLayout(layoutControls)
+}
+
+// onLayout registers a layout pass and applies it right away
+func onLayout(pass func(w, h int)) {
+	layoutPasses = append(layoutPasses, pass)
+	w, h := app.App().GetSize()
+	pass(w, h)
+}
+
+func relayout() {
+	w, h := app.App().GetSize()
+	for _, pass := range layoutPasses {
+		pass(w, h)
+	}
+}
+
+func addBottomDockPanel(scene *core.Node, panel gui.IPanel) {
+	bottomDockPanels = append(bottomDockPanels, panel)
+	scene.Add(panel)
+	relayout()
+}
+
+func addControlWidget(scene *core.Node, widget gui.IPanel) {
+	controlWidgets = append(controlWidgets, widget)
+	scene.Add(widget)
+	relayout()
+}
+
+// bottomDockTop is the y coordinate of the highest bottom dock row
+var bottomDockTop float32
+
+func layoutBottomDock(w, h int) {
+	// the right-hand sidebar starts at 80% of the width
+	maxX := float32(w) * 0.8
+	x := float32(layoutMargin)
+	rowBottom := float32(h) - layoutMargin
+	rowHeight := float32(0)
+	for _, p := range bottomDockPanels {
+		panel := p.GetPanel()
+		if x > layoutMargin && x+panel.Width() > maxX {
+			x = layoutMargin
+			rowBottom -= rowHeight + layoutMargin
+			rowHeight = 0
+		}
+		panel.SetPosition(x, rowBottom-panel.Height())
+		x += panel.Width() + layoutMargin
+		if panel.Height() > rowHeight {
+			rowHeight = panel.Height()
+		}
+	}
+	bottomDockTop = rowBottom - rowHeight
+}
+
+func layoutControls(w, h int) {
+	const top, columnWidth = 40, 110
+	x := float32(100)
+	y := float32(top)
+	for _, widget := range controlWidgets {
+		panel := widget.GetPanel()
+		if y > top && y+panel.Height() > bottomDockTop-layoutMargin {
+			x += columnWidth
+			y = top
+		}
+		panel.SetPosition(x, y)
+		y += panel.Height() + layoutMargin
+	}
+}
+
+func removeControlWidget(scene *core.Node, widget gui.IPanel) {
+	detachControlWidget(scene, widget)
+	widget.Dispose()
+}
+
+// detachControlWidget takes widget out of the controls without freeing it, so it can be
+// added again later
+func detachControlWidget(scene *core.Node, widget gui.IPanel) {
+	for i, w := range controlWidgets {
+		if w == widget {
+			controlWidgets = append(controlWidgets[:i], controlWidgets[i+1:]...)
+			break
+		}
+	}
+	scene.Remove(widget)
+	relayout()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/g3n/engine/core"
+)
+
+// SceneLifecycle owns the objects that are added to and removed from the scene at runtime.
+// Removing an object through it disposes the geometries, materials and textures of the
+// whole subtree, which scene.Remove alone does not do.
+type SceneLifecycle struct {
+	scene *core.Node
+	live  map[core.INode]string // tracked object -> kind, for leak auditing
+}
+
+var objects *SceneLifecycle
+
+func NewSceneLifecycle(scene *core.Node) *SceneLifecycle {
+	return &SceneLifecycle{scene: scene, live: make(map[core.INode]string)}
+}
+
+// Add attaches node to the scene and tracks it under kind
+func (l *SceneLifecycle) Add(node core.INode, kind string) {
+	l.scene.Add(node)
+	l.live[node] = kind
+}
+
+// Remove detaches node from its parent and releases its GPU resources.
+// Untracked nodes, like models added by the loaders, are released the same way.
+func (l *SceneLifecycle) Remove(node core.INode) {
+	if node == nil {
+		return
+	}
+	if parent := node.Parent(); parent != nil {
+		parent.GetNode().Remove(node)
+	}
+	node.GetNode().DisposeChildren(true)
+	node.Dispose()
+	delete(l.live, node)
+}
+
+// RemoveKind removes every tracked object of the given kind
+func (l *SceneLifecycle) RemoveKind(kind string) {
+	for node, k := range l.live {
+		if k == kind {
+			l.Remove(node)
+		}
+	}
+}
+
+// Summary returns the number of live objects per kind, e.g. "annotation=2 wind particle=40"
+func (l *SceneLifecycle) Summary() string {
+	counts := make(map[string]int)
+	for _, kind := range l.live {
+		counts[kind]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s=%d", kind, counts[kind])
+	}
+	return strings.Join(parts, " ")
+}
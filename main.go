@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 	"time"
 
 	"github.com/g3n/engine/app"
@@ -16,7 +18,6 @@ import (
 	"github.com/g3n/engine/math32"
 	"github.com/g3n/engine/renderer"
 	"github.com/g3n/engine/util/helper"
-	"github.com/g3n/engine/window"
 )
 
 var scene *core.Node
@@ -24,11 +25,25 @@ var mesh *core.Node
 var windEnabled bool
 
 func main() {
+	flag.Parse()
+	captureLog()
+	defer recoverMain()
 	a := app.App()
 	scene = core.NewNode()
+	objects = NewSceneLifecycle(scene)
+	initializeLayout()
+	initializeRenderResources()
+	overlays = NewOverlayManager(scene)
 	ml := &ModelLoader{scene: scene}
+	crashModelLoader = ml
 	gui.Manager().Set(scene)
 	windEnabled = false
+	if err := loadSimulationConfig(simulationConfigFile); err != nil {
+		log.Printf("Using the default simulation config: %v", err)
+	}
+	watchFile(simulationConfigFile, func() error {
+		return loadSimulationConfig(simulationConfigFile)
+	})
 
 	// Camera setup
 	cam := camera.New(1)
@@ -39,13 +54,10 @@ func main() {
 	camera.NewOrbitControl(cam)
 
 	// Window resize handling
-	onResize := func(evname string, ev interface{}) {
-		width, height := a.GetSize()
+	onRenderResize(func(width, height int) {
 		a.Gls().Viewport(0, 0, int32(width), int32(height))
 		cam.SetAspect(float32(width) / float32(height))
-	}
-	a.Subscribe(window.OnWindowSize, onResize)
-	onResize("", nil)
+	})
 
 	// Create surface
 	surfaceGeom := geometry.NewPlane(20, 20)
@@ -55,52 +67,70 @@ func main() {
 	scene.Add(surfaceMesh)
 
 	// Setup wind sources and UI
-	windSources := initializeWindSources(scene)
-	initializeUI(scene, windSources, ml, cam)
+	windSources = initializeWindSources(scene)
+	initializeUI(scene, ml, cam)
 
 	// Initialize fluid simulation
 	initializeFluidSimulation(scene, windSources)
+	initializeFieldCompute()
 
 	// Lights and helpers
-	scene.Add(light.NewAmbient(&math32.Color{R: 1.0, G: 1.0, B: 1.0}, 0.8))
+	ambientLight = light.NewAmbient(&math32.Color{R: 1.0, G: 1.0, B: 1.0}, renderSettings.AmbientIntensity)
+	scene.Add(ambientLight)
 	pointLight := light.NewPoint(&math32.Color{R: 1, G: 1, B: 1}, 5.0)
 	pointLight.SetPosition(1, 0, 2)
 	scene.Add(pointLight)
+	initializeSunLight(scene)
 	scene.Add(helper.NewAxes(1.0))
 
 	a.Gls().ClearColor(0.5, 0.5, 0.5, 1.0)
+	initializeRenderSettingsUI(scene)
+	initializeClipUI(scene)
+	initializeFilterUI(scene)
+	initializeCollisionMeshUI(scene)
+	initializeConvergenceUI(scene)
+	initializeFieldSliceUI(scene)
+	initializeStatusBar(scene)
+	initializeHistoryUI(scene)
+
+	if *goldenDir != "" {
+		os.Exit(runGoldenCases(*goldenDir, scene, ml, cam))
+	}
 
 	// Application loop
-	lastParticleTime := time.Now()
 	a.Run(func(renderer *renderer.Renderer, deltaTime time.Duration) {
+		frameStart := time.Now()
+		defer recoverFrame()
 		a.Gls().Clear(gls.DEPTH_BUFFER_BIT | gls.STENCIL_BUFFER_BIT | gls.COLOR_BUFFER_BIT)
 		renderer.Render(scene, cam)
+		captureRequestedFrame()
 
-		log.Printf("Scene children count: %d, Wind particles: %d", len(scene.Children()), len(windParticles))
-
-		// Continuous particle generation from wind sources
-		if windEnabled {
-			if time.Since(lastParticleTime).Milliseconds() >= 100 { // Spawn every 100ms
-				for _, wind := range windSources {
-					windParticles = append(windParticles, createWindParticle(wind.Position, wind.Direction))
-					log.Printf("Spawning particle from wind source at: %v, Direction: %v", wind.Position, wind.Direction)
-				}
-				lastParticleTime = time.Now()
-			}
-		}
+		log.Printf("Scene children count: %d, Wind particles: %d, Live objects: %s", len(scene.Children()), windParticles.Len(), objects.Summary())
 
-		if mesh != nil {
-			log.Printf("Mesh is present at position: %v", mesh.Position())
-			updatePhysics(mesh, windSources, float32(deltaTime.Seconds()))
-		} else {
-			log.Println("Mesh is nil")
+		processFieldUpdates(time.Now())
+		if !simulationPaused {
+			advanceSimulation(float32(deltaTime.Seconds()), scene)
 		}
-		updateWindParticles(float32(deltaTime.Seconds()), scene, mesh)
-
-		// Simulate fluid dynamics
-		simulateFluid(float32(deltaTime.Seconds()))
+		updateViewFrustum(cam)
+		interpolateParticles(stepAlpha())
+		updateParticleLOD(cam)
+		updateShadows(scene)
+		updateCollisionPreview(scene)
+		updateClipBox(scene)
+		updateFieldSlice()
+		updateStagnation()
+		updateStatusBar()
+		runStats.Frame()
+		windAudio.Update(cam)
+		applyParticleVisibility()
+		showHistoryFrame(scene)
+		updateCheckResult()
+		overlays.Update(time.Now())
+		updateFileWatch(time.Now())
+		limitFrameRate(frameStart)
 	})
 
 	// Save simulation data
-	saveSimulationData()
+	stopPlaybackRecording()
+	appendRunReport(saveSimulationData())
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/window"
+)
+
+// EventMarker is a named point in time inserted by the user during a run
+type EventMarker struct {
+	Name    string
+	Time    float64 // Unix time in seconds, the clock of SimulationData.Time
+	SimTime float32 // simulated seconds, the clock of the history and SimulationData.SimTime
+}
+
+var eventMarkers []EventMarker
+
+func addEventMarker(name string) {
+	if name == "" {
+		name = fmt.Sprintf("Marker %d", len(eventMarkers)+1)
+	}
+	eventMarkers = append(eventMarkers, EventMarker{
+		Name:    name,
+		Time:    float64(time.Now().UnixNano()) / 1e9,
+		SimTime: historyClock,
+	})
+	log.Printf("Event marker %q at %.2f s", name, historyClock)
+}
+
+func initializeMarkerUI(scene *core.Node) {
+	markerName := gui.NewEdit(120, "Marker name")
+	addSidebarWidget(scene, markerName)
+
+	markBtn := gui.NewButton("Add Marker (Ctrl+M)")
+	markBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		addEventMarker(markerName.Text())
+	})
+	addSidebarWidget(scene, markBtn)
+
+	app.App().Subscribe(window.OnKeyDown, func(evname string, ev interface{}) {
+		kev := ev.(*window.KeyEvent)
+		if overlays.Blocking() {
+			return
+		}
+		if kev.Key == window.KeyM && kev.Mods&window.ModControl != 0 {
+			addEventMarker(markerName.Text())
+		}
+	})
+}
@@ -20,24 +20,38 @@ import (
 type ModelLoader struct {
 	scene  *core.Node
 	models []*core.Node
+	path   string // file the current model was loaded from
 }
 
 func openFileDialog() (string, error) {
+	return chooseFile("Select a 3D model", "3D Models", "obj", "gltf", "dae", "glb")
+}
+
+// chooseFile shows the native file picker limited to the given extensions
+func chooseFile(prompt, kind string, exts ...string) (string, error) {
 	var cmd *exec.Cmd
 
+	patterns := make([]string, len(exts))
+	quoted := make([]string, len(exts))
+	for i, ext := range exts {
+		patterns[i] = "*." + ext
+		quoted[i] = `"` + ext + `"`
+	}
+
 	switch runtime.GOOS {
 	case "windows":
+		filter := fmt.Sprintf("%s (%s)|%s", kind, strings.Join(patterns, ";"), strings.Join(patterns, ";"))
 		cmd = exec.Command("powershell", "-Command", "Add-Type -AssemblyName System.Windows.Forms; "+
 			"$dlg = New-Object System.Windows.Forms.OpenFileDialog; "+
-			"$dlg.Filter = '3D Models (*.obj;*.gltf;*.dae;*.glb)|*.obj;*.gltf;*.dae';*.glb; "+
+			"$dlg.Filter = '"+filter+"'; "+
 			"$dlg.ShowDialog() | Out-Null; "+
 			"Write-Output $dlg.FileName")
 	case "darwin":
 		cmd = exec.Command("osascript", "-e",
-			`set filePath to POSIX path of (choose file with prompt "Select a 3D model" of type {"obj", "gltf", "dae"})`,
+			fmt.Sprintf(`set filePath to POSIX path of (choose file with prompt "%s" of type {%s})`, prompt, strings.Join(quoted, ", ")),
 			"-e", `do shell script "echo " & quoted form of filePath`)
 	case "linux":
-		cmd = exec.Command("zenity", "--file-selection", "--title=Select a 3D model", "--file-filter=*.obj *.gltf *.dae *.glb")
+		cmd = exec.Command("zenity", "--file-selection", "--title="+prompt, "--file-filter="+strings.Join(patterns, " "))
 	default:
 		return "", fmt.Errorf("unsupported platform")
 	}
@@ -66,6 +80,7 @@ func (ml *ModelLoader) LoadModel(fpath string) error {
 		}
 		ml.scene.Add(grp)
 		ml.models = append(ml.models, grp)
+		buildModelBVH(grp)
 
 	case ".gltf", ".glb":
 		data, err := os.ReadFile(fpath)
@@ -87,7 +102,7 @@ func (ml *ModelLoader) LoadModel(fpath string) error {
 			log.Println("GLTF Scene undefined, check the file.")
 			return fmt.Errorf("no scene defined in GLTF file")
 		}
-	
+
 	case ".dae":
 		dec, err := collada.Decode(fpath)
 		if err != nil && err != io.EOF {
@@ -100,8 +115,37 @@ func (ml *ModelLoader) LoadModel(fpath string) error {
 		}
 		ml.scene.Add(s)
 		ml.models = append(ml.models, s.GetNode())
+		buildModelBVH(s.GetNode())
 	default:
 		return fmt.Errorf("unknown model format: %s", ext)
 	}
 	return nil
 }
+
+// ReplaceModel removes the current model from the scene and loads fpath as the new one
+func (ml *ModelLoader) ReplaceModel(fpath string) error {
+	// Remove old model
+	if mesh != nil {
+		objects.Remove(mesh)
+		forgetModel(mesh)
+		mesh = nil
+	}
+	ml.models = nil
+	ml.path = ""
+
+	// Load new model
+	if err := ml.LoadModel(fpath); err != nil {
+		return err
+	}
+
+	if len(ml.models) == 0 {
+		log.Println("No models were loaded.")
+		return nil
+	}
+	mesh = ml.models[0]
+	ml.path = fpath
+	// Set position directly (remove centering logic for now)
+	mesh.SetPosition(0, 1, 0)
+	log.Printf("New mesh loaded and added to scene at position: %v", mesh.Position())
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+)
+
+// ModelRenderMode controls how an imported model is drawn. Every mode still blocks particles.
+type ModelRenderMode int
+
+const (
+	RenderSolid ModelRenderMode = iota
+	RenderTransparent
+	RenderWireframe
+	RenderHidden
+)
+
+const transparentModelOpacity = 0.3
+
+var modelRenderModeNames = []string{"Solid", "Transparent", "Wireframe", "Hidden"}
+
+func (m ModelRenderMode) String() string {
+	if int(m) < len(modelRenderModeNames) {
+		return modelRenderModeNames[m]
+	}
+	return "Unknown"
+}
+
+// modelRenderModes holds the render mode chosen for each imported model
+var modelRenderModes = map[*core.Node]ModelRenderMode{}
+
+func modelRenderMode(model *core.Node) ModelRenderMode {
+	return modelRenderModes[model]
+}
+
+// setModelRenderMode updates the materials of every mesh under model to match mode
+func setModelRenderMode(model *core.Node, mode ModelRenderMode) {
+	if model == nil {
+		return
+	}
+	modelRenderModes[model] = mode
+	model.SetVisible(mode != RenderHidden)
+
+	var walk func(inode core.INode)
+	walk = func(inode core.INode) {
+		if gr, ok := inode.(graphic.IGraphic); ok {
+			for _, gm := range gr.GetGraphic().Materials() {
+				applyRenderModeToMaterial(gm.IMaterial(), mode)
+			}
+		}
+		for _, child := range inode.Children() {
+			walk(child)
+		}
+	}
+	walk(model)
+	log.Printf("Model render mode set to %s", mode)
+}
+
+func applyRenderModeToMaterial(imat material.IMaterial, mode ModelRenderMode) {
+	mat := imat.GetMaterial()
+	mat.SetWireframe(mode == RenderWireframe)
+	mat.SetTransparent(mode == RenderTransparent)
+	if std, ok := imat.(*material.Standard); ok {
+		if mode == RenderTransparent {
+			std.SetOpacity(transparentModelOpacity)
+		} else {
+			std.SetOpacity(1)
+		}
+	}
+}
+
+func initializeModelRenderUI(scene *core.Node) {
+	modeBtn := gui.NewButton("Model: " + RenderSolid.String())
+	modeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if mesh == nil {
+			log.Println("No model loaded")
+			return
+		}
+		next := (modelRenderMode(mesh) + 1) % ModelRenderMode(len(modelRenderModeNames))
+		setModelRenderMode(mesh, next)
+		modeBtn.Label.SetText("Model: " + next.String())
+	})
+	addSidebarWidget(scene, modeBtn)
+}
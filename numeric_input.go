@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// NumericInput is an edit field for a single number. Values are clamped to [Min, Max],
+// the -/+ buttons and the Up/Down arrow keys change it by Step, and text that does not
+// parse is shown with a red border instead of being applied. Value, range and step are
+// in the SI unit; when it has conversions the value is shown in the unit of the global
+// unit system and typed values may carry any unit of the quantity, like "12 mph".
+type NumericInput struct {
+	gui.Panel
+	edit      *gui.Edit
+	unitLabel *gui.Label
+	unit      string // SI unit, or just a label
+	value     float32
+	min       float32
+	max       float32
+	step      float32
+	onChange  func(value float32)
+	styles    gui.EditStyles // the default edit styles, restored when the text is valid again
+	invalid   gui.EditStyles
+	unitsX    float32 // where the unit label starts
+}
+
+const numericEditWidth = 50
+
+var invalidBorderColor = math32.Color4{R: 0.9, G: 0.1, B: 0.1, A: 1}
+
+// NewNumericInput creates the widget showing value; onChange is called with the clamped
+// value whenever the user commits a new one
+func NewNumericInput(value, min, max, step float32, unit string, onChange func(value float32)) *NumericInput {
+	n := &NumericInput{unit: unit, min: min, max: max, step: step, onChange: onChange}
+	n.edit = gui.NewEdit(numericEditWidth, "")
+	n.styles = gui.StyleDefault().Edit
+	n.invalid = n.styles
+	for _, s := range []*gui.EditStyle{&n.invalid.Normal, &n.invalid.Over, &n.invalid.Focus} {
+		s.BorderColor = invalidBorderColor
+		s.Border = gui.RectBounds{Top: 2, Right: 2, Bottom: 2, Left: 2}
+	}
+
+	height := n.edit.Height()
+	n.Panel.Initialize(&n.Panel, 0, height)
+	n.Panel.Add(n.edit)
+
+	x := float32(numericEditWidth) + 1
+	for _, spin := range []struct {
+		text  string
+		delta float32
+	}{{"-", -step}, {"+", step}} {
+		delta := spin.delta
+		btn := gui.NewButton(spin.text)
+		btn.SetPosition(x, 0)
+		btn.SetSize(14, height)
+		btn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+			n.SetValue(n.value + delta)
+			n.onChange(n.value)
+		})
+		n.Panel.Add(btn)
+		x += 15
+	}
+
+	n.unitsX = x
+	if unit != "" {
+		n.unitLabel = gui.NewLabel("")
+		n.Panel.Add(n.unitLabel)
+	}
+	n.Panel.SetWidth(x)
+	if _, converts := displayUnit(unit); converts {
+		registerUnitInput(n)
+	}
+
+	n.edit.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		text := n.edit.Text()
+		filteredText := filterNumericInput(text)
+		if text != filteredText {
+			n.edit.SetText(filteredText)
+		}
+		_, ok := n.parse()
+		n.setInvalid(!ok)
+	})
+	keyHandler := func(name string, ev interface{}) {
+		kev := ev.(*window.KeyEvent)
+		switch kev.Key {
+		case window.KeyEnter:
+			value, ok := n.parse()
+			if !ok {
+				n.setInvalid(true)
+				return
+			}
+			n.SetValue(value)
+			n.onChange(n.value)
+		case window.KeyUp:
+			n.SetValue(n.value + n.step)
+			n.onChange(n.value)
+		case window.KeyDown:
+			n.SetValue(n.value - n.step)
+			n.onChange(n.value)
+		}
+	}
+	n.edit.Subscribe(gui.OnKeyDown, keyHandler)
+	n.edit.Subscribe(gui.OnKeyRepeat, keyHandler)
+
+	n.SetValue(value)
+	n.refreshUnit()
+	return n
+}
+
+// refreshUnit shows the value and the unit label in the current display unit
+func (n *NumericInput) refreshUnit() {
+	n.SetValue(n.value)
+	if n.unitLabel == nil {
+		return
+	}
+	shown, _ := displayUnit(n.unit)
+	n.unitLabel.SetText(shown.name)
+	height := n.edit.Height()
+	n.unitLabel.SetPosition(n.unitsX+2, (height-n.unitLabel.Height())/2)
+	n.Panel.SetWidth(n.unitsX + n.unitLabel.Width() + 2)
+}
+
+// Value returns the last committed value
+func (n *NumericInput) Value() float32 {
+	return n.value
+}
+
+// SetValue clamps value into range and shows it, without calling onChange
+func (n *NumericInput) SetValue(value float32) {
+	n.value = math32.Clamp(value, n.min, n.max)
+	n.edit.SetText(n.format(n.value))
+	n.edit.CursorEnd()
+	n.setInvalid(false)
+}
+
+// parse reports whether the current text is a quantity inside the allowed range, and
+// returns it in the SI unit
+func (n *NumericInput) parse() (float32, bool) {
+	value, err := parseQuantity(n.edit.Text(), n.unit)
+	if err != nil || value < n.min || value > n.max {
+		return 0, false
+	}
+	return value, true
+}
+
+// format shows an SI value in the display unit
+func (n *NumericInput) format(value float32) string {
+	decimals := 2
+	shown, converts := displayUnit(n.unit)
+	if n.step >= 1 && n.step == math32.Floor(n.step) && (!converts || shown.scale == 1) {
+		decimals = 0
+	}
+	return strconv.FormatFloat(shown.fromSI(float64(value)), 'f', decimals, 32)
+}
+
+func (n *NumericInput) setInvalid(invalid bool) {
+	if invalid {
+		n.edit.SetStyles(&n.invalid)
+	} else {
+		n.edit.SetStyles(&n.styles)
+	}
+}
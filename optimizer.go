@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// OptimizerParam is a scalar of the setup the optimizer may vary
+type OptimizerParam struct {
+	Name           string
+	Min, Max, Step float32 // Step is the initial search step
+	get            func() float32
+	set            func(float32)
+}
+
+// the first wind source stands for "the vent" of the source parameters
+func firstSource() *WindSource {
+	if len(windSources) == 0 {
+		return nil
+	}
+	return &windSources[0]
+}
+
+func moveFirstSource(move func(w *WindSource)) {
+	if w := firstSource(); w != nil {
+		move(w)
+		if w.Node != nil {
+			w.Node.SetPositionVec(&w.Position)
+		}
+	}
+}
+
+var optimizerParams = []OptimizerParam{
+	{
+		Name: "model yaw (°)", Min: -180, Max: 180, Step: 45,
+		get: func() float32 {
+			if mesh == nil {
+				return 0
+			}
+			r := mesh.Rotation()
+			return math32.RadToDeg(r.Y)
+		},
+		set: func(v float32) {
+			if mesh != nil {
+				mesh.SetRotationY(math32.DegToRad(v))
+			}
+		},
+	},
+	{
+		Name: "source X (m)", Min: -9, Max: 9, Step: 2,
+		get: func() float32 {
+			if w := firstSource(); w != nil {
+				return w.Position.X
+			}
+			return 0
+		},
+		set: func(v float32) { moveFirstSource(func(w *WindSource) { w.Position.X = v }) },
+	},
+	{
+		Name: "source Z (m)", Min: -9, Max: 9, Step: 2,
+		get: func() float32 {
+			if w := firstSource(); w != nil {
+				return w.Position.Z
+			}
+			return 0
+		},
+		set: func(v float32) { moveFirstSource(func(w *WindSource) { w.Position.Z = v }) },
+	},
+	{
+		Name: "vent radius (m)", Min: 0.5, Max: 5, Step: 1,
+		get: func() float32 {
+			if w := firstSource(); w != nil {
+				return w.Radius
+			}
+			return 0
+		},
+		set: func(v float32) { moveFirstSource(func(w *WindSource) { w.Radius = v }) },
+	},
+}
+
+// OptimizerMetric is what the optimizer scores a configuration by
+type OptimizerMetric int
+
+const (
+	MetricDrag      OptimizerMetric = iota // drag on the model in N
+	MetricPlaneFlow                        // volume flow through the plane x = flowPlaneX in m³/s
+)
+
+var optimizerMetricNames = []string{"drag", "flow through plane"}
+
+// flowPlaneX places the plane the flow metric measures
+var flowPlaneX float32
+
+// measureMetric measures the current flow
+func measureMetric(m OptimizerMetric) float32 {
+	if m == MetricPlaneFlow {
+		return vectorField.FlowThroughPlaneX(flowPlaneX)
+	}
+	return lastDragForce
+}
+
+// evaluation simulates one configuration from a fresh field for a settle time and
+// averages the metrics over its second half, once the start-up transient has passed
+type evaluation struct {
+	settle, elapsed, weight float32
+	sums                    [2]float32 // per OptimizerMetric
+}
+
+func (e *evaluation) start(settle float32) {
+	*e = evaluation{settle: settle}
+	resetVectorField()
+}
+
+// advance adds a simulated dt and reports whether the evaluation is complete
+func (e *evaluation) advance(dt float32) bool {
+	e.elapsed += dt
+	if e.elapsed >= e.settle/2 {
+		for m := range e.sums {
+			e.sums[m] += measureMetric(OptimizerMetric(m)) * dt
+		}
+		e.weight += dt
+	}
+	return e.elapsed >= e.settle && e.weight > 0
+}
+
+func (e *evaluation) score(m OptimizerMetric) float32 {
+	return e.sums[m] / e.weight
+}
+
+// Optimizer searches the selected parameters for the best value of the metric with a
+// compass search: it tries a step up and down each parameter, keeps any improvement and
+// halves the steps when none of the moves helps. Every configuration is scored by an
+// evaluation of SettleTime seconds.
+type Optimizer struct {
+	Selected       []bool // parallel to optimizerParams
+	Metric         OptimizerMetric
+	Maximize       bool
+	SettleTime     float32 // simulated seconds per evaluation
+	MaxEvaluations int
+
+	running     bool
+	best        []float32 // parameter values of the best configuration so far
+	bestScore   float32
+	steps       []float32
+	trial       int       // next move to try: parameter trial/2, downward when odd
+	candidate   []float32 // configuration being simulated, nil for the start point
+	run         evaluation
+	evaluations int
+}
+
+var optimizer = Optimizer{
+	Selected:       make([]bool, len(optimizerParams)),
+	SettleTime:     4,
+	MaxEvaluations: 40,
+}
+
+// optimizerStatus shows the progress while the optimizer dialog is open
+var optimizerStatus *gui.Label
+
+// minStepFraction ends the search once every step is this small a part of its range
+const minStepFraction = 0.02
+
+// Running reports whether a search is in progress
+func (o *Optimizer) Running() bool {
+	return o.running
+}
+
+// Start begins a search from the current setup
+func (o *Optimizer) Start() error {
+	if o.running || sensitivity.Running() {
+		return fmt.Errorf("a study is already running")
+	}
+	any := false
+	for _, s := range o.Selected {
+		any = any || s
+	}
+	if !any {
+		return fmt.Errorf("no parameter selected")
+	}
+	o.best = make([]float32, len(optimizerParams))
+	o.steps = make([]float32, len(optimizerParams))
+	for i, p := range optimizerParams {
+		o.best[i] = p.get()
+		o.steps[i] = p.Step
+	}
+	o.running = true
+	o.trial = 0
+	o.evaluations = 0
+	o.begin(nil)
+	log.Printf("Optimizer started, %s the %s", o.goal(), optimizerMetricNames[o.Metric])
+	return nil
+}
+
+// Stop ends the search and puts the best configuration found in place
+func (o *Optimizer) Stop() {
+	if !o.running {
+		return
+	}
+	o.running = false
+	o.apply(o.best)
+	text := "Optimizer stopped, best " + o.describe(o.best, o.bestScore)
+	if o.evaluations == 0 {
+		text = "Optimizer stopped before the first evaluation"
+	}
+	log.Println(text)
+	overlays.Notify(text)
+	o.refreshStatus(text)
+}
+
+func (o *Optimizer) goal() string {
+	if o.Maximize {
+		return "maximizing"
+	}
+	return "minimizing"
+}
+
+// begin sets up and starts simulating a configuration, the start point when values is nil
+func (o *Optimizer) begin(values []float32) {
+	o.candidate = values
+	if values == nil {
+		values = o.best
+	}
+	o.apply(values)
+	o.run.start(o.SettleTime)
+	o.refreshStatus(fmt.Sprintf("Evaluation %d: %s", o.evaluations+1, o.describe(values, math32.NaN())))
+}
+
+func (o *Optimizer) apply(values []float32) {
+	applyParams(o.Selected, values)
+}
+
+// applyParams sets the selected parameters to values, both parallel to optimizerParams
+func applyParams(selected []bool, values []float32) {
+	for i, p := range optimizerParams {
+		if selected[i] {
+			p.set(values[i])
+		}
+	}
+}
+
+// Update advances the evaluation in progress by a simulated dt, called once per frame
+func (o *Optimizer) Update(dt float32) {
+	if !o.running {
+		return
+	}
+	if !o.run.advance(dt) {
+		return
+	}
+	score := o.run.score(o.Metric)
+	o.evaluations++
+
+	switch {
+	case o.candidate == nil:
+		o.bestScore = score
+	case o.better(score, o.bestScore):
+		o.best, o.bestScore = o.candidate, score
+		log.Printf("Optimizer improved: %s", o.describe(o.best, score))
+		// try the same move again from the new best
+		o.trial--
+	}
+	o.next()
+}
+
+func (o *Optimizer) better(score, than float32) bool {
+	if o.Maximize {
+		return score > than
+	}
+	return score < than
+}
+
+// next starts the next move, shrinking the steps after a full round without improvement
+func (o *Optimizer) next() {
+	if o.evaluations >= o.MaxEvaluations {
+		o.finish("evaluation limit reached")
+		return
+	}
+	for {
+		if o.trial < 0 {
+			o.trial = 0
+		}
+		if o.trial >= 2*len(optimizerParams) {
+			o.trial = 0
+			converged := true
+			for i, p := range optimizerParams {
+				o.steps[i] /= 2
+				if o.Selected[i] && o.steps[i] >= minStepFraction*(p.Max-p.Min) {
+					converged = false
+				}
+			}
+			if converged {
+				o.finish("steps below tolerance")
+				return
+			}
+		}
+		param := o.trial / 2
+		step := o.steps[param]
+		if o.trial%2 == 1 {
+			step = -step
+		}
+		o.trial++
+		p := optimizerParams[param]
+		if !o.Selected[param] {
+			continue
+		}
+		value := o.best[param] + step
+		if value < p.Min || value > p.Max {
+			continue
+		}
+		candidate := append([]float32(nil), o.best...)
+		candidate[param] = value
+		o.begin(candidate)
+		return
+	}
+}
+
+func (o *Optimizer) finish(reason string) {
+	o.running = false
+	o.apply(o.best)
+	text := fmt.Sprintf("Optimizer done (%s, %d evaluations), best %s", reason, o.evaluations, o.describe(o.best, o.bestScore))
+	log.Println(text)
+	overlays.Notify(text)
+	o.refreshStatus(text)
+}
+
+// describe lists the selected parameter values and the score, if it is a number
+func (o *Optimizer) describe(values []float32, score float32) string {
+	text := ""
+	for i, p := range optimizerParams {
+		if o.Selected[i] {
+			text += fmt.Sprintf("%s %.2f, ", p.Name, values[i])
+		}
+	}
+	if math32.IsNaN(score) {
+		return text[:len(text)-2]
+	}
+	return text + fmt.Sprintf("%s %.3f", optimizerMetricNames[o.Metric], score)
+}
+
+func (o *Optimizer) refreshStatus(text string) {
+	if optimizerStatus != nil {
+		optimizerStatus.SetText(text)
+	}
+}
+
+// FlowThroughPlaneX returns the volume flow in m³/s through the interior of the plane of
+// x faces nearest to x, in the +x direction
+func (f *VectorField) FlowThroughPlaneX(x float32) float32 {
+	if f.Field == nil {
+		return 0
+	}
+	h := f.CellSize()
+	lo, hi := f.interior(axisX)
+	i := int(math32.Round((x - f.origin().X) / h))
+	i = int(math32.Clamp(float32(i), float32(lo), float32(hi)))
+	jlo, jhi := f.interior(axisY)
+	klo, khi := f.interior(axisZ)
+	var flow float32
+	for j := jlo; j <= jhi; j++ {
+		for k := klo; k <= khi; k++ {
+			flow += f.face(axisX, i, j, k) * h * h
+		}
+	}
+	return flow
+}
+
+// showOptimizerDialog picks the parameters, the metric and the goal, and runs the search
+func showOptimizerDialog(scene *core.Node) {
+	dialog := gui.NewPanel(360, 300)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Optimize")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	y := float32(35)
+	for i, p := range optimizerParams {
+		i := i
+		check := gui.NewCheckBox(p.Name)
+		check.SetPosition(10, y)
+		check.SetValue(optimizer.Selected[i])
+		check.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+			optimizer.Selected[i] = check.Value()
+		})
+		dialog.Add(check)
+		y += 22
+	}
+
+	goalBtn := gui.NewButton("")
+	metricBtn := gui.NewButton(optimizerMetricNames[optimizer.Metric])
+	refreshGoal := func() {
+		if optimizer.Maximize {
+			goalBtn.Label.SetText("maximize")
+		} else {
+			goalBtn.Label.SetText("minimize")
+		}
+	}
+	refreshGoal()
+	goalBtn.SetPosition(10, y+5)
+	goalBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		optimizer.Maximize = !optimizer.Maximize
+		refreshGoal()
+	})
+	dialog.Add(goalBtn)
+	metricBtn.SetPosition(90, y+5)
+	metricBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		optimizer.Metric = (optimizer.Metric + 1) % OptimizerMetric(len(optimizerMetricNames))
+		metricBtn.Label.SetText(optimizerMetricNames[optimizer.Metric])
+	})
+	dialog.Add(metricBtn)
+	y += 35
+
+	planeLabel := gui.NewLabel("Plane at x")
+	planeLabel.SetPosition(10, y+3)
+	dialog.Add(planeLabel)
+	planeInput := NewNumericInput(flowPlaneX, -10, 10, 0.5, "m", func(value float32) {
+		flowPlaneX = value
+	})
+	planeInput.SetPosition(120, y)
+	dialog.Add(planeInput)
+	y += 27
+
+	settleLabel := gui.NewLabel("Time per run")
+	settleLabel.SetPosition(10, y+3)
+	dialog.Add(settleLabel)
+	settleInput := NewNumericInput(optimizer.SettleTime, 0.5, 60, 0.5, "s", func(value float32) {
+		optimizer.SettleTime = value
+	})
+	settleInput.SetPosition(120, y)
+	dialog.Add(settleInput)
+	y += 30
+
+	optimizerStatus = gui.NewLabel("")
+	optimizerStatus.SetPosition(10, y)
+	dialog.Add(optimizerStatus)
+
+	startBtn := gui.NewButton("Start")
+	startBtn.SetPosition(10, 265)
+	startBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := optimizer.Start(); err != nil {
+			overlays.Notify("Could not start the optimizer: " + err.Error())
+		}
+	})
+	dialog.Add(startBtn)
+
+	stopBtn := gui.NewButton("Stop")
+	stopBtn.SetPosition(70, 265)
+	stopBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		optimizer.Stop()
+	})
+	dialog.Add(stopBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(300, 265)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		// the search goes on in the background and notifies when it is done
+		optimizerStatus = nil
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeOptimizerUI(scene *core.Node) {
+	optimizeBtn := gui.NewButton("Optimize...")
+	optimizeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showOptimizerDialog(scene)
+	})
+	addSidebarWidget(scene, optimizeBtn)
+}
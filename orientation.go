@@ -0,0 +1,185 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// principalAxes returns the centroid of the model surface and its principal axes in world
+// space, sorted from the longest to the shortest. For a typical aircraft or car the first
+// axis runs nose to tail and the last one is the thickness (up) axis.
+func principalAxes(model *core.Node) (math32.Vector3, [3]math32.Vector3, bool) {
+	var centroid math32.Vector3
+	var totalArea float32
+	type weighted struct {
+		p    math32.Vector3
+		area float32
+	}
+	var points []weighted
+	forEachWorldTriangle(model, func(a, b, c math32.Vector3) {
+		ab := *b.Clone().Sub(&a)
+		ac := *c.Clone().Sub(&a)
+		area := ab.Cross(&ac).Length() / 2
+		if area == 0 {
+			return
+		}
+		for _, p := range []math32.Vector3{a, b, c} {
+			points = append(points, weighted{p, area / 3})
+			centroid.Add(p.Clone().MultiplyScalar(area / 3))
+		}
+		totalArea += area
+	})
+	var axes [3]math32.Vector3
+	if totalArea == 0 {
+		return centroid, axes, false
+	}
+	centroid.DivideScalar(totalArea)
+
+	// area-weighted covariance of the surface
+	var cov [3][3]float32
+	for _, w := range points {
+		d := [3]float32{w.p.X - centroid.X, w.p.Y - centroid.Y, w.p.Z - centroid.Z}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				cov[i][j] += w.area * d[i] * d[j]
+			}
+		}
+	}
+
+	values, vectors := symmetricEigen3(cov)
+	order := [3]int{0, 1, 2}
+	for i := 0; i < 3; i++ {
+		for j := i + 1; j < 3; j++ {
+			if values[order[j]] > values[order[i]] {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+	}
+	for i, k := range order {
+		axes[i] = math32.Vector3{X: vectors[0][k], Y: vectors[1][k], Z: vectors[2][k]}
+		axes[i].Normalize()
+	}
+	return centroid, axes, true
+}
+
+// symmetricEigen3 diagonalizes a symmetric 3x3 matrix with Jacobi rotations.
+// The eigenvectors are the columns of the returned matrix.
+func symmetricEigen3(m [3][3]float32) ([3]float32, [3][3]float32) {
+	v := [3][3]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for sweep := 0; sweep < 50; sweep++ {
+		off := math32.Abs(m[0][1]) + math32.Abs(m[0][2]) + math32.Abs(m[1][2])
+		if off < 1e-9 {
+			break
+		}
+		for p := 0; p < 2; p++ {
+			for q := p + 1; q < 3; q++ {
+				if m[p][q] == 0 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math32.Abs(theta) + math32.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math32.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 3; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < 3; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	return [3]float32{m[0][0], m[1][1], m[2][2]}, v
+}
+
+// meanWindDirection is the speed-weighted average direction of the wind sources
+func meanWindDirection() (math32.Vector3, bool) {
+	var sum math32.Vector3
+	for _, wind := range windSources {
+		sum.Add(wind.Direction.Clone().Normalize().MultiplyScalar(wind.Speed))
+	}
+	if sum.Length() < 1e-6 {
+		return sum, false
+	}
+	return *sum.Normalize(), true
+}
+
+// alignAxis rotates model about its centroid so axis ends up along target. The axis has no
+// inherent sign, so whichever of axis and -axis needs the smaller rotation is used.
+func alignAxis(model *core.Node, centroid, axis, target math32.Vector3) {
+	if axis.Dot(&target) < 0 {
+		axis.Negate()
+	}
+	var q math32.Quaternion
+	q.SetFromUnitVectors(&axis, &target)
+
+	current := model.Quaternion()
+	var rotated math32.Quaternion
+	rotated.MultiplyQuaternions(&q, &current)
+	model.SetQuaternionQuat(&rotated)
+
+	// keep the centroid where it was
+	pos := model.Position()
+	offset := pos.Clone().Sub(&centroid).ApplyQuaternion(&q)
+	model.SetPositionVec(centroid.Clone().Add(offset))
+}
+
+// pointNoseIntoWind turns the model so its long axis faces the oncoming wind
+func pointNoseIntoWind(model *core.Node) bool {
+	wind, ok := meanWindDirection()
+	if !ok {
+		log.Println("No wind to point the model into")
+		return false
+	}
+	centroid, axes, ok := principalAxes(model)
+	if !ok {
+		return false
+	}
+	alignAxis(model, centroid, axes[0], *wind.Negate())
+	log.Printf("Model nose pointed into wind %v", wind)
+	return true
+}
+
+// levelToGround turns the model so its thinnest axis points straight up
+func levelToGround(model *core.Node) bool {
+	centroid, axes, ok := principalAxes(model)
+	if !ok {
+		return false
+	}
+	alignAxis(model, centroid, axes[2], math32.Vector3{X: 0, Y: 1, Z: 0})
+	log.Println("Model leveled to ground")
+	return true
+}
+
+func initializeOrientationUI(scene *core.Node) {
+	noseBtn := gui.NewButton("Nose Into Wind")
+	noseBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if mesh == nil || !pointNoseIntoWind(mesh) {
+			overlays.Notify("Needs a model and at least one wind source")
+		}
+	})
+	addSidebarWidget(scene, noseBtn)
+
+	levelBtn := gui.NewButton("Level Model")
+	levelBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if mesh == nil || !levelToGround(mesh) {
+			overlays.Notify("Needs a model with geometry")
+		}
+	})
+	addSidebarWidget(scene, levelBtn)
+}
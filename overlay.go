@@ -0,0 +1,182 @@
+package main
+
+import (
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// Z-layers of the overlays. Regular panels live in layer 0, every modal gets its own
+// layer above the previous one and notifications are drawn above everything.
+const (
+	modalZLayer  = 100
+	noticeZLayer = 1000
+)
+
+const noticeDuration = 4 * time.Second
+
+// OverlayManager stacks modal dialogs and notifications above the regular panels.
+// Each modal sits on a full-window backdrop which swallows the clicks meant for
+// whatever is beneath it.
+type OverlayManager struct {
+	scene   *core.Node
+	modals  []*modalOverlay // bottom to top
+	notices []*notice       // oldest first
+}
+
+type modalOverlay struct {
+	backdrop *gui.Panel
+	content  gui.IPanel
+}
+
+type notice struct {
+	panel   *gui.Panel
+	expires time.Time
+}
+
+var overlays *OverlayManager
+
+func NewOverlayManager(scene *core.Node) *OverlayManager {
+	m := &OverlayManager{scene: scene}
+	onLayout(func(w, h int) {
+		m.layout()
+	})
+	app.App().Subscribe(window.OnKeyDown, func(evname string, ev interface{}) {
+		kev := ev.(*window.KeyEvent)
+		if kev.Key == window.KeyEscape && len(m.modals) > 0 {
+			m.CloseModal(m.modals[len(m.modals)-1].content)
+		}
+	})
+	return m
+}
+
+// ShowModal centers content above all panels and blocks input to everything beneath it
+// until CloseModal is called
+func (m *OverlayManager) ShowModal(content gui.IPanel) {
+	backdrop := gui.NewPanel(0, 0)
+	backdrop.SetColor4(&math32.Color4{R: 0, G: 0, B: 0, A: 0.4})
+	backdrop.SetZLayerDelta(modalZLayer + len(m.modals))
+	// swallow clicks so they never reach the panels or the camera control below
+	backdrop.Subscribe(gui.OnMouseDown, func(name string, ev interface{}) {})
+	backdrop.Subscribe(gui.OnScroll, func(name string, ev interface{}) {})
+	backdrop.Add(content)
+	objects.Add(backdrop, "overlay")
+
+	m.modals = append(m.modals, &modalOverlay{backdrop: backdrop, content: content})
+	gui.Manager().SetModal(backdrop)
+	m.layout()
+}
+
+// CloseModal removes the modal showing content and hands input back to the one below it
+func (m *OverlayManager) CloseModal(content gui.IPanel) {
+	for i, modal := range m.modals {
+		if modal.content != content {
+			continue
+		}
+		objects.Remove(modal.backdrop)
+		m.modals = append(m.modals[:i], m.modals[i+1:]...)
+		break
+	}
+	if len(m.modals) > 0 {
+		gui.Manager().SetModal(m.modals[len(m.modals)-1].backdrop)
+	} else {
+		gui.Manager().SetModal(nil)
+	}
+}
+
+// Showing reports whether the modal showing content is open
+func (m *OverlayManager) Showing(content gui.IPanel) bool {
+	for _, modal := range m.modals {
+		if modal.content == content {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocking reports whether a modal is open. Handlers subscribed directly to the window
+// bypass the gui manager and have to check this themselves.
+func (m *OverlayManager) Blocking() bool {
+	return len(m.modals) > 0
+}
+
+// Notify shows a short message at the bottom of the window for a few seconds
+func (m *OverlayManager) Notify(text string) {
+	label := gui.NewLabel(text)
+	label.SetPosition(10, 5)
+	panel := gui.NewPanel(label.Width()+20, label.Height()+10)
+	panel.SetColor4(&math32.Color4{R: 0.1, G: 0.1, B: 0.1, A: 0.85})
+	panel.SetZLayerDelta(noticeZLayer)
+	panel.Add(label)
+	objects.Add(panel, "notice")
+
+	m.notices = append(m.notices, &notice{panel: panel, expires: time.Now().Add(noticeDuration)})
+	m.layout()
+}
+
+// Update drops expired notifications, called once per frame
+func (m *OverlayManager) Update(now time.Time) {
+	kept := m.notices[:0]
+	for _, n := range m.notices {
+		if now.After(n.expires) {
+			objects.Remove(n.panel)
+			continue
+		}
+		kept = append(kept, n)
+	}
+	if len(kept) != len(m.notices) {
+		m.notices = kept
+		m.layout()
+	}
+}
+
+func (m *OverlayManager) layout() {
+	w, h := app.App().GetSize()
+	for _, modal := range m.modals {
+		modal.backdrop.SetSize(float32(w), float32(h))
+		content := modal.content.GetPanel()
+		content.SetPosition((float32(w)-content.Width())/2, (float32(h)-content.Height())/2)
+	}
+
+	// newest notification just above the bottom dock, older ones pushed up
+	y := bottomDockTop - layoutMargin
+	for i := len(m.notices) - 1; i >= 0; i-- {
+		panel := m.notices[i].panel
+		y -= panel.Height() + 5
+		panel.SetPosition((float32(w)-panel.Width())/2, y)
+	}
+}
+
+// Confirm shows a modal with the question and OK/Cancel buttons, onConfirm runs only on OK
+func (m *OverlayManager) Confirm(question string, onConfirm func()) {
+	dialog := gui.NewPanel(300, 90)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+
+	label := gui.NewLabel(question)
+	label.SetPosition(10, 15)
+	dialog.Add(label)
+
+	okBtn := gui.NewButton("OK")
+	okBtn.SetPosition(150, 50)
+	okBtn.SetSize(65, 28)
+	okBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		m.CloseModal(dialog)
+		onConfirm()
+	})
+	dialog.Add(okBtn)
+
+	cancelBtn := gui.NewButton("Cancel")
+	cancelBtn.SetPosition(225, 50)
+	cancelBtn.SetSize(65, 28)
+	cancelBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		m.CloseModal(dialog)
+	})
+	dialog.Add(cancelBtn)
+
+	m.ShowModal(dialog)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minParallelChunk is the least work handed to a goroutine, below it the handing over
+// costs more than the goroutine saves
+const minParallelChunk = 256
+
+// parallelRange splits [0, n) into contiguous chunks, runs fn on each from a pool of up
+// to GOMAXPROCS goroutines and returns once all are done. fn must only write to the
+// indices of its own chunk.
+func parallelRange(n int, fn func(lo, hi int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if most := (n + minParallelChunk - 1) / minParallelChunk; most < workers {
+		workers = most
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	chunk := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += chunk {
+		hi := lo + chunk
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
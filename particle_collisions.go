@@ -0,0 +1,76 @@
+package main
+
+import "github.com/g3n/engine/math32"
+
+// Particle collisions treat the particles as small spheres of simConfig.ParticleRadius:
+// after each step the pairs that overlap are pushed apart by half the overlap each and
+// lose the speed they approached each other with. Without it dense clouds pile up on one
+// spot, in the corners of the domain above all, and the trajectories look like the air
+// collects there. Neighbors come from a spatial hash so the pass stays linear.
+
+var collisionHash SpatialHash
+
+// separateParticles resolves the overlaps among positions, adjusting velocities to match
+func separateParticles(positions, velocities []math32.Vector3, radius float32) {
+	if len(positions) < 2 || radius <= 0 {
+		return
+	}
+	contact := 2 * radius
+	collisionHash.Build(positions, contact)
+	for i := range positions {
+		collisionHash.Near(positions[i], contact, func(j int) {
+			if j <= i {
+				return // each pair once
+			}
+			delta := positions[i].Clone().Sub(&positions[j])
+			dist := delta.Length()
+			if dist >= contact {
+				return
+			}
+			var normal math32.Vector3
+			if dist < 1e-6 {
+				// particles born on the same spot, part them in a direction of their own
+				angle := float32(i*7+j*13) * 2.39996 // golden angle
+				normal = math32.Vector3{X: math32.Cos(angle), Z: math32.Sin(angle)}
+			} else {
+				normal = *delta.DivideScalar(dist)
+			}
+			push := normal.Clone().MultiplyScalar((contact - dist) / 2)
+			positions[i].Add(push)
+			positions[j].Sub(push)
+
+			// only approaching pairs are slowed, separating ones are left alone
+			relative := velocities[i].Clone().Sub(&velocities[j])
+			if approach := relative.Dot(&normal); approach < 0 {
+				change := normal.Clone().MultiplyScalar(approach / 2)
+				velocities[i].Sub(change)
+				velocities[j].Add(change)
+			}
+		})
+	}
+}
+
+// collideParticles runs the collision pass over the wind and the fluid particles, each
+// kind among themselves. SPH parcels already keep apart by their pressure.
+func collideParticles() {
+	if !simConfig.ParticleCollisions {
+		return
+	}
+	radius := simConfig.ParticleRadius / simConfig.LengthUnit
+	if simConfig.Solver != SolverSPH {
+		separateParticles(windParticles.Position, windParticles.Velocity, radius)
+	}
+
+	positions := make([]math32.Vector3, len(fluidParticles))
+	velocities := make([]math32.Vector3, len(fluidParticles))
+	for i, p := range fluidParticles {
+		positions[i] = math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}
+		velocities[i] = math32.Vector3{X: p.VX, Y: p.VY, Z: p.VZ}
+	}
+	separateParticles(positions, velocities, radius)
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		p.X, p.Y, p.Z = positions[i].X, positions[i].Y, positions[i].Z
+		p.VX, p.VY, p.VZ = velocities[i].X, velocities[i].Y, velocities[i].Z
+	}
+}
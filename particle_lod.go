@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/math32"
+)
+
+// With distance LOD on, every fluid particle has both a sphere and a point of the fluid
+// sprite cloud, and shows one of them: the sphere when it is near the camera, the point
+// when it is far. At most lodSphereBudget spheres are shown per frame, the nearest ones,
+// so zooming out over a dense cloud costs one draw call for the far particles instead
+// of a mesh each.
+
+const (
+	lodDistance     = 6   // m from the camera beyond which particles are points
+	lodSphereBudget = 150 // spheres drawn per frame at most
+)
+
+// lodView is the ParticleView of a fluid particle with distance LOD
+type lodView struct {
+	mesh    ParticleView
+	point   *spriteView
+	far     bool
+	visible bool
+}
+
+func newFluidParticleLOD(pos math32.Vector3) ParticleView {
+	v := &lodView{
+		mesh:    newFluidParticleMesh(pos),
+		point:   newFluidParticleSprite(pos).(*spriteView),
+		visible: true,
+	}
+	v.point.SetVisible(false)
+	return v
+}
+
+func (v *lodView) SetPositionVec(pos *math32.Vector3) {
+	v.mesh.SetPositionVec(pos)
+	v.point.SetPositionVec(pos)
+}
+
+func (v *lodView) SetVisible(visible bool) {
+	v.visible = visible
+	v.mesh.SetVisible(visible && !v.far)
+	v.point.SetVisible(visible && v.far)
+}
+
+func (v *lodView) SetStreak(velocity *math32.Vector3) {
+	setStreak(v.mesh, *velocity, fluidParticleLength)
+	v.point.SetStreak(velocity)
+}
+
+// setFar shows the point instead of the sphere, or the other way round
+func (v *lodView) setFar(far bool) {
+	if far != v.far {
+		v.far = far
+		v.SetVisible(v.visible)
+	}
+}
+
+// Release removes the sphere and frees the point
+func (v *lodView) Release() {
+	removeView(v.mesh)
+	v.point.Release()
+}
+
+// updateParticleLOD picks which fluid particles in view show their spheres; called
+// each frame after they are placed
+func updateParticleLOD(cam *camera.Camera) {
+	if renderSettings.PointSprites || !renderSettings.ParticleLOD {
+		return
+	}
+	eye := cam.Position()
+	type candidate struct {
+		view     *lodView
+		distance float32
+	}
+	var near []candidate
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		v, ok := p.Mesh.(*lodView)
+		if !ok {
+			continue
+		}
+		pos := math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}
+		d := pos.DistanceTo(&eye)
+		if d > lodDistance || !inView(pos) {
+			v.setFar(true)
+			continue
+		}
+		near = append(near, candidate{v, d})
+	}
+	sort.Slice(near, func(i, j int) bool { return near[i].distance < near[j].distance })
+	for i, c := range near {
+		c.view.setFar(i >= lodSphereBudget)
+	}
+}
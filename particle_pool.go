@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// The views of expired wind particles are kept hidden in a pool and given to the next
+// particles spawned, instead of a mesh with its own geometry and material being built
+// for every particle and thrown away a few seconds later. The particle arrays already
+// keep their capacity between runs, see WindParticles.truncate.
+
+// windViewPoolMax is the most hidden views kept, the rest are removed from the scene
+const windViewPoolMax = 4096
+
+var windViewPool []ParticleView
+
+// acquireWindView returns a pooled view moved to position and turned along direction,
+// or a new one when the pool is empty
+func acquireWindView(position, direction math32.Vector3) ParticleView {
+	n := len(windViewPool)
+	if n == 0 {
+		return newWindParticleView(position, direction)
+	}
+	v := windViewPool[n-1]
+	windViewPool[n-1] = nil
+	windViewPool = windViewPool[:n-1]
+	if node, ok := v.(core.INode); ok {
+		orientWindParticle(node.GetNode(), direction)
+	}
+	setStreak(v, math32.Vector3{}, windParticleLength)
+	v.SetPositionVec(&position)
+	v.SetVisible(true)
+	return v
+}
+
+// releaseWindView hides the view of an expired particle and keeps it for reuse
+func releaseWindView(v ParticleView) {
+	if len(windViewPool) >= windViewPoolMax {
+		removeView(v)
+		return
+	}
+	v.SetVisible(false)
+	windViewPool = append(windViewPool, v)
+}
+
+// drainWindViewPool removes the pooled views from the scene, when the views change kind
+func drainWindViewPool() {
+	for _, v := range windViewPool {
+		removeView(v)
+	}
+	windViewPool = nil
+}
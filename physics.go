@@ -10,12 +10,10 @@ import (
 var velocity = math32.NewVector3(0, 0, 0)
 var dragCoefficient float32 = 0.47
 
-const airDensity = 1.225
-const area = 1.0
-
 var mass float32 = 1.0
 
-const gravity = -9.8
+// lastDragForce is the drag of the last physics update in N, along the wind
+var lastDragForce float32
 
 func updatePhysics(mesh *core.Node, windSources []WindSource, dt float32) {
 	if mesh == nil {
@@ -27,31 +25,62 @@ func updatePhysics(mesh *core.Node, windSources []WindSource, dt float32) {
 	log.Printf("Mesh position: %v", torusPos)
 
 	totalForce := math32.NewVector3(0, 0, 0)
+	var dragForceSum, liftForceSum float32
+	moment := math32.NewVector3(0, 0, 0)
 	angularMomentum := math32.NewVector3(0, 0, 0)
 	windPower := float32(0)
 	dampingEffect := float32(0.01)
 
+	// with the flow resolved around the model its loads come from the surface, the drag
+	// coefficient estimate is only for when they can't
+	surface := surfaceForcesAvailable(mesh)
+	if surface {
+		forces := surfaceForces(&vectorField, mesh)
+		force := forces.Total()
+		totalForce.Add(&force)
+		windDir := freestreamDirection()
+		dragForceSum = force.Dot(&windDir)
+		liftForceSum = force.Y
+		moment.Copy(&forces.Moment)
+		angularMomentum.Copy(&forces.Moment).Negate()
+		flow := vectorField.SampleVelocity(torusPos)
+		windPower = dragForceSum * flow.Length()
+	}
+
 	for i := range windSources {
 		wind := &windSources[i]
 		distanceVec := torusPos.Clone().Sub(&wind.Position)
 		distance := distanceVec.Length()
 		log.Printf("Wind source %d at %v, Distance to mesh: %v, Radius: %v", i, wind.Position, distance, wind.Radius)
 
-		if distance <= wind.Radius {
+		if distance <= wind.Radius && !surface {
 			windVelocity := wind.Direction.Clone().MultiplyScalar(wind.Speed)
-			dragMagnitude := 0.5 * airDensity * wind.Speed * wind.Speed * dragCoefficient * area
+			dragMagnitude := 0.5 * simConfig.AirDensity * wind.Speed * wind.Speed * dragCoefficient * simConfig.ReferenceArea
 			dragForce := windVelocity.Clone().Normalize().MultiplyScalar(dragMagnitude)
 			totalForce.Add(dragForce)
 
+			// Split the load into drag along this source's wind and lift perpendicular to it
+			windDir := wind.Direction.Clone().Normalize()
+			dragForceSum += dragForce.Dot(windDir)
+			liftForceSum += dragForce.Clone().Sub(windDir.Clone().MultiplyScalar(dragForce.Dot(windDir))).Y
+			moment.Add(torusPos.Clone().MultiplyScalar(simConfig.LengthUnit).Cross(dragForce))
+
 			windPower += dragMagnitude * wind.Speed
 			angularMomentum.Add(dragForce.Cross(&torusPos))
-
-			windParticles = append(windParticles, createWindParticle(wind.Position, wind.Direction))
+		}
+		if distance <= wind.Radius {
+			windParticles.Add(createWindParticle(i))
 			log.Printf("Particle created at position: %v, Distance to mesh: %v", wind.Position, distance)
 		}
 	}
 
-	gravityForce := math32.NewVector3(0, gravity*mass, 0)
+	lastCoefficients = aeroCoefficients(dragForceSum, liftForceSum, *totalForce, *moment, torusPos, freestreamDirection())
+
+	// the moment about the model's position, M - r x F, turns it
+	arm := torusPos.Clone().MultiplyScalar(simConfig.LengthUnit)
+	rigidBody.Rotate(mesh, *moment.Clone().Sub(arm.Cross(totalForce)), dt)
+
+	gravityForce := math32.NewVector3(0, simConfig.Gravity*mass, 0)
 	totalForce.Add(gravityForce)
 
 	velocity.MultiplyScalar(1 - dampingEffect)
@@ -62,8 +91,8 @@ func updatePhysics(mesh *core.Node, windSources []WindSource, dt float32) {
 		velocity.Normalize().MultiplyScalar(10)
 	}
 
-	// Re-enable position update
-	displacement := velocity.Clone().MultiplyScalar(dt)
+	// Re-enable position update, the velocity is in m/s and the scene in length units
+	displacement := velocity.Clone().MultiplyScalar(dt / simConfig.LengthUnit)
 	newPos := torusPos.Add(displacement)
 	if newPos.Length() > 20 {
 		newPos.Normalize().MultiplyScalar(20)
@@ -76,5 +105,18 @@ func updatePhysics(mesh *core.Node, windSources []WindSource, dt float32) {
 
 	log.Printf("Physics update - New position: %v, Velocity: %v", newPos, velocity)
 
-	recordSimulationData(dt, *acceleration, windPower, *angularMomentum, dampingEffect)
+	lastDragForce = dragForceSum
+	convergence.Sample(dragForceSum, liftForceSum, vectorField.ChangeRate(), dt)
+	recordSimulationData(SimulationData{
+		Acceleration:    *acceleration,
+		WindPower:       windPower,
+		AngularMomentum: *angularMomentum,
+		DampingEffect:   dampingEffect,
+		DragForce:       dragForceSum,
+		LiftForce:       liftForceSum,
+		Moment:          *moment,
+		Cd:              lastCoefficients.Cd,
+		Cl:              lastCoefficients.Cl,
+		Cm:              lastCoefficients.Cm,
+	})
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// simulationConfigFile holds the simulation configuration loaded at startup
+const simulationConfigFile = "simulation_config.json"
+
+// loadSimulationConfig reads the configuration from path over the defaults, a missing
+// file keeps the defaults
+func loadSimulationConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	config := simConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if config.AirDensity <= 0 || config.ReferenceArea <= 0 || config.LengthUnit <= 0 || config.ReferenceChord <= 0 {
+		return fmt.Errorf("%s: density, reference area and chord and length unit must be positive", path)
+	}
+	simConfig = config
+	log.Printf("Simulation config loaded from %s: %+v", path, simConfig)
+	return nil
+}
+
+func saveSimulationConfig(path string) error {
+	data, err := json.MarshalIndent(simConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	markFileWritten(path)
+	log.Printf("Simulation config saved to %s", path)
+	return nil
+}
+
+// showPhysicsDialog edits the physical constants of the simulation
+func showPhysicsDialog(scene *core.Node) {
+	dialog := gui.NewPanel(300, 310)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Physical constants")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	y := float32(35)
+	row := func(name string, input *NumericInput) {
+		label := gui.NewLabel(name)
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		input.SetPosition(140, y)
+		dialog.Add(input)
+		y += 30
+	}
+	row("Air density", NewNumericInput(simConfig.AirDensity, 0.01, 1000, 0.05, "kg/m3", func(value float32) {
+		simConfig.AirDensity = value
+	}))
+	row("Gravity", NewNumericInput(simConfig.Gravity, -100, 100, 0.1, "m/s2", func(value float32) {
+		simConfig.Gravity = value
+	}))
+
+	reynoldsLabel := gui.NewLabel("")
+	showReynolds := func() {
+		reynoldsLabel.SetText("Reynolds number " + formatReynolds(reynoldsNumber()))
+	}
+	var areaInput, chordInput, speedInput *NumericInput
+	areaInput = NewNumericInput(simConfig.ReferenceArea, 0.0001, 1000, 0.1, "m2", func(value float32) {
+		simConfig.ReferenceArea = value
+	})
+	row("Reference area", areaInput)
+	// a larger scene unit makes the model larger, matching Reynolds scales the reference
+	// lengths with it and the wind down
+	row("Scene unit", NewNumericInput(simConfig.LengthUnit, 0.001, 1000, 0.1, "m", func(value float32) {
+		k := value / simConfig.LengthUnit
+		simConfig.LengthUnit = value
+		if matchReynolds {
+			simConfig.ReferenceArea *= k * k
+			simConfig.ReferenceChord *= k
+			areaInput.SetValue(simConfig.ReferenceArea)
+			chordInput.SetValue(simConfig.ReferenceChord)
+			scaleWindSpeeds(1 / k)
+			speedInput.SetValue(simConfig.FreestreamSpeed)
+		}
+		showReynolds()
+	}))
+	chordInput = NewNumericInput(simConfig.ReferenceChord, 0.001, 1000, 0.1, "m", func(value float32) {
+		k := value / simConfig.ReferenceChord
+		simConfig.ReferenceChord = value
+		if matchReynolds {
+			scaleWindSpeeds(1 / k)
+			speedInput.SetValue(simConfig.FreestreamSpeed)
+		}
+		showReynolds()
+	})
+	row("Reference chord", chordInput)
+	// 0 takes the speed from the wind
+	speedInput = NewNumericInput(simConfig.FreestreamSpeed, 0, 200, 0.5, "m/s", func(value float32) {
+		simConfig.FreestreamSpeed = value
+		showReynolds()
+	})
+	row("Freestream speed", speedInput)
+
+	reynoldsLabel.SetPosition(10, y+3)
+	dialog.Add(reynoldsLabel)
+	showReynolds()
+	matchCheck := gui.NewCheckBox("Match Reynolds")
+	matchCheck.SetPosition(10, y+30)
+	matchCheck.SetValue(matchReynolds)
+	matchCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		matchReynolds = matchCheck.Value()
+	})
+	dialog.Add(matchCheck)
+
+	saveBtn := gui.NewButton("Save as default")
+	saveBtn.SetPosition(10, 275)
+	saveBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := saveSimulationConfig(simulationConfigFile); err != nil {
+			overlays.Notify("Could not save the config: " + err.Error())
+			return
+		}
+		overlays.Notify("Saved to " + simulationConfigFile)
+	})
+	dialog.Add(saveBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(240, 275)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializePhysicsUI(scene *core.Node) {
+	physicsBtn := gui.NewButton("Physics...")
+	physicsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showPhysicsDialog(scene)
+	})
+	addSidebarWidget(scene, physicsBtn)
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// The in-memory history only holds the last historySeconds. For longer runs the history
+// frames can also be streamed to a playback file, and a playback file of any size can be
+// opened and scrubbed: only its index is read up front, the frames are read when the
+// scrubber gets to them and just the ones around it are kept.
+//
+// The file is little endian:
+//
+//	"AFPB" version:u32
+//	frames   time:f32 model:3×f32 fluid:u32 wind:u32 positions:(fluid+wind)×3×f32
+//	         accepted:(fluid+wind)×u8, since version 2, 1 where the particle passed the filter
+//	index    offset:u64 per frame
+//	footer   index offset:u64 frames:u32 "AFPX"
+
+const (
+	playbackMagic       = "AFPB"
+	playbackFooterMagic = "AFPX"
+	playbackVersion     = 2
+	playbackFooterSize  = 8 + 4 + 4
+
+	// playbackCacheFrames is how many frames around the scrubber are kept in memory
+	playbackCacheFrames = 90
+)
+
+// PlaybackWriter streams history frames to a playback file
+type PlaybackWriter struct {
+	file    *os.File
+	w       *bufio.Writer
+	offset  uint64
+	offsets []uint64
+}
+
+func createPlaybackFile(path string) (*PlaybackWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	pw := &PlaybackWriter{file: file, w: bufio.NewWriter(file)}
+	pw.w.WriteString(playbackMagic)
+	binary.Write(pw.w, binary.LittleEndian, uint32(playbackVersion))
+	pw.offset = 8
+	return pw, nil
+}
+
+// WriteFrame appends a frame
+func (pw *PlaybackWriter) WriteFrame(frame *HistoryFrame) error {
+	pw.offsets = append(pw.offsets, pw.offset)
+	values := make([]float32, 0, 4+3*(len(frame.FluidPosition)+len(frame.WindPosition)))
+	values = append(values, frame.Time, frame.ModelPosition.X, frame.ModelPosition.Y, frame.ModelPosition.Z)
+	if err := binary.Write(pw.w, binary.LittleEndian, values); err != nil {
+		return err
+	}
+	counts := []uint32{uint32(len(frame.FluidPosition)), uint32(len(frame.WindPosition))}
+	if err := binary.Write(pw.w, binary.LittleEndian, counts); err != nil {
+		return err
+	}
+	values = values[:0]
+	for _, positions := range [][]math32.Vector3{frame.FluidPosition, frame.WindPosition} {
+		for _, p := range positions {
+			values = append(values, p.X, p.Y, p.Z)
+		}
+	}
+	if err := binary.Write(pw.w, binary.LittleEndian, values); err != nil {
+		return err
+	}
+	accepted := make([]byte, 0, len(frame.FluidPosition)+len(frame.WindPosition))
+	for _, flags := range []struct {
+		positions []math32.Vector3
+		accepted  []bool
+	}{{frame.FluidPosition, frame.FluidAccepted}, {frame.WindPosition, frame.WindAccepted}} {
+		for i := range flags.positions {
+			var b byte
+			if i >= len(flags.accepted) || flags.accepted[i] {
+				b = 1
+			}
+			accepted = append(accepted, b)
+		}
+	}
+	if _, err := pw.w.Write(accepted); err != nil {
+		return err
+	}
+	pw.offset += uint64(4*4 + 2*4 + 4*len(values) + len(accepted))
+	return nil
+}
+
+// Close writes the index and closes the file
+func (pw *PlaybackWriter) Close() error {
+	binary.Write(pw.w, binary.LittleEndian, pw.offsets)
+	binary.Write(pw.w, binary.LittleEndian, pw.offset)
+	binary.Write(pw.w, binary.LittleEndian, uint32(len(pw.offsets)))
+	pw.w.WriteString(playbackFooterMagic)
+	if err := pw.w.Flush(); err != nil {
+		pw.file.Close()
+		return err
+	}
+	return pw.file.Close()
+}
+
+// PlaybackFile reads the frames of a playback file on demand
+type PlaybackFile struct {
+	path    string
+	file    *os.File
+	version uint32
+	offsets []uint64
+	end     int64 // where the frames end and the index starts
+	cache   map[int]*HistoryFrame
+}
+
+func openPlaybackFile(path string) (*PlaybackFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	pf, err := readPlaybackIndex(path, file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pf, nil
+}
+
+func readPlaybackIndex(path string, file *os.File) (*PlaybackFile, error) {
+	header := make([]byte, 8)
+	if _, err := file.ReadAt(header, 0); err != nil || string(header[:4]) != playbackMagic {
+		return nil, fmt.Errorf("not a playback file")
+	}
+	version := binary.LittleEndian.Uint32(header[4:])
+	if version > playbackVersion {
+		return nil, fmt.Errorf("playback file version %d is newer than supported version %d", version, playbackVersion)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	footer := make([]byte, playbackFooterSize)
+	if _, err := file.ReadAt(footer, info.Size()-playbackFooterSize); err != nil || string(footer[12:]) != playbackFooterMagic {
+		return nil, fmt.Errorf("the playback file is incomplete, the run may not have been stopped")
+	}
+	indexAt := binary.LittleEndian.Uint64(footer)
+	count := binary.LittleEndian.Uint32(footer[8:])
+	offsets := make([]uint64, count)
+	index := io.NewSectionReader(file, int64(indexAt), int64(count)*8)
+	if err := binary.Read(index, binary.LittleEndian, offsets); err != nil {
+		return nil, fmt.Errorf("reading the index: %w", err)
+	}
+	return &PlaybackFile{path: path, file: file, version: version, offsets: offsets, end: int64(indexAt), cache: map[int]*HistoryFrame{}}, nil
+}
+
+// Len returns the number of frames
+func (pf *PlaybackFile) Len() int {
+	return len(pf.offsets)
+}
+
+// Frame returns frame i, reading it when it isn't cached. It returns nil when the frame
+// can't be read.
+func (pf *PlaybackFile) Frame(i int) *HistoryFrame {
+	if frame, ok := pf.cache[i]; ok {
+		return frame
+	}
+	frame, err := pf.readFrame(i)
+	if err != nil {
+		log.Printf("Playback: reading frame %d: %v", i, err)
+		return nil
+	}
+	if len(pf.cache) >= playbackCacheFrames {
+		pf.evictFarthest(i)
+	}
+	pf.cache[i] = frame
+	return frame
+}
+
+// evictFarthest drops the cached frame farthest from frame i
+func (pf *PlaybackFile) evictFarthest(i int) {
+	farthest, distance := -1, -1
+	for n := range pf.cache {
+		d := n - i
+		if d < 0 {
+			d = -d
+		}
+		if d > distance {
+			farthest, distance = n, d
+		}
+	}
+	delete(pf.cache, farthest)
+}
+
+func (pf *PlaybackFile) readFrame(i int) (*HistoryFrame, error) {
+	start := int64(pf.offsets[i])
+	end := pf.end
+	if i+1 < len(pf.offsets) {
+		end = int64(pf.offsets[i+1])
+	}
+	r := io.NewSectionReader(pf.file, start, end-start)
+	var head [4]float32
+	var counts [2]uint32
+	if err := binary.Read(r, binary.LittleEndian, &head); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &counts); err != nil {
+		return nil, err
+	}
+	if int64(counts[0]+counts[1])*12 > end-start {
+		return nil, fmt.Errorf("corrupt frame")
+	}
+	values := make([]float32, 3*(counts[0]+counts[1]))
+	if err := binary.Read(r, binary.LittleEndian, values); err != nil {
+		return nil, err
+	}
+	positions := make([]math32.Vector3, counts[0]+counts[1])
+	for n := range positions {
+		positions[n] = math32.Vector3{X: values[3*n], Y: values[3*n+1], Z: values[3*n+2]}
+	}
+	frame := &HistoryFrame{
+		Time:          head[0],
+		ModelPosition: math32.Vector3{X: head[1], Y: head[2], Z: head[3]},
+		FluidPosition: positions[:counts[0]],
+		WindPosition:  positions[counts[0]:],
+	}
+	// older files have no filter results, their particles all pass
+	if pf.version >= 2 {
+		flags := make([]byte, counts[0]+counts[1])
+		if _, err := io.ReadFull(r, flags); err != nil {
+			return nil, err
+		}
+		accepted := make([]bool, len(flags))
+		for n, b := range flags {
+			accepted[n] = b != 0
+		}
+		frame.FluidAccepted, frame.WindAccepted = accepted[:counts[0]], accepted[counts[0]:]
+	}
+	return frame, nil
+}
+
+// Duration returns the time between the first and the last frame
+func (pf *PlaybackFile) Duration() float32 {
+	if pf.Len() < 2 {
+		return 0
+	}
+	first, last := pf.Frame(0), pf.Frame(pf.Len()-1)
+	if first == nil || last == nil {
+		return 0
+	}
+	return last.Time - first.Time
+}
+
+func (pf *PlaybackFile) Close() error {
+	pf.cache = nil
+	return pf.file.Close()
+}
+
+var (
+	playbackWriter *PlaybackWriter // the file the history is streamed to, nil when not
+	playback       *PlaybackFile   // the file being played back, nil for the live history
+)
+
+// writePlaybackFrame streams the newest history frame when recording to a file
+func writePlaybackFrame(frame *HistoryFrame) {
+	if playbackWriter == nil {
+		return
+	}
+	if err := playbackWriter.WriteFrame(frame); err != nil {
+		log.Printf("Playback: writing frame: %v", err)
+		stopPlaybackRecording()
+		overlays.Notify("Stopped recording the playback file: " + err.Error())
+	}
+}
+
+func startPlaybackRecording() error {
+	path := fmt.Sprintf("playback_%s.afp", time.Now().Format("20060102_150405"))
+	pw, err := createPlaybackFile(path)
+	if err != nil {
+		return err
+	}
+	playbackWriter = pw
+	log.Printf("Recording playback to %s", path)
+	return nil
+}
+
+func stopPlaybackRecording() {
+	if playbackWriter == nil {
+		return
+	}
+	path := playbackWriter.file.Name()
+	if err := playbackWriter.Close(); err != nil {
+		log.Printf("Playback: closing %s: %v", path, err)
+	}
+	playbackWriter = nil
+	log.Printf("Playback recorded to %s", path)
+}
+
+// openPlayback switches the scrubber to a playback file, pausing the live run
+func openPlayback(path string) error {
+	pf, err := openPlaybackFile(path)
+	if err != nil {
+		return err
+	}
+	closePlayback()
+	playback = pf
+	setSimulationPaused(true)
+	syncHistoryControls()
+	log.Printf("Playing back %d frames from %s", pf.Len(), path)
+	return nil
+}
+
+// closePlayback switches the scrubber back to the live history
+func closePlayback() {
+	if playback == nil {
+		return
+	}
+	playback.Close()
+	playback = nil
+	syncHistoryControls()
+}
+
+func showPlaybackDialog() {
+	dialog := gui.NewPanel(300, 150)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Playback file")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	status := gui.NewLabel("")
+	status.SetPosition(10, 35)
+	dialog.Add(status)
+	showStatus := func() {
+		switch {
+		case playback != nil:
+			status.SetText(fmt.Sprintf("Playing %d frames, %.0f s", playback.Len(), playback.Duration()))
+		case playbackWriter != nil:
+			status.SetText(fmt.Sprintf("Recording, %d frames", len(playbackWriter.offsets)))
+		default:
+			status.SetText("Live")
+		}
+	}
+	showStatus()
+
+	recordCheck := gui.NewCheckBox("Record history to a file")
+	recordCheck.SetPosition(10, 65)
+	recordCheck.SetValue(playbackWriter != nil)
+	recordCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		if !recordCheck.Value() {
+			stopPlaybackRecording()
+		} else if err := startPlaybackRecording(); err != nil {
+			overlays.Notify("Could not record: " + err.Error())
+			recordCheck.SetValue(false)
+		}
+		showStatus()
+	})
+	dialog.Add(recordCheck)
+
+	openBtn := gui.NewButton("Open...")
+	openBtn.SetPosition(10, 115)
+	openBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path, err := chooseFile("Select a playback file", "Playback files", "afp")
+		if err != nil || path == "" {
+			return
+		}
+		if err := openPlayback(path); err != nil {
+			overlays.Notify("Could not open the playback file: " + err.Error())
+			return
+		}
+		showStatus()
+	})
+	dialog.Add(openBtn)
+
+	liveBtn := gui.NewButton("Back to live")
+	liveBtn.SetPosition(90, 115)
+	liveBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		closePlayback()
+		showStatus()
+	})
+	dialog.Add(liveBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(240, 115)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializePlaybackUI(scene *core.Node) {
+	playbackBtn := gui.NewButton("Playback...")
+	playbackBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showPlaybackDialog()
+	})
+	addSidebarWidget(scene, playbackBtn)
+}
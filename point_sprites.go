@@ -0,0 +1,252 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// With point sprites on, each kind of particle is drawn as one vertex buffer of points
+// instead of a mesh per particle: one draw call for all of them, so 100k particles and
+// more stay interactive. A custom shader sizes the sprites by their distance, like the
+// spheres would be, and shades them as discs lit from the front, or as ellipses along
+// their velocity on screen when streaks are on.
+
+const spriteVertexShader = `
+#include <attributes>
+
+uniform mat4 MVP;
+uniform mat4 MV;
+#include <material>
+
+in float VertexVisible;
+in vec3 VertexVelocity; // the streak, in sprite diameters
+
+out vec2 streakDir;
+out float streakWidth; // of the streak across, over its length
+
+void main() {
+    if (VertexVisible < 0.5) {
+        // outside the clip volume, nothing is rasterized
+        gl_Position = vec4(2.0, 2.0, 2.0, 1.0);
+        gl_PointSize = 0.0;
+        return;
+    }
+    gl_Position = MVP * vec4(VertexPosition, 1.0);
+    vec4 posMV = MV * vec4(VertexPosition, 1.0);
+    float disc = MatPointSize / -posMV.z;
+    vec2 trail = (MV * vec4(VertexVelocity, 0.0)).xy * disc;
+    float len = min(length(trail), 7.0 * disc);
+    gl_PointSize = disc + len;
+    streakWidth = disc / (disc + len);
+    // gl_PointCoord runs down the screen
+    streakDir = len > 0.0 ? normalize(vec2(trail.x, -trail.y)) : vec2(1.0, 0.0);
+}
+`
+
+const spriteFragmentShader = `
+precision highp float;
+#include <material>
+
+in vec2 streakDir;
+in float streakWidth;
+
+out vec4 FragColor;
+
+void main() {
+    vec2 p = gl_PointCoord * 2.0 - 1.0;
+    float along = dot(p, streakDir);
+    float across = dot(p, vec2(-streakDir.y, streakDir.x)) / streakWidth;
+    float r2 = along * along + across * across;
+    if (r2 > 1.0) {
+        discard;
+    }
+    float shade = 0.35 + 0.65 * sqrt(1.0 - r2);
+    // a streak fades towards its tail
+    shade *= mix(1.0, 0.5 + 0.25 * (along + 1.0), 1.0 - streakWidth);
+    FragColor = vec4(MatEmissiveColor * shade, MatOpacity);
+}
+`
+
+// spriteStride is the floats per point: the position, whether it is shown and the streak
+const spriteStride = 7
+
+// spriteInitialCapacity is the points of a new cloud, it doubles when full
+const spriteInitialCapacity = 1024
+
+var spriteShaderAdded bool
+
+func addSpriteShader() {
+	if spriteShaderAdded {
+		return
+	}
+	r := app.App().Renderer()
+	r.AddShader("particleSpriteVertex", spriteVertexShader)
+	r.AddShader("particleSpriteFragment", spriteFragmentShader)
+	r.AddProgram("particleSprite", "particleSpriteVertex", "particleSpriteFragment")
+	spriteShaderAdded = true
+}
+
+// SpriteCloud draws many particles as the points of one buffer. Slots of removed
+// particles are hidden and reused.
+type SpriteCloud struct {
+	diameter float32
+	points   *graphic.Points
+	vbo      *gls.VBO
+	buffer   math32.ArrayF32
+	free     []int
+}
+
+// newSpriteCloud adds a cloud of sprites of the given color and diameter in scene units
+func newSpriteCloud(color string, diameter float32) *SpriteCloud {
+	addSpriteShader()
+	c := &SpriteCloud{diameter: diameter, buffer: math32.NewArrayF32(spriteInitialCapacity*spriteStride, spriteInitialCapacity*spriteStride)}
+	for i := spriteInitialCapacity - 1; i >= 0; i-- {
+		c.free = append(c.free, i)
+	}
+	c.vbo = gls.NewVBO(c.buffer).
+		AddAttrib(gls.VertexPosition).
+		AddCustomAttribOffset("VertexVisible", 1, 3*4).
+		AddCustomAttribOffset("VertexVelocity", 3, 4*4)
+	c.vbo.SetUsage(gls.DYNAMIC_DRAW)
+	geom := geometry.NewGeometry()
+	geom.AddVBO(c.vbo)
+
+	mat := material.NewPoint(math32.NewColor(color))
+	mat.SetShader("particleSprite")
+	mat.SetShaderUnique(true)
+	// MatPointSize is the size in pixels at a distance of one unit, taken for a 60° view
+	// over the window height
+	onRenderResize(func(w, h int) {
+		mat.SetSize(diameter * float32(h) / (2 * math32.Tan(math32.DegToRad(30))))
+	})
+
+	c.points = graphic.NewPoints(geom, mat)
+	// the points move every frame, the bounds the renderer culls by would be stale
+	c.points.SetCullable(false)
+	objects.Add(c.points, "particle sprites")
+	return c
+}
+
+// add takes a free slot, growing the buffer when there is none
+func (c *SpriteCloud) add(pos math32.Vector3) *spriteView {
+	if len(c.free) == 0 {
+		n := len(c.buffer) / spriteStride
+		grown := math32.NewArrayF32(2*n*spriteStride, 2*n*spriteStride)
+		copy(grown, c.buffer)
+		c.buffer = grown
+		c.vbo.SetBuffer(c.buffer)
+		for i := 2*n - 1; i >= n; i-- {
+			c.free = append(c.free, i)
+		}
+		log.Printf("Sprite cloud grown to %d points", 2*n)
+	}
+	v := &spriteView{cloud: c, slot: c.free[len(c.free)-1]}
+	c.free = c.free[:len(c.free)-1]
+	v.SetPositionVec(&pos)
+	v.SetVisible(true)
+	v.SetStreak(&math32.Vector3{})
+	return v
+}
+
+// spriteView is one point of a cloud, a ParticleView
+type spriteView struct {
+	cloud *SpriteCloud
+	slot  int
+}
+
+func (v *spriteView) SetPositionVec(pos *math32.Vector3) {
+	i := v.slot * spriteStride
+	v.cloud.buffer[i], v.cloud.buffer[i+1], v.cloud.buffer[i+2] = pos.X, pos.Y, pos.Z
+	v.cloud.vbo.Update()
+}
+
+func (v *spriteView) SetVisible(visible bool) {
+	var shown float32
+	if visible {
+		shown = 1
+	}
+	v.cloud.buffer[v.slot*spriteStride+3] = shown
+	v.cloud.vbo.Update()
+}
+
+// SetStreak sets the streak to the travel over streakSeconds at velocity
+func (v *spriteView) SetStreak(velocity *math32.Vector3) {
+	i := v.slot*spriteStride + 4
+	scale := streakSeconds / v.cloud.diameter
+	v.cloud.buffer[i], v.cloud.buffer[i+1], v.cloud.buffer[i+2] = velocity.X*scale, velocity.Y*scale, velocity.Z*scale
+	v.cloud.vbo.Update()
+}
+
+// Release hides the point and frees its slot
+func (v *spriteView) Release() {
+	v.SetVisible(false)
+	v.cloud.free = append(v.cloud.free, v.slot)
+}
+
+var windSprites, fluidSprites *SpriteCloud
+
+func newWindParticleSprite(position, direction math32.Vector3) ParticleView {
+	if windSprites == nil {
+		windSprites = newSpriteCloud("Cyan", 0.1)
+	}
+	return windSprites.add(position)
+}
+
+func newFluidParticleSprite(pos math32.Vector3) ParticleView {
+	if fluidSprites == nil {
+		fluidSprites = newSpriteCloud("Blue", 0.2)
+	}
+	return fluidSprites.add(pos)
+}
+
+// applyParticleViews picks the views of the particles from the render settings and
+// replaces those of the particles of every tab
+func applyParticleViews() {
+	kind := "meshes"
+	switch {
+	case renderSettings.PointSprites:
+		newWindParticleView, newFluidParticleView = newWindParticleSprite, newFluidParticleSprite
+		kind = "point sprites"
+	case renderSettings.ParticleLOD:
+		newWindParticleView, newFluidParticleView = newWindParticleMesh, newFluidParticleLOD
+		kind = "meshes, far ones as points"
+	default:
+		newWindParticleView, newFluidParticleView = newWindParticleMesh, newFluidParticleMesh
+	}
+	drainWindViewPool()
+	for i, tab := range tabs {
+		if i == activeTab {
+			replaceParticleViews(&windParticles, fluidParticles, true)
+		} else {
+			replaceParticleViews(&tab.windParticles, tab.fluidParticles, false)
+		}
+	}
+	log.Printf("Particles drawn as %s", kind)
+}
+
+// replaceParticleViews gives the particles new views from the current constructors
+func replaceParticleViews(w *WindParticles, fluid []Particle, visible bool) {
+	for i, m := range w.Mesh {
+		removeView(m)
+		direction := w.Velocity[i]
+		if direction.LengthSq() == 0 {
+			direction = math32.Vector3{X: 1}
+		}
+		w.Mesh[i] = newWindParticleView(w.Position[i], direction)
+		w.Mesh[i].SetVisible(visible)
+	}
+	for i := range fluid {
+		p := &fluid[i]
+		if p.Mesh != nil {
+			removeView(p.Mesh)
+		}
+		p.Mesh = newFluidParticleView(math32.Vector3{X: p.X, Y: p.Y, Z: p.Z})
+		p.Mesh.SetVisible(visible)
+	}
+}
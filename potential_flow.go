@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/g3n/engine/math32"
+)
+
+// In potential mode the field is the steady, inviscid and irrotational flow of a uniform
+// wind around the solid cells. A uniform field projected to be divergence free is exactly
+// that: the projection only subtracts a gradient, so no vorticity is added, and it stops
+// the flow at the solid faces. It takes one long pressure solve instead of a transient
+// run, handy for trying layouts before simulating them properly. There is no wake or
+// separation, the flow closes smoothly behind every obstacle.
+
+// potentialIterations is the length of the pressure solve, far more than the transient
+// steps need since the uniform field starts nowhere near divergence free
+const potentialIterations = 400
+
+// potentialFor is the freestream the field was last solved for, nil when it needs solving
+var potentialFor *math32.Vector3
+
+// freestream returns the uniform wind the potential flow is solved for: the wind of the
+// sources averaged with their volumes as weights
+func freestream(sources []WindSource) math32.Vector3 {
+	var sum math32.Vector3
+	var weight float32
+	for _, wind := range sources {
+		w := wind.Radius * wind.Radius * wind.Radius
+		sum.Add(wind.Direction.Clone().Normalize().MultiplyScalar(math32.Min(wind.Speed, maxFieldSpeed) * w))
+		weight += w
+	}
+	if weight == 0 {
+		return sum
+	}
+	return *sum.DivideScalar(weight)
+}
+
+// updatePotentialFlow solves the field again when the obstacles or the sources changed
+func updatePotentialFlow() {
+	f := &vectorField
+	f.markSolids(obstacleModels())
+	u := freestream(windSources)
+	if f.topology != nil && potentialFor != nil && *potentialFor == u {
+		return
+	}
+	f.solvePotentialFlow(u)
+	potentialFor = &u
+}
+
+// solvePotentialFlow replaces the field with the potential flow of the freestream u
+func (f *VectorField) solvePotentialFlow(u math32.Vector3) {
+	start := time.Now()
+	f.prepare()
+	values := [3]float32{u.X, u.Y, u.Z}
+	for _, face := range f.topology.openFaces {
+		f.setFace(face.axis, face.c, values[face.axis])
+	}
+	f.project(potentialIterations)
+	f.applyBoundaries()
+	// the projection pressure of a single solve has no time step to convert it with, but
+	// the flow is steady and irrotational so Bernoulli gives the pressure
+	f.substep = 0
+	f.changeRate = 0
+	f.storeBernoulliPressure(u)
+	log.Printf("Potential flow for freestream %v solved in %v", u, time.Since(start))
+}
+
+// storeBernoulliPressure sets the cell pressures of a potential flow with freestream u
+// from Bernoulli's equation, p = rho/2 (|u|² - |v|²) relative to the freestream
+func (f *VectorField) storeBernoulliPressure(u math32.Vector3) {
+	freestream := u.LengthSq()
+	f.forEachCell(func(c [3]int) {
+		v := f.SampleVelocity(f.cellCenter(c[0], c[1], c[2]))
+		f.cell(c).P = 0.5 * simConfig.AirDensity * (freestream - v.LengthSq())
+	})
+}
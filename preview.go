@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+)
+
+// In preview quality the flow field runs at half its resolution in each direction and
+// the wind sources and the fluid simulation put out a quarter of their particles, which
+// is quick enough to move sources and models around and see roughly what happens.
+// Nothing is recorded meanwhile; switching back to full quality restarts the field so
+// the recorded run starts from a full resolution flow.
+
+const (
+	fluidParticleCount = 250
+	previewDivisor     = 4 // particles in full quality per particle in preview
+)
+
+var previewQuality bool
+
+// fieldCells returns the resolution of the default domain for the current quality
+func fieldCells() (nx, ny, nz int) {
+	if previewQuality {
+		return 20, 5, 20 // 1 m cells
+	}
+	return 40, 10, 40 // 0.5 m cells
+}
+
+// particleCount scales a full quality particle count down in preview
+func particleCount(full int) int {
+	if previewQuality && full >= previewDivisor {
+		return full / previewDivisor
+	}
+	return full
+}
+
+// spawnInterval returns the simulated seconds between particles from a wind source
+func spawnInterval() float32 {
+	if previewQuality {
+		return windSpawnInterval * previewDivisor
+	}
+	return windSpawnInterval
+}
+
+// setPreviewQuality switches the quality and rebuilds the field and the fluid particles
+// to match
+func setPreviewQuality(preview bool, scene *core.Node) {
+	if preview == previewQuality {
+		return
+	}
+	previewQuality = preview
+	resetVectorField()
+	removeFluidParticles()
+	if len(windSources) > 0 {
+		fluidParticles = initParticles(particleCount(fluidParticleCount), windSources, scene)
+	}
+	spawnClock = 0
+	if preview {
+		log.Println("Preview quality: half field resolution, quarter particles, not recording")
+	} else {
+		log.Println("Full quality restored")
+	}
+}
+
+// previewWanted is the quality last chosen on the button, previewQuality follows it
+// once the queued rebuild ran
+var previewWanted bool
+
+func previewButtonText() string {
+	if previewWanted {
+		return "Quality: preview"
+	}
+	return "Quality: full"
+}
+
+func initializePreviewUI(scene *core.Node) {
+	previewBtn := gui.NewButton(previewButtonText())
+	previewBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		previewWanted = !previewWanted
+		requestFieldUpdate("quality", func() {
+			setPreviewQuality(previewWanted, scene)
+		})
+		previewBtn.Label.SetText(previewButtonText())
+		overlays.Notify(previewButtonText())
+	})
+	addSidebarWidget(scene, previewBtn)
+}
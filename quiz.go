@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// Educator mode hides the numeric readouts and asks questions about the flow that the
+// students answer by clicking on the ground. Each answer is graded against the point the
+// current field gives, by its distance from it.
+
+// QuizQuestion is a question with an answer point computed from the field
+type QuizQuestion struct {
+	Prompt string
+	// answer returns the point on the ground the question asks for, ok is false when
+	// the scene has none
+	answer func(f *VectorField) (math32.Vector3, bool)
+}
+
+// quizHeight is the height in m up to which the questions look at the flow, the layer
+// the clicks on the ground stand for
+const quizHeight = 2
+
+var quizQuestions = []QuizQuestion{
+	{"Where is the stagnation point in front of the obstacle?", stagnationAnswer},
+	{"Where is the wind fastest near the ground?", fastestAnswer},
+	{"Where is the pressure lowest near the ground?", lowestPressureAnswer},
+	{"Where does the air flow back against the wind?", recirculationAnswer},
+}
+
+// Quiz is the state of educator mode
+type Quiz struct {
+	Tolerance float32 // m an answer may be off and still count
+
+	readoutsHidden bool
+	question       int // index into quizQuestions
+	awaiting       bool
+	asked, correct int
+	markers        []*graphic.Mesh
+}
+
+var quiz = Quiz{Tolerance: 1.5}
+
+// readoutPanels show computed numbers and are hidden in educator mode
+var readoutPanels []gui.IPanel
+
+func registerReadout(panel gui.IPanel) {
+	readoutPanels = append(readoutPanels, panel)
+}
+
+// SetReadoutsHidden hides or shows the numeric readouts
+func (q *Quiz) SetReadoutsHidden(hidden bool) {
+	q.readoutsHidden = hidden
+	for _, panel := range readoutPanels {
+		panel.GetPanel().SetVisible(!hidden)
+	}
+}
+
+// nearGroundCells calls fn for the fluid cells up to quizHeight
+func nearGroundCells(f *VectorField, fn func(c [3]int, center math32.Vector3)) {
+	f.forEachCell(func(c [3]int) {
+		if !f.isInterior(c) || f.isSolid(c) {
+			return
+		}
+		center := f.cellCenter(c[0], c[1], c[2])
+		if center.Y*simConfig.LengthUnit > quizHeight {
+			return
+		}
+		fn(c, center)
+	})
+}
+
+// stagnationAnswer is the cell beside an obstacle with the highest pressure, where the
+// wind runs into it and stops
+func stagnationAnswer(f *VectorField) (math32.Vector3, bool) {
+	var best math32.Vector3
+	found := false
+	highest := float32(-math32.Infinity)
+	nearGroundCells(f, func(c [3]int, center math32.Vector3) {
+		touches := false
+		for axis := 0; axis < 3; axis++ {
+			for _, side := range []int{-1, 1} {
+				nb := c
+				nb[axis] += side
+				if f.isInterior(nb) && f.isSolid(nb) {
+					touches = true
+				}
+			}
+		}
+		if p := f.PressureAt(c); touches && p > highest {
+			highest, best, found = p, center, true
+		}
+	})
+	return best, found
+}
+
+func fastestAnswer(f *VectorField) (math32.Vector3, bool) {
+	var best math32.Vector3
+	found := false
+	fastest := float32(0)
+	nearGroundCells(f, func(c [3]int, center math32.Vector3) {
+		v := f.cellVelocity(c)
+		if speed := v.Length(); speed > fastest {
+			fastest, best, found = speed, center, true
+		}
+	})
+	return best, found
+}
+
+func lowestPressureAnswer(f *VectorField) (math32.Vector3, bool) {
+	var best math32.Vector3
+	found := false
+	lowest := float32(0)
+	nearGroundCells(f, func(c [3]int, center math32.Vector3) {
+		if p := f.PressureAt(c); p < lowest {
+			lowest, best, found = p, center, true
+		}
+	})
+	return best, found
+}
+
+// recirculationAnswer is the cell flowing fastest against the mean flow, in the wake
+// behind an obstacle
+func recirculationAnswer(f *VectorField) (math32.Vector3, bool) {
+	var mean math32.Vector3
+	nearGroundCells(f, func(c [3]int, center math32.Vector3) {
+		v := f.cellVelocity(c)
+		mean.Add(&v)
+	})
+	mean.Y = 0
+	if mean.Length() < 1e-6 {
+		return math32.Vector3{}, false
+	}
+	mean.Normalize()
+	var best math32.Vector3
+	found := false
+	backwards := float32(0)
+	nearGroundCells(f, func(c [3]int, center math32.Vector3) {
+		v := f.cellVelocity(c)
+		if along := v.Dot(&mean); along < backwards {
+			backwards, best, found = along, center, true
+		}
+	})
+	return best, found
+}
+
+// Ask poses question i, the next click on the ground answers it
+func (q *Quiz) Ask(i int) {
+	q.clearMarkers()
+	q.question = i
+	q.awaiting = true
+	overlays.Notify(quizQuestions[i].Prompt + " Click on the ground.")
+}
+
+// Answer grades a click at point
+func (q *Quiz) Answer(point math32.Vector3) {
+	q.awaiting = false
+	question := quizQuestions[q.question]
+	truth, ok := question.answer(&vectorField)
+	if !ok {
+		overlays.Notify("This scene has no answer to that question, try another one")
+		return
+	}
+	dx, dz := point.X-truth.X, point.Z-truth.Z
+	off := math32.Sqrt(dx*dx+dz*dz) * simConfig.LengthUnit
+	q.asked++
+	verdict := fmt.Sprintf("Off by %.1f m", off)
+	if off <= q.Tolerance {
+		q.correct++
+		verdict = fmt.Sprintf("Correct, %.1f m off", off)
+	}
+	log.Printf("Quiz %q answered at %v, computed %v: %s", question.Prompt, point, truth, verdict)
+	q.addMarker(point, "Orange")
+	q.addMarker(math32.Vector3{X: truth.X, Z: truth.Z}, "LimeGreen")
+	overlays.Notify(fmt.Sprintf("%s (score %d/%d)", verdict, q.correct, q.asked))
+}
+
+func (q *Quiz) addMarker(pos math32.Vector3, color string) {
+	marker := graphic.NewMesh(geometry.NewSphere(0.25, 16, 16), material.NewStandard(math32.NewColor(color)))
+	marker.SetPositionVec(&pos)
+	objects.Add(marker, "quiz marker")
+	q.markers = append(q.markers, marker)
+}
+
+func (q *Quiz) clearMarkers() {
+	for _, marker := range q.markers {
+		objects.Remove(marker)
+	}
+	q.markers = nil
+}
+
+// showQuizDialog switches educator mode and asks the questions
+func showQuizDialog(scene *core.Node) {
+	dialog := gui.NewPanel(400, float32(150+30*len(quizQuestions)))
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Quiz")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	hideCheck := gui.NewCheckBox("Hide numeric readouts")
+	hideCheck.SetPosition(10, 35)
+	hideCheck.SetValue(quiz.readoutsHidden)
+	hideCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		quiz.SetReadoutsHidden(hideCheck.Value())
+	})
+	dialog.Add(hideCheck)
+
+	toleranceLabel := gui.NewLabel("Tolerance")
+	toleranceLabel.SetPosition(10, 68)
+	dialog.Add(toleranceLabel)
+	toleranceInput := NewNumericInput(quiz.Tolerance, 0.25, 10, 0.25, "m", func(value float32) {
+		quiz.Tolerance = value
+	})
+	toleranceInput.SetPosition(140, 65)
+	dialog.Add(toleranceInput)
+
+	y := float32(100)
+	for i, question := range quizQuestions {
+		i := i
+		askBtn := gui.NewButton(question.Prompt)
+		askBtn.SetPosition(10, y)
+		askBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+			overlays.CloseModal(dialog)
+			quiz.Ask(i)
+		})
+		dialog.Add(askBtn)
+		y += 30
+	}
+
+	score := gui.NewLabel(fmt.Sprintf("Score %d/%d", quiz.correct, quiz.asked))
+	score.SetPosition(10, y+13)
+	dialog.Add(score)
+
+	resetBtn := gui.NewButton("Reset score")
+	resetBtn.SetPosition(110, y+10)
+	resetBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		quiz.asked, quiz.correct = 0, 0
+		quiz.clearMarkers()
+		score.SetText("Score 0/0")
+	})
+	dialog.Add(resetBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(340, y+10)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeQuizUI(scene *core.Node, cam camera.ICamera) {
+	quizBtn := gui.NewButton("Quiz...")
+	quizBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showQuizDialog(scene)
+	})
+	addSidebarWidget(scene, quizBtn)
+
+	app.App().Subscribe(window.OnMouseDown, func(evname string, ev interface{}) {
+		if !quiz.awaiting || overlays.Blocking() {
+			return
+		}
+		mev := ev.(*window.MouseEvent)
+		if mev.Button != window.MouseButtonLeft {
+			return
+		}
+		point, ok := pickGroundPoint(cam, mev.Xpos, mev.Ypos)
+		if !ok {
+			return
+		}
+		quiz.Answer(*point)
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// LimitPolicy decides what happens when a recording grows past its size limit
+type LimitPolicy int
+
+const (
+	PolicyDownsample LimitPolicy = iota // drop every other frame and keep recording into the same file
+	PolicyRotate                        // write the current file and start a new one
+)
+
+// RecordingLimits caps how large an in-memory recording may grow.
+// Zero values disable the corresponding limit.
+type RecordingLimits struct {
+	MaxSizeMB      float32
+	MaxDurationMin float32 // always rotates, downsampling can't shorten a recording
+	Policy         LimitPolicy
+}
+
+var recordingLimits = RecordingLimits{
+	MaxSizeMB:      50,
+	MaxDurationMin: 0,
+	Policy:         PolicyDownsample,
+}
+
+var recordingStart time.Time
+
+// frameSizeEstimate is the encoded size of one frame in bytes, sampled from the recording
+var frameSizeEstimate int
+
+// estimatedRecordingSize returns the approximate size in bytes of the current recording on disk
+func estimatedRecordingSize() int {
+	if len(simulationData) == 0 {
+		return 0
+	}
+	if frameSizeEstimate == 0 || len(simulationData)%100 == 0 {
+		if data, err := json.Marshal(simulationData[len(simulationData)-1]); err == nil {
+			frameSizeEstimate = len(data) + 1
+		}
+	}
+	return frameSizeEstimate * len(simulationData)
+}
+
+// enforceRecordingLimits is called after every recorded frame
+func enforceRecordingLimits() {
+	if len(simulationData) == 1 {
+		recordingStart = time.Now()
+	}
+
+	if recordingLimits.MaxDurationMin > 0 && time.Since(recordingStart).Minutes() > float64(recordingLimits.MaxDurationMin) {
+		log.Printf("Recording reached %.1f min, rotating file", recordingLimits.MaxDurationMin)
+		rotateRecording()
+		return
+	}
+
+	if recordingLimits.MaxSizeMB > 0 && float32(estimatedRecordingSize()) > recordingLimits.MaxSizeMB*1e6 {
+		switch recordingLimits.Policy {
+		case PolicyDownsample:
+			downsampleRecording()
+		case PolicyRotate:
+			log.Printf("Recording reached %.0f MB, rotating file", recordingLimits.MaxSizeMB)
+			rotateRecording()
+		}
+	}
+}
+
+// downsampleRecording halves the number of frames, keeping every other one
+func downsampleRecording() {
+	kept := simulationData[:0]
+	for i, frame := range simulationData {
+		if i%2 == 0 {
+			kept = append(kept, frame)
+		}
+	}
+	simulationData = kept
+	log.Printf("Recording downsampled to %d frames", len(simulationData))
+}
+
+// rotateRecording writes the current recording to disk and starts an empty one
+func rotateRecording() {
+	saveSimulationData()
+	simulationData = nil
+	eventMarkers = nil
+}
+
+// RecordingFile is a simulation data file found in the working directory
+type RecordingFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// listRecordingFiles returns the saved simulation data files, oldest first
+func listRecordingFiles() ([]RecordingFile, error) {
+	matches, err := filepath.Glob("simulation_data_*.json")
+	if err != nil {
+		return nil, err
+	}
+	var files []RecordingFile
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, RecordingFile{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.Before(files[j].ModTime) })
+	return files, nil
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1e9:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/1e9)
+	case bytes >= 1e6:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/1e6)
+	default:
+		return fmt.Sprintf("%.1f kB", float64(bytes)/1e3)
+	}
+}
+
+func policyLabel() string {
+	if recordingLimits.Policy == PolicyRotate {
+		return "When full: rotate"
+	}
+	return "When full: downsample"
+}
+
+// showRecordingCleanupDialog lists the saved recordings with their sizes so old ones can be deleted,
+// together with the recording size limits
+func showRecordingCleanupDialog(scene *core.Node) {
+	dialog := gui.NewPanel(420, 380)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Recordings")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	sizeLabel := gui.NewLabel("Max size MB")
+	sizeLabel.SetPosition(10, 35)
+	dialog.Add(sizeLabel)
+	sizeInput := NewNumericInput(recordingLimits.MaxSizeMB, 0, 10000, 10, "MB", func(value float32) {
+		recordingLimits.MaxSizeMB = value
+	})
+	sizeInput.SetPosition(100, 32)
+	dialog.Add(sizeInput)
+
+	durationLabel := gui.NewLabel("Max minutes")
+	durationLabel.SetPosition(210, 35)
+	dialog.Add(durationLabel)
+	durationInput := NewNumericInput(recordingLimits.MaxDurationMin, 0, 600, 1, "min", func(value float32) {
+		recordingLimits.MaxDurationMin = value
+	})
+	durationInput.SetPosition(300, 32)
+	dialog.Add(durationInput)
+
+	policyBtn := gui.NewButton(policyLabel())
+	policyBtn.SetPosition(10, 60)
+	policyBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if recordingLimits.Policy == PolicyDownsample {
+			recordingLimits.Policy = PolicyRotate
+		} else {
+			recordingLimits.Policy = PolicyDownsample
+		}
+		policyBtn.Label.SetText(policyLabel())
+	})
+	dialog.Add(policyBtn)
+
+	list := gui.NewVList(400, 220)
+	list.SetSingle(false)
+	list.SetPosition(10, 95)
+	dialog.Add(list)
+
+	totalLabel := gui.NewLabel("")
+	totalLabel.SetPosition(10, 322)
+	dialog.Add(totalLabel)
+
+	paths := map[gui.IPanel]string{}
+	refresh := func() {
+		list.Clear()
+		paths = map[gui.IPanel]string{}
+		files, err := listRecordingFiles()
+		if err != nil {
+			log.Println("Error listing recordings:", err)
+		}
+		var total int64
+		for _, f := range files {
+			item := gui.NewLabel(fmt.Sprintf("%s  %s  %s", f.ModTime.Format("2006-01-02 15:04"), formatSize(f.Size), f.Path))
+			list.Add(item)
+			paths[item] = f.Path
+			total += f.Size
+		}
+		totalLabel.SetText(fmt.Sprintf("%d files, %s", len(files), formatSize(total)))
+	}
+	refresh()
+
+	deleteBtn := gui.NewButton("Delete selected")
+	deleteBtn.SetPosition(10, 345)
+	deleteBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		for _, item := range list.Selected() {
+			path := paths[item]
+			if err := os.Remove(path); err != nil {
+				log.Println("Error deleting recording:", err)
+				continue
+			}
+			log.Printf("Deleted recording %s", path)
+		}
+		refresh()
+	})
+	dialog.Add(deleteBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(350, 345)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeRecordingLimitsUI(scene *core.Node) {
+	cleanupBtn := gui.NewButton("Recordings...")
+	cleanupBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showRecordingCleanupDialog(scene)
+	})
+	addSidebarWidget(scene, cleanupBtn)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/window"
+)
+
+// GPU state that depends on the size of the window, the viewport, the camera's aspect,
+// the pixel size of the point sprites, registers here and is rebuilt when the size
+// changes. A minimized window reports a size of zero, which would give the camera an
+// infinite aspect and the captures an empty image; the resources keep their last size
+// until the window has an area again. GLFW never loses the context of a window, so
+// there is no context event to rebuild for.
+
+// renderResizes are the rebuilds of the window sized resources
+var renderResizes []func(w, h int)
+
+// renderSize is the size the resources were last built for
+var renderSize [2]int
+
+// initializeRenderResources subscribes the rebuilds to window resizes
+func initializeRenderResources() {
+	app.App().Subscribe(window.OnWindowSize, func(evname string, ev interface{}) {
+		resizeRenderResources()
+	})
+	renderSize[0], renderSize[1] = app.App().GetSize()
+}
+
+// onRenderResize registers a rebuild and runs it for the current size right away
+func onRenderResize(rebuild func(w, h int)) {
+	renderResizes = append(renderResizes, rebuild)
+	if renderSize[0] > 0 && renderSize[1] > 0 {
+		rebuild(renderSize[0], renderSize[1])
+	}
+}
+
+func resizeRenderResources() {
+	w, h := app.App().GetSize()
+	if w <= 0 || h <= 0 || (w == renderSize[0] && h == renderSize[1]) {
+		return
+	}
+	renderSize = [2]int{w, h}
+	for _, rebuild := range renderResizes {
+		rebuild(w, h)
+	}
+	log.Printf("Render resources rebuilt for %dx%d", w, h)
+}
+
+// windowMinimized reports whether the window has no area to render to
+func windowMinimized() bool {
+	w, h := app.App().GetFramebufferSize()
+	return w <= 0 || h <= 0
+}
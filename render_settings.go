@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/light"
+	"github.com/g3n/engine/math32"
+)
+
+// RenderSettings holds the render-quality options exposed in the settings panel
+type RenderSettings struct {
+	MSAA             bool    // g3n creates the window with 8 samples, this toggles GL_MULTISAMPLE
+	ParticleDetail   int     // sphere segments used for fluid particles
+	Shadows          bool    // project the imported model onto the ground along the sun direction
+	ParticleShadows  bool    // also project dense particle clusters when shadows are on
+	AmbientIntensity float32 // intensity of the scene ambient light
+	MaxFPS           float32 // frame rate cap, 0 for none
+	BackgroundFPS    float32 // lower cap while the window is unfocused or minimized, 0 for none
+	PointSprites     bool    // draw the particles as point sprites instead of meshes
+	ParticleLOD      bool    // draw far fluid particles as points when drawing meshes
+	MeshStreaks      bool    // stretch the particle meshes along their velocity
+	SpriteStreaks    bool    // draw the point sprites as streaks along their velocity
+}
+
+var renderSettings = RenderSettings{
+	MSAA:             true,
+	ParticleDetail:   8,
+	Shadows:          false,
+	ParticleShadows:  false,
+	AmbientIntensity: 0.8,
+	MaxFPS:           60,
+	BackgroundFPS:    5,
+}
+
+// particleDetailLevels are the sphere segment counts cycled by the detail button
+var particleDetailLevels = []int{8, 16, 32}
+
+var ambientLight *light.Ambient
+
+// applyRenderSettings pushes the current settings to the GL state and the scene
+func applyRenderSettings() {
+	gs := app.App().Gls()
+	if renderSettings.MSAA {
+		gs.Enable(gls.MULTISAMPLE)
+	} else {
+		gs.Disable(gls.MULTISAMPLE)
+	}
+	if ambientLight != nil {
+		ambientLight.SetIntensity(renderSettings.AmbientIntensity)
+	}
+	log.Printf("Render settings applied: %+v", renderSettings)
+}
+
+func nextParticleDetail(current int) int {
+	for i, level := range particleDetailLevels {
+		if level == current {
+			return particleDetailLevels[(i+1)%len(particleDetailLevels)]
+		}
+	}
+	return particleDetailLevels[0]
+}
+
+func msaaLabel() string {
+	if renderSettings.MSAA {
+		return "MSAA 8x"
+	}
+	return "MSAA OFF"
+}
+
+func initializeRenderSettingsUI(scene *core.Node) {
+	panel := gui.NewPanel(140, 354)
+	panel.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	addBottomDockPanel(scene, panel)
+
+	title := gui.NewLabel("Render quality")
+	title.SetPosition(10, 5)
+	panel.Add(title)
+
+	msaaBtn := gui.NewButton(msaaLabel())
+	msaaBtn.SetPosition(10, 25)
+	msaaBtn.SetSize(120, 25)
+	msaaBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		renderSettings.MSAA = !renderSettings.MSAA
+		msaaBtn.Label.SetText(msaaLabel())
+		applyRenderSettings()
+	})
+	panel.Add(msaaBtn)
+
+	detailBtn := gui.NewButton(fmt.Sprintf("Detail %d", renderSettings.ParticleDetail))
+	detailBtn.SetPosition(10, 55)
+	detailBtn.SetSize(120, 25)
+	detailBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		renderSettings.ParticleDetail = nextParticleDetail(renderSettings.ParticleDetail)
+		detailBtn.Label.SetText(fmt.Sprintf("Detail %d", renderSettings.ParticleDetail))
+		rebuildParticleMeshes(scene)
+	})
+	panel.Add(detailBtn)
+
+	shadowCheck := gui.NewCheckBox("Shadows")
+	shadowCheck.SetPosition(10, 88)
+	shadowCheck.SetValue(renderSettings.Shadows)
+	shadowCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		renderSettings.Shadows = shadowCheck.Value()
+		applyRenderSettings()
+	})
+	panel.Add(shadowCheck)
+
+	particleShadowCheck := gui.NewCheckBox("Particle shadows")
+	particleShadowCheck.SetPosition(10, 110)
+	particleShadowCheck.SetValue(renderSettings.ParticleShadows)
+	particleShadowCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		renderSettings.ParticleShadows = particleShadowCheck.Value()
+	})
+	panel.Add(particleShadowCheck)
+
+	ambientLabel := gui.NewLabel("Ambient")
+	ambientLabel.SetPosition(10, 137)
+	panel.Add(ambientLabel)
+
+	ambientInput := NewNumericInput(renderSettings.AmbientIntensity, 0, 2, 0.1, "", func(value float32) {
+		renderSettings.AmbientIntensity = value
+		applyRenderSettings()
+	})
+	ambientInput.SetPosition(10, 157)
+	panel.Add(ambientInput)
+
+	fpsLabel := gui.NewLabel("Max FPS (0: off)")
+	fpsLabel.SetPosition(10, 185)
+	panel.Add(fpsLabel)
+	fpsInput := NewNumericInput(renderSettings.MaxFPS, 0, 240, 10, "", func(value float32) {
+		renderSettings.MaxFPS = value
+	})
+	fpsInput.SetPosition(10, 205)
+	panel.Add(fpsInput)
+
+	backgroundLabel := gui.NewLabel("In background")
+	backgroundLabel.SetPosition(10, 233)
+	panel.Add(backgroundLabel)
+	backgroundInput := NewNumericInput(renderSettings.BackgroundFPS, 0, 240, 1, "", func(value float32) {
+		renderSettings.BackgroundFPS = value
+	})
+	backgroundInput.SetPosition(10, 253)
+	panel.Add(backgroundInput)
+
+	streaksCheck := gui.NewCheckBox("Streaks")
+	spritesCheck := gui.NewCheckBox("Point sprites")
+	spritesCheck.SetPosition(10, 283)
+	spritesCheck.SetValue(renderSettings.PointSprites)
+	spritesCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		renderSettings.PointSprites = spritesCheck.Value()
+		applyParticleViews()
+		streaksCheck.SetValue(streaksEnabled())
+	})
+	panel.Add(spritesCheck)
+
+	// streaks are chosen for meshes and sprites separately, the box shows the current ones
+	streaksCheck.SetPosition(10, 305)
+	streaksCheck.SetValue(streaksEnabled())
+	streaksCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		if streaksCheck.Value() != streaksEnabled() {
+			setStreaks(streaksCheck.Value())
+		}
+	})
+	panel.Add(streaksCheck)
+
+	lodCheck := gui.NewCheckBox("Distance LOD")
+	lodCheck.SetPosition(10, 327)
+	lodCheck.SetValue(renderSettings.ParticleLOD)
+	lodCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		renderSettings.ParticleLOD = lodCheck.Value()
+		applyParticleViews()
+	})
+	panel.Add(lodCheck)
+
+	applyRenderSettings()
+}
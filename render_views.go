@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// The simulation moves and hides the meshes that show the particles and marks the wind
+// sources, and needs nothing else of them, so it only sees them through the interfaces
+// below. The g3n meshes implement them. Code that runs the simulation without a GL
+// context, to record what would be shown or to check the simulation on its own, puts
+// its own views in their place by replacing the constructors.
+
+// ParticleView shows one particle
+type ParticleView interface {
+	SetPositionVec(pos *math32.Vector3)
+	SetVisible(visible bool)
+}
+
+// SourceMarker shows one wind source
+type SourceMarker interface {
+	SetPositionVec(pos *math32.Vector3)
+}
+
+// The constructors of the views, which also add them to the scene
+var (
+	newWindParticleView  = newWindParticleMesh
+	newFluidParticleView = newFluidParticleMesh
+	newSourceMarker      = newSourceMarkerMesh
+)
+
+// removeView takes a view out of the scene. Views that share a scene node with others
+// release their part of it, other views have nothing to take out.
+func removeView(view interface{}) {
+	switch v := view.(type) {
+	case core.INode:
+		objects.Remove(v)
+	case interface{ Release() }:
+		v.Release()
+	}
+}
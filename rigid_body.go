@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// RigidBody lets the aerodynamic moment turn the model as well as the force push it: the
+// model is a rigid body with the mass of the control column and the principal moments
+// of inertia below, about axes through its position along its own axes. A light model
+// in a strong wind tumbles and its new attitude changes the flow around it in turn.
+type RigidBody struct {
+	Enabled bool
+	Inertia math32.Vector3 // kg m² about the model's x, y and z axes
+
+	angularVelocity math32.Vector3 // rad/s in world space
+}
+
+// maxAngularSpeed keeps a model with a tiny inertia from spinning the step apart
+const maxAngularSpeed = 20
+
+var rigidBody = RigidBody{Inertia: math32.Vector3{X: 1, Y: 1, Z: 1}}
+
+// Rotate turns model by moment, in N m about the model's position in world space, over dt
+// by Euler's equations in the body frame, I dw/dt + w x (I w) = M
+func (r *RigidBody) Rotate(model *core.Node, moment math32.Vector3, dt float32) {
+	if !r.Enabled || r.Inertia.X <= 0 || r.Inertia.Y <= 0 || r.Inertia.Z <= 0 {
+		return
+	}
+	q := model.Quaternion()
+	toBody := q.Clone().Conjugate()
+	m := moment.Clone().ApplyQuaternion(toBody)
+	w := r.angularVelocity.Clone().ApplyQuaternion(toBody)
+
+	iw := math32.Vector3{X: r.Inertia.X * w.X, Y: r.Inertia.Y * w.Y, Z: r.Inertia.Z * w.Z}
+	m.Sub(w.Clone().Cross(&iw))
+	w.Add(&math32.Vector3{X: m.X / r.Inertia.X * dt, Y: m.Y / r.Inertia.Y * dt, Z: m.Z / r.Inertia.Z * dt})
+	r.angularVelocity = *w.ApplyQuaternion(&q)
+	if speed := r.angularVelocity.Length(); speed > maxAngularSpeed {
+		r.angularVelocity.MultiplyScalar(maxAngularSpeed / speed)
+	}
+
+	speed := r.angularVelocity.Length()
+	if speed == 0 {
+		return
+	}
+	axis := r.angularVelocity.Clone().DivideScalar(speed)
+	var turn math32.Quaternion
+	turn.SetFromAxisAngle(axis, speed*dt)
+	turn.Multiply(&q).Normalize()
+	model.SetQuaternionQuat(&turn)
+}
+
+// Reset stops the model turning and stands it upright again
+func (r *RigidBody) Reset(model *core.Node) {
+	r.angularVelocity = math32.Vector3{}
+	if model != nil {
+		model.SetQuaternion(0, 0, 0, 1)
+	}
+}
+
+func showRigidBodyDialog() {
+	dialog := gui.NewPanel(300, 200)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Rigid body")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	enabledCheck := gui.NewCheckBox("Moments turn the model")
+	enabledCheck.SetPosition(10, 35)
+	enabledCheck.SetValue(rigidBody.Enabled)
+	enabledCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		rigidBody.Enabled = enabledCheck.Value()
+		rigidBody.angularVelocity = math32.Vector3{}
+	})
+	dialog.Add(enabledCheck)
+
+	y := float32(65)
+	for i, name := range []string{"Inertia x", "Inertia y", "Inertia z"} {
+		axis := i
+		label := gui.NewLabel(name)
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		input := NewNumericInput(rigidBody.Inertia.Component(axis), 0.0001, 100000, 0.1, "kg m2", func(value float32) {
+			rigidBody.Inertia.SetComponent(axis, value)
+		})
+		input.SetPosition(140, y)
+		dialog.Add(input)
+		y += 30
+	}
+
+	resetBtn := gui.NewButton("Stand upright")
+	resetBtn.SetPosition(10, 165)
+	resetBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		rigidBody.Reset(mesh)
+	})
+	dialog.Add(resetBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(240, 165)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeRigidBodyUI(scene *core.Node) {
+	rigidBtn := gui.NewButton("Rigid Body...")
+	rigidBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showRigidBodyDialog()
+	})
+	addSidebarWidget(scene, rigidBtn)
+}
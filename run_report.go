@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"runtime"
+	"time"
+)
+
+// runManifestFile collects one line per run, so the cost of the runs can be compared
+// with what was in their scenes when planning bigger studies
+const runManifestFile = "run_manifest.jsonl"
+
+// RunReport is the line of a run in the manifest
+type RunReport struct {
+	Recording  string    `json:"recording"`
+	Started    time.Time `json:"started"`
+	WallTime   float64   `json:"wall_s"`
+	CPUTime    float64   `json:"cpu_s"` // user and system time of the process, 0 when unknown
+	PeakMemory float64   `json:"peak_memory_mb"`
+	AverageFPS float64   `json:"average_fps"`
+	Simulated  float32   `json:"simulated_s"`
+
+	Solver              string  `json:"solver"`
+	FieldCells          int     `json:"field_cells"`
+	PressureIterations  int     `json:"pressure_iterations"`
+	FieldSteps          int     `json:"field_steps"`
+	FieldStepTime       float64 `json:"field_step_ms"` // mean
+	FinalChangeRate     float32 `json:"final_change_rate"`
+	WindSources         int     `json:"wind_sources"`
+	PeakWindParticles   int     `json:"peak_wind_particles"`
+	PeakVortexParticles int     `json:"peak_vortex_particles"`
+}
+
+// RunStats gathers what the report needs while the run goes on
+type RunStats struct {
+	start      time.Time
+	frames     int
+	fieldSteps int
+	fieldTime  time.Duration
+	peakWind   int
+	peakVortex int
+	peakMemory uint64 // bytes the Go runtime got from the system, sampled
+	lastSample time.Time
+}
+
+var runStats = RunStats{start: time.Now()}
+
+// memorySampleInterval keeps ReadMemStats, which stops the world, off most frames
+const memorySampleInterval = time.Second
+
+// Frame is called once per rendered frame
+func (s *RunStats) Frame() {
+	s.frames++
+	if n := windParticles.Len(); n > s.peakWind {
+		s.peakWind = n
+	}
+	if n := len(vortexParticles); n > s.peakVortex {
+		s.peakVortex = n
+	}
+	if time.Since(s.lastSample) >= memorySampleInterval {
+		s.lastSample = time.Now()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.Sys > s.peakMemory {
+			s.peakMemory = m.Sys
+		}
+	}
+}
+
+// FieldStep adds a flow field step that began at start, for use with defer
+func (s *RunStats) FieldStep(start time.Time) {
+	s.fieldSteps++
+	s.fieldTime += time.Since(start)
+}
+
+// Report summarizes the run up to now
+func (s *RunStats) Report(recording string) RunReport {
+	wall := time.Since(s.start).Seconds()
+	r := RunReport{
+		Recording:           recording,
+		Started:             s.start,
+		WallTime:            wall,
+		PeakMemory:          float64(s.peakMemory) / (1 << 20),
+		Simulated:           historyClock,
+		Solver:              solverModeNames[simConfig.Solver],
+		PressureIterations:  simConfig.SolverIterations,
+		FieldSteps:          s.fieldSteps,
+		FinalChangeRate:     vectorField.ChangeRate(),
+		WindSources:         len(windSources),
+		PeakWindParticles:   s.peakWind,
+		PeakVortexParticles: s.peakVortex,
+	}
+	// the topology is only built on the first grid step, so a run on the SPH solver or
+	// one that never stepped has none
+	if vectorField.topology != nil {
+		r.FieldCells = len(vectorField.topology.fluidCells)
+	}
+	if wall > 0 {
+		r.AverageFPS = float64(s.frames) / wall
+	}
+	if s.fieldSteps > 0 {
+		r.FieldStepTime = s.fieldTime.Seconds() * 1000 / float64(s.fieldSteps)
+	}
+	if cpu, peak, ok := processUsage(); ok {
+		r.CPUTime = cpu.Seconds()
+		// the resident peak includes the driver and C libraries the Go runtime doesn't see
+		if mb := float64(peak) / (1 << 20); mb > r.PeakMemory {
+			r.PeakMemory = mb
+		}
+	}
+	return r
+}
+
+// appendRunReport adds the report of the run that recorded to recording to the manifest
+func appendRunReport(recording string) {
+	report := runStats.Report(recording)
+	file, err := os.OpenFile(runManifestFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Run manifest: %v", err)
+		return
+	}
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(report); err != nil {
+		log.Printf("Run manifest: %v", err)
+		return
+	}
+	log.Printf("Run report appended to %s: %.1f s CPU, %.0f MB peak, %.1f fps",
+		runManifestFile, report.CPUTime, report.PeakMemory, report.AverageFPS)
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// processUsage returns the CPU time the process used so far and its peak resident size
+// in bytes
+func processUsage() (cpu time.Duration, peak uint64, ok bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0, false
+	}
+	cpu = time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+	peak = uint64(ru.Maxrss)
+	// Linux counts in kilobytes, macOS in bytes
+	if runtime.GOOS != "darwin" {
+		peak *= 1024
+	}
+	return cpu, peak, true
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// ScenarioConstraints bound the scenes the "surprise me" generator makes. The same seed
+// always gives the same scene, so a class can hand every student their own seed and the
+// teacher can recreate any of the cases.
+type ScenarioConstraints struct {
+	Seed                   int64
+	MinSources, MaxSources int
+	MinSpeed, MaxSpeed     float32 // m/s at the sources and of the ambient wind
+	TemperatureSpread      float32 // °C the sources may differ from the ambient air
+	MinObstacles           int
+	MaxObstacles           int
+	MaxObstacleSize        float32 // m across
+	AmbientChance          float32 // probability of an ambient wind, 0 to 1
+}
+
+var scenarioConstraints = ScenarioConstraints{
+	Seed:            1,
+	MinSources:      1,
+	MaxSources:      3,
+	MinSpeed:        2,
+	MaxSpeed:        10,
+	MinObstacles:    1,
+	MaxObstacles:    4,
+	MaxObstacleSize: 3,
+	AmbientChance:   0.3,
+}
+
+// scenarioMargin keeps the obstacles away from the domain sides, where the sources are
+const scenarioMargin = 3
+
+// ScenarioObstacle is a box or an upright cylinder of a generated scene
+type ScenarioObstacle struct {
+	Cylinder bool
+	Position math32.Vector3 // center of the base
+	Size     float32        // m across
+	Height   float32
+}
+
+// Scenario is a generated scene
+type Scenario struct {
+	Seed      int64
+	Sources   []WindSource
+	Obstacles []ScenarioObstacle
+	Ambient   AmbientWind
+}
+
+// scenarioObstacles are the nodes of the obstacles of the generated scene
+var scenarioObstacles []*core.Node
+
+// GenerateScenario makes a random scene within c. Obstacles don't overlap each other and
+// the sources stand clear of them, around the domain and blowing roughly towards its
+// middle, so every case has some flow around some obstacles.
+func GenerateScenario(c ScenarioConstraints) Scenario {
+	rng := rand.New(rand.NewSource(c.Seed))
+	between := func(lo, hi float32) float32 {
+		return lo + rng.Float32()*(hi-lo)
+	}
+	count := func(lo, hi int) int {
+		if hi <= lo {
+			return lo
+		}
+		return lo + rng.Intn(hi-lo+1)
+	}
+	halfWidth := float32(vectorField.Width) / 2
+	halfDepth := float32(vectorField.Depth) / 2
+	maxHeight := math32.Max(float32(vectorField.Height)-1, 1)
+
+	s := Scenario{Seed: c.Seed}
+	overlaps := func(pos math32.Vector3, radius float32) bool {
+		for _, o := range s.Obstacles {
+			dx, dz := pos.X-o.Position.X, pos.Z-o.Position.Z
+			clearance := radius + o.Size/2 + 0.5
+			if dx*dx+dz*dz < clearance*clearance {
+				return true
+			}
+		}
+		return false
+	}
+
+	for n := count(c.MinObstacles, c.MaxObstacles); len(s.Obstacles) < n; {
+		placed := false
+		for try := 0; try < 50 && !placed; try++ {
+			size := between(1, math32.Max(c.MaxObstacleSize, 1))
+			pos := math32.Vector3{
+				X: between(-halfWidth+scenarioMargin+size/2, halfWidth-scenarioMargin-size/2),
+				Z: between(-halfDepth+scenarioMargin+size/2, halfDepth-scenarioMargin-size/2),
+			}
+			if overlaps(pos, size/2) {
+				continue
+			}
+			s.Obstacles = append(s.Obstacles, ScenarioObstacle{
+				Cylinder: rng.Intn(2) == 0,
+				Position: pos,
+				Size:     size,
+				Height:   between(1, maxHeight),
+			})
+			placed = true
+		}
+		if !placed {
+			break // the domain is full
+		}
+	}
+
+	for n, try := count(c.MinSources, c.MaxSources), 0; len(s.Sources) < n && try < 200; try++ {
+		angle := between(0, 2*math32.Pi)
+		pos := math32.Vector3{
+			X: math32.Cos(angle) * (halfWidth - 1.5),
+			Y: between(1, math32.Min(3, maxHeight)),
+			Z: math32.Sin(angle) * (halfDepth - 1.5),
+		}
+		if overlaps(pos, 1) {
+			continue
+		}
+		// towards the middle give or take 20°
+		heading := angle + math32.Pi + math32.DegToRad(between(-20, 20))
+		s.Sources = append(s.Sources, WindSource{
+			Position:    pos,
+			Radius:      between(1, 3),
+			Speed:       between(c.MinSpeed, c.MaxSpeed),
+			Direction:   math32.Vector3{X: math32.Cos(heading), Z: math32.Sin(heading)},
+			Temperature: defaultTemperature + between(-c.TemperatureSpread, c.TemperatureSpread),
+		})
+	}
+
+	s.Ambient = ambientWind
+	s.Ambient.Enabled = rng.Float32() < c.AmbientChance
+	if s.Ambient.Enabled {
+		s.Ambient.Speed = between(c.MinSpeed, c.MaxSpeed)
+		s.Ambient.Heading = float32(15 * rng.Intn(24))
+		s.Ambient.Roughness = []float32{0.01, 0.1, 0.5, 1}[rng.Intn(4)] // grass to suburbs
+	}
+	return s
+}
+
+// Describe lists the scene for the exercise sheet
+func (s Scenario) Describe() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Scenario %d\n", s.Seed)
+	for i, w := range s.Sources {
+		heading := math32.RadToDeg(math32.Atan2(w.Direction.Z, w.Direction.X))
+		fmt.Fprintf(&b, "Source %d at (%.1f, %.1f, %.1f) m: %.1f m/s, heading %.0f°, %.1f °C\n",
+			i+1, w.Position.X, w.Position.Y, w.Position.Z, w.Speed, heading, w.Temperature)
+	}
+	for i, o := range s.Obstacles {
+		shape := "box"
+		if o.Cylinder {
+			shape = "cylinder"
+		}
+		fmt.Fprintf(&b, "Obstacle %d: %s %.1f m across, %.1f m high at (%.1f, %.1f)\n",
+			i+1, shape, o.Size, o.Height, o.Position.X, o.Position.Z)
+	}
+	if s.Ambient.Enabled {
+		fmt.Fprintf(&b, "Ambient wind %.1f m/s at %.0f m, heading %.0f°, z0 %.2f m\n",
+			s.Ambient.Speed, s.Ambient.RefHeight, s.Ambient.Heading, s.Ambient.Roughness)
+	}
+	return b.String()
+}
+
+// applyScenario replaces the scene with s
+func applyScenario(s Scenario, scene *core.Node, ml *ModelLoader) {
+	newScene(scene, ml)
+
+	setWindSources(scene, append([]WindSource(nil), s.Sources...))
+
+	mat := material.NewStandard(math32.NewColor("LightGray"))
+	for _, o := range s.Obstacles {
+		var geom *geometry.Geometry
+		if o.Cylinder {
+			geom = geometry.NewCylinder(float64(o.Size/2), float64(o.Height), 24, 1, true, true)
+		} else {
+			geom = geometry.NewBox(o.Size, o.Height, o.Size)
+		}
+		node := core.NewNode()
+		node.Add(graphic.NewMesh(geom, mat))
+		node.SetPosition(o.Position.X, o.Height/2, o.Position.Z)
+		objects.Add(node, "scenario obstacle")
+		scenarioObstacles = append(scenarioObstacles, node)
+	}
+
+	ambientWind = s.Ambient
+	if ambientWind.Enabled {
+		vectorField.seedAmbientWind()
+	}
+	log.Printf("Scenario %d generated:\n%s", s.Seed, s.Describe())
+}
+
+// clearScenarioObstacles removes the obstacles of a generated scene
+func clearScenarioObstacles() {
+	for _, node := range scenarioObstacles {
+		objects.Remove(node)
+	}
+	scenarioObstacles = nil
+}
+
+// showScenarioReport shows the generated scene
+func showScenarioReport(s Scenario) {
+	lines := strings.Split(strings.TrimSpace(s.Describe()), "\n")
+	dialog := gui.NewPanel(460, float32(55+20*len(lines)))
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	for i, line := range lines {
+		label := gui.NewLabel(line)
+		label.SetPosition(10, float32(10+20*i))
+		dialog.Add(label)
+	}
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(400, float32(20+20*len(lines)))
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+// showScenarioDialog edits the constraints and generates a scene
+func showScenarioDialog(scene *core.Node, ml *ModelLoader) {
+	dialog := gui.NewPanel(330, 370)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Surprise me")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	c := &scenarioConstraints
+	y := float32(35)
+	row := func(name string, input *NumericInput) {
+		label := gui.NewLabel(name)
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		input.SetPosition(170, y)
+		dialog.Add(input)
+		y += 30
+	}
+	seedInput := NewNumericInput(float32(c.Seed), 1, 99999, 1, "", func(value float32) {
+		c.Seed = int64(value)
+	})
+	row("Seed", seedInput)
+	row("Min sources", NewNumericInput(float32(c.MinSources), 1, 6, 1, "", func(value float32) {
+		c.MinSources = int(value)
+	}))
+	row("Max sources", NewNumericInput(float32(c.MaxSources), 1, 6, 1, "", func(value float32) {
+		c.MaxSources = int(value)
+	}))
+	row("Min speed", NewNumericInput(c.MinSpeed, 0.5, 30, 0.5, "m/s", func(value float32) {
+		c.MinSpeed = value
+	}))
+	row("Max speed", NewNumericInput(c.MaxSpeed, 0.5, 30, 0.5, "m/s", func(value float32) {
+		c.MaxSpeed = value
+	}))
+	row("Temperature spread", NewNumericInput(c.TemperatureSpread, 0, 30, 1, "°C", func(value float32) {
+		c.TemperatureSpread = value
+	}))
+	row("Min obstacles", NewNumericInput(float32(c.MinObstacles), 0, 8, 1, "", func(value float32) {
+		c.MinObstacles = int(value)
+	}))
+	row("Max obstacles", NewNumericInput(float32(c.MaxObstacles), 0, 8, 1, "", func(value float32) {
+		c.MaxObstacles = int(value)
+	}))
+	row("Max obstacle size", NewNumericInput(c.MaxObstacleSize, 1, 6, 0.5, "m", func(value float32) {
+		c.MaxObstacleSize = value
+	}))
+	row("Ambient wind chance", NewNumericInput(c.AmbientChance*100, 0, 100, 10, "%", func(value float32) {
+		c.AmbientChance = value / 100
+	}))
+
+	generateBtn := gui.NewButton("Generate")
+	generateBtn.SetPosition(10, 335)
+	generateBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		constraints := *c
+		if constraints.MaxSources < constraints.MinSources {
+			constraints.MaxSources = constraints.MinSources
+		}
+		if constraints.MaxObstacles < constraints.MinObstacles {
+			constraints.MaxObstacles = constraints.MinObstacles
+		}
+		if constraints.MaxSpeed < constraints.MinSpeed {
+			constraints.MaxSpeed = constraints.MinSpeed
+		}
+		overlays.CloseModal(dialog)
+		overlays.Confirm(fmt.Sprintf("Replace the scene with scenario %d?", constraints.Seed), func() {
+			s := GenerateScenario(constraints)
+			applyScenario(s, scene, ml)
+			c.Seed++ // the next student gets the next case
+			showScenarioReport(s)
+		})
+	})
+	dialog.Add(generateBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(270, 335)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeScenarioUI(scene *core.Node, ml *ModelLoader) {
+	surpriseBtn := gui.NewButton("Surprise me...")
+	surpriseBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showScenarioDialog(scene, ml)
+	})
+	addSidebarWidget(scene, surpriseBtn)
+}
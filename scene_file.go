@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+const defaultSceneFile = "scene.json"
+
+// SceneFile is the saved description of a scene: models, wind sources and annotations
+type SceneFile struct {
+	Version         int
+	ModelPath       string
+	ModelPosition   math32.Vector3
+	ModelRenderMode ModelRenderMode
+	ModelDecorative bool
+	ContextModels   []*ContextModel
+	WindSources     []WindSource
+	Annotations     []*Annotation
+}
+
+// sceneMigrations upgrade older scene files, indexed by the version they start from
+var sceneMigrations = []migration{
+	// v0 wind sources have no temperature, give them the ambient default instead of 0 °C
+	func(doc interface{}) (interface{}, error) {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("scene is not a JSON object")
+		}
+		sources, _ := obj["WindSources"].([]interface{})
+		for _, src := range sources {
+			if ws, ok := src.(map[string]interface{}); ok {
+				if _, ok := ws["Temperature"]; !ok {
+					ws["Temperature"] = defaultTemperature
+				}
+			}
+		}
+		return obj, nil
+	},
+}
+
+var sceneVersion = len(sceneMigrations)
+
+// currentSceneFile describes the scene as it is now
+func currentSceneFile(ml *ModelLoader) SceneFile {
+	syncContextModels()
+	sf := SceneFile{
+		Version:         sceneVersion,
+		ModelPath:       ml.path,
+		ModelDecorative: modelDecorative,
+		ContextModels:   contextModels,
+		WindSources:     windSources,
+		Annotations:     annotations,
+	}
+	if mesh != nil {
+		sf.ModelPosition = mesh.Position()
+		sf.ModelRenderMode = modelRenderMode(mesh)
+	}
+	return sf
+}
+
+func saveScene(path string, ml *ModelLoader) error {
+	sf := currentSceneFile(ml)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating scene file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(sf); err != nil {
+		return fmt.Errorf("writing scene file: %w", err)
+	}
+	log.Printf("Scene saved to %s", path)
+	return nil
+}
+
+func loadScene(path string, scene *core.Node, ml *ModelLoader) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading scene file: %w", err)
+	}
+	var sf SceneFile
+	if err := decodeVersioned(data, sceneMigrations, &sf); err != nil {
+		return fmt.Errorf("parsing scene file: %w", err)
+	}
+
+	setWindSources(scene, sf.WindSources)
+
+	clearAnnotations(scene)
+	for _, a := range sf.Annotations {
+		addAnnotation(scene, a)
+	}
+
+	// relative model paths are relative to the scene file, as in bundles
+	resolve := func(modelPath string) string {
+		if !filepath.IsAbs(modelPath) {
+			modelPath = filepath.Join(filepath.Dir(path), filepath.FromSlash(modelPath))
+		}
+		return modelPath
+	}
+	if sf.ModelPath != "" {
+		if err := ml.ReplaceModel(resolve(sf.ModelPath)); err != nil {
+			return fmt.Errorf("loading scene model: %w", err)
+		}
+		if mesh != nil {
+			mesh.SetPositionVec(&sf.ModelPosition)
+			setModelRenderMode(mesh, sf.ModelRenderMode)
+		}
+	}
+	modelDecorative = sf.ModelDecorative
+
+	clearContextModels()
+	for _, m := range sf.ContextModels {
+		m.Path = resolve(m.Path)
+		if err := addContextModel(scene, m); err != nil {
+			return fmt.Errorf("loading context model: %w", err)
+		}
+	}
+	log.Printf("Scene loaded from %s", path)
+	return nil
+}
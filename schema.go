@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// migration upgrades a decoded JSON document from one schema version to the next
+type migration func(doc interface{}) (interface{}, error)
+
+// decodeVersioned parses data, runs the migrations needed to bring it to the
+// current version (len(migrations)) and decodes the result into out.
+// Documents without a Version field are treated as version 0.
+func decodeVersioned(data []byte, migrations []migration, out interface{}) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	version := documentVersion(doc)
+	if version > len(migrations) {
+		return fmt.Errorf("file version %d is newer than supported version %d", version, len(migrations))
+	}
+	for ; version < len(migrations); version++ {
+		var err error
+		if doc, err = migrations[version](doc); err != nil {
+			return fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+	}
+	if obj, ok := doc.(map[string]interface{}); ok {
+		obj["Version"] = len(migrations)
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(migrated, out)
+}
+
+func documentVersion(doc interface{}) int {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	if v, ok := obj["Version"].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/gui/assets"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/text"
+)
+
+// ScreenshotOptions choose what is burned into a screenshot, so the picture still says
+// what it shows once it is pasted into a report
+type ScreenshotOptions struct {
+	ScaleBar   bool // a bar of a round length at the middle of the view
+	Legend     bool // the color scale of the field slice, when it is shown
+	Timestamp  bool // wall clock and simulated time
+	Parameters bool // model, wind sources and ambient wind
+}
+
+var screenshotOptions = ScreenshotOptions{ScaleBar: true, Legend: true, Timestamp: true, Parameters: true}
+
+const screenshotMargin = 12
+
+var screenshotFont *text.Font
+
+func burnInFont() *text.Font {
+	if screenshotFont == nil {
+		font, err := text.NewFontFromData(assets.MustAsset("fonts/FreeSans.ttf"))
+		if err != nil {
+			log.Println("Error loading screenshot font:", err)
+			return nil
+		}
+		font.SetPointSize(14)
+		font.SetColor(&math32.Color4{R: 1, G: 1, B: 1, A: 1})
+		font.SetBgColor(&math32.Color4{R: 0, G: 0, B: 0, A: 0})
+		screenshotFont = font
+	}
+	return screenshotFont
+}
+
+var burnInBackground = color.RGBA{A: 160}
+
+// drawTextBox draws lines of text on a translucent box with its top left at x, y and
+// returns the size of the box
+func drawTextBox(img *image.RGBA, lines []string, x, y int) (int, int) {
+	font := burnInFont()
+	if font == nil || len(lines) == 0 {
+		return 0, 0
+	}
+	joined := strings.Join(lines, "\n")
+	w, h := font.MeasureText(joined)
+	box := image.Rect(x, y, x+w+8, y+h+8)
+	draw.Draw(img, box, image.NewUniform(burnInBackground), image.Point{}, draw.Over)
+	font.DrawTextOnImage(joined, x+4, y+4, img)
+	return box.Dx(), box.Dy()
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// niceLength rounds a length down to 1, 2 or 5 times a power of ten
+func niceLength(length float32) float32 {
+	if length <= 0 {
+		return 0
+	}
+	power := float32(math.Pow(10, math.Floor(math.Log10(float64(length)))))
+	for _, step := range []float32{5, 2, 1} {
+		if step*power <= length {
+			return step * power
+		}
+	}
+	return power
+}
+
+// pixelsPerUnit returns how many pixels one scene unit across the view measures where
+// the middle of the view meets the ground, in an image width pixels wide
+func pixelsPerUnit(cam camera.ICamera, width int) float32 {
+	var proj, view, viewProj math32.Matrix4
+	cam.ProjMatrix(&proj)
+	cam.ViewMatrix(&view)
+	viewProj.MultiplyMatrices(&proj, &view)
+	// the first row of the view matrix is the camera's right in world space
+	right := math32.Vector3{X: view[0], Y: view[4], Z: view[8]}
+	windowW, windowH := app.App().GetSize()
+	center, _ := pickGroundPoint(cam, float32(windowW)/2, float32(windowH)/2)
+	if center == nil {
+		center = &math32.Vector3{}
+	}
+	a := center.Clone().ApplyProjection(&viewProj)
+	b := center.Clone().Add(&right).ApplyProjection(&viewProj)
+	return math32.Abs(b.X-a.X) / 2 * float32(width)
+}
+
+// drawScaleBar draws a bar of a round length in the bottom left corner
+func drawScaleBar(img *image.RGBA, cam camera.ICamera) {
+	b := img.Bounds()
+	perMeter := pixelsPerUnit(cam, b.Dx()) / simConfig.LengthUnit
+	if perMeter <= 0 {
+		return
+	}
+	meters := niceLength(float32(b.Dx()) / 5 / perMeter)
+	pixels := int(meters * perMeter)
+	if pixels < 10 {
+		return
+	}
+	label := fmt.Sprintf("%g m", meters)
+	if meters < 1 {
+		label = fmt.Sprintf("%g cm", meters*100)
+	}
+	x, y := screenshotMargin, b.Dy()-screenshotMargin-40
+	fillRect(img, image.Rect(x-4, y-4, x+pixels+8, y+40), burnInBackground)
+	fillRect(img, image.Rect(x, y+24, x+pixels, y+30), color.White)
+	fillRect(img, image.Rect(x, y+18, x+2, y+30), color.White)
+	fillRect(img, image.Rect(x+pixels-2, y+18, x+pixels, y+30), color.White)
+	if font := burnInFont(); font != nil {
+		font.DrawTextOnImage(label, x, y, img)
+	}
+}
+
+// drawLegend draws the color scale of the field slice in the bottom right corner
+func drawLegend(img *image.RGBA) {
+	if !fieldSlice.Enabled || sliceRangeText == "" {
+		return
+	}
+	b := img.Bounds()
+	const barW, barH = 200, 14
+	x, y := b.Dx()-screenshotMargin-barW, b.Dy()-screenshotMargin-barH-44
+	fillRect(img, image.Rect(x-6, y-6, x+barW+6, y+barH+44), burnInBackground)
+	for i := 0; i < barW; i++ {
+		c := heatColor(float32(i) / (barW - 1))
+		fillRect(img, image.Rect(x+i, y, x+i+1, y+barH), color.RGBA{R: uint8(c.R * 255), G: uint8(c.G * 255), B: uint8(c.B * 255), A: 255})
+	}
+	quantity := sliceQuantityNames[fieldSlice.Quantity]
+	if fieldSlice.Averaged {
+		quantity = "mean " + quantity
+	}
+	if font := burnInFont(); font != nil {
+		font.DrawTextOnImage(fmt.Sprintf("%s at %g m\n%s", quantity, fieldSlice.Height, sliceRangeText), x, y+barH+4, img)
+	}
+}
+
+// screenshotParameters lists the setup of the run
+func screenshotParameters(ml *ModelLoader) []string {
+	var lines []string
+	if ml.path != "" {
+		lines = append(lines, "Model "+filepath.Base(ml.path))
+	}
+	for i, w := range windSources {
+		lines = append(lines, fmt.Sprintf("Source %d: %.1f m/s, %.1f °C", i+1, w.Speed, w.Temperature))
+	}
+	if ambientWind.Enabled {
+		lines = append(lines, fmt.Sprintf("Ambient wind %.1f m/s at %g m, heading %.0f°",
+			ambientWind.Speed, ambientWind.RefHeight, ambientWind.Heading))
+	}
+	if mesh != nil {
+		c := lastCoefficients
+		lines = append(lines, fmt.Sprintf("Cd %.3f  Cl %.3f  Cm %.3f", c.Cd, c.Cl, c.Cm))
+	}
+	return lines
+}
+
+// annotateScreenshot burns the chosen annotations into img
+func annotateScreenshot(img *image.RGBA, cam camera.ICamera, ml *ModelLoader, opts ScreenshotOptions) {
+	y := screenshotMargin
+	if opts.Timestamp {
+		_, h := drawTextBox(img, []string{
+			time.Now().Format("2006-01-02 15:04:05"),
+			"Simulated " + formatDuration(historyClock),
+		}, screenshotMargin, y)
+		y += h + 6
+	}
+	if opts.Parameters {
+		drawTextBox(img, screenshotParameters(ml), screenshotMargin, y)
+	}
+	if opts.ScaleBar {
+		drawScaleBar(img, cam)
+	}
+	if opts.Legend {
+		drawLegend(img)
+	}
+}
+
+func saveScreenshot(path string, img *image.RGBA) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+// takeScreenshot saves the next frame with the chosen annotations
+func takeScreenshot(cam camera.ICamera, ml *ModelLoader) {
+	opts := screenshotOptions
+	requestFrameCapture(func(img *image.RGBA) {
+		annotateScreenshot(img, cam, ml, opts)
+		path := fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
+		if err := saveScreenshot(path, img); err != nil {
+			log.Println("Error saving screenshot:", err)
+			overlays.Notify("Could not save screenshot: " + err.Error())
+			return
+		}
+		log.Printf("Screenshot saved to %s", path)
+		overlays.Notify("Screenshot saved to " + path)
+	})
+}
+
+// showScreenshotDialog chooses the annotations and takes the screenshot
+func showScreenshotDialog(cam camera.ICamera, ml *ModelLoader) {
+	dialog := gui.NewPanel(260, 175)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Screenshot")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	y := float32(35)
+	check := func(name string, value *bool) {
+		box := gui.NewCheckBox(name)
+		box.SetPosition(10, y)
+		box.SetValue(*value)
+		box.Subscribe(gui.OnChange, func(string, interface{}) {
+			*value = box.Value()
+		})
+		dialog.Add(box)
+		y += 25
+	}
+	check("Scale bar", &screenshotOptions.ScaleBar)
+	check("Color legend", &screenshotOptions.Legend)
+	check("Timestamp", &screenshotOptions.Timestamp)
+	check("Parameters", &screenshotOptions.Parameters)
+
+	saveBtn := gui.NewButton("Save")
+	saveBtn.SetPosition(10, 140)
+	saveBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		// close first, the dialog shouldn't be in the picture
+		overlays.CloseModal(dialog)
+		takeScreenshot(cam, ml)
+	})
+	dialog.Add(saveBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(200, 140)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeScreenshotUI(scene *core.Node, cam camera.ICamera, ml *ModelLoader) {
+	screenshotBtn := gui.NewButton("Screenshot...")
+	screenshotBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showScreenshotDialog(cam, ml)
+	})
+	addSidebarWidget(scene, screenshotBtn)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/demos/hellog3n/voxel"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// A model's signed distance field holds, on a grid at half the flow field's cell size
+// in the model's own frame, the distance to its surface, negative inside. It is computed
+// once from the model's BVH, and after that the particles and the flow field read the
+// model from it: a particle is inside where the distance is negative and is pushed out
+// along its gradient, and a field cell is solid where the surface is within half a cell
+// diagonal of its center. Moving the model then costs no pass over its triangles.
+
+const (
+	sdfMargin   = 2  // nodes around the model's bounds
+	sdfMaxNodes = 64 // along the longest side, the spacing grows for big models
+)
+
+// SDF is a signed distance field sampled at the nodes of a grid
+type SDF struct {
+	origin math32.Vector3 // first node, in the model's frame
+	cell   float32
+	dims   [3]int
+	dist   []float32
+
+	bvh    *BVH    // the triangles it was computed from
+	wanted float32 // spacing asked for, cell may be coarser
+}
+
+// buildSDF samples the signed distance to the triangles of bvh with the given spacing
+func buildSDF(bvh *BVH, cell float32) *SDF {
+	box := bvh.nodes[0].box
+	size := boxSize(&box)
+	longest := math32.Max(size.X, math32.Max(size.Y, size.Z))
+	s := &SDF{cell: cell, bvh: bvh, wanted: cell}
+	if longest/cell > sdfMaxNodes-2*sdfMargin {
+		s.cell = longest / (sdfMaxNodes - 2*sdfMargin)
+	}
+	s.origin = *box.Min.Clone().SubScalar(sdfMargin * s.cell)
+	for a := 0; a < 3; a++ {
+		s.dims[a] = int(math32.Ceil(size.Component(a)/s.cell)) + 2*sdfMargin + 1
+	}
+	s.dist = make([]float32, s.dims[0]*s.dims[1]*s.dims[2])
+	parallelRange(s.dims[0], func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			for j := 0; j < s.dims[1]; j++ {
+				for k := 0; k < s.dims[2]; k++ {
+					p := s.node(i, j, k)
+					d := bvh.Distance(p)
+					if bvh.Contains(p) {
+						d = -d
+					}
+					s.dist[s.index(i, j, k)] = d
+				}
+			}
+		}
+	})
+	return s
+}
+
+func (s *SDF) index(i, j, k int) int {
+	return (i*s.dims[1]+j)*s.dims[2] + k
+}
+
+func (s *SDF) node(i, j, k int) math32.Vector3 {
+	return math32.Vector3{
+		X: s.origin.X + float32(i)*s.cell,
+		Y: s.origin.Y + float32(j)*s.cell,
+		Z: s.origin.Z + float32(k)*s.cell,
+	}
+}
+
+// Distance returns the signed distance at p in the model's frame, interpolated between
+// the nodes. Outside the grid the distance to the grid is added to that at its edge.
+func (s *SDF) Distance(p math32.Vector3) float32 {
+	var g [3]float32
+	var outside float32
+	for a := 0; a < 3; a++ {
+		x := (p.Component(a) - s.origin.Component(a)) / s.cell
+		top := float32(s.dims[a] - 1)
+		if x < 0 {
+			outside += x * x
+			x = 0
+		} else if x > top {
+			outside += (x - top) * (x - top)
+			x = top
+		}
+		g[a] = x
+	}
+	var base [3]int
+	var t [3]float32
+	for a := 0; a < 3; a++ {
+		base[a] = int(g[a])
+		if base[a] >= s.dims[a]-1 {
+			base[a] = s.dims[a] - 2
+		}
+		t[a] = g[a] - float32(base[a])
+	}
+	var d float32
+	for corner := 0; corner < 8; corner++ {
+		c := base
+		w := float32(1)
+		for a := 0; a < 3; a++ {
+			if corner>>a&1 == 1 {
+				c[a]++
+				w *= t[a]
+			} else {
+				w *= 1 - t[a]
+			}
+		}
+		d += w * s.dist[s.index(c[0], c[1], c[2])]
+	}
+	return d + math32.Sqrt(outside)*s.cell
+}
+
+// Gradient returns the direction the distance grows fastest at p, the outward normal
+// near the surface
+func (s *SDF) Gradient(p math32.Vector3) math32.Vector3 {
+	var g math32.Vector3
+	for a := 0; a < 3; a++ {
+		ahead, behind := p, p
+		ahead.SetComponent(a, p.Component(a)+s.cell/2)
+		behind.SetComponent(a, p.Component(a)-s.cell/2)
+		g.SetComponent(a, s.Distance(ahead)-s.Distance(behind))
+	}
+	if g.LengthSq() == 0 {
+		return math32.Vector3{Y: 1}
+	}
+	return *g.Normalize()
+}
+
+// Distance returns the distance from p to the nearest triangle
+func (b *BVH) Distance(p math32.Vector3) float32 {
+	best := float32(math32.Infinity)
+	stack := []int32{0}
+	for len(stack) > 0 {
+		n := &b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if n.box.DistanceToPoint(&p) >= best {
+			continue
+		}
+		if n.count > 0 {
+			for _, tri := range b.tris[n.start : n.start+n.count] {
+				closest := closestOnTriangle(p, tri)
+				best = math32.Min(best, closest.DistanceTo(&p))
+			}
+			continue
+		}
+		stack = append(stack, n.child, n.child+1)
+	}
+	return best
+}
+
+// closestOnTriangle returns the point of tri nearest to p, by the regions of Ericson's
+// Real-Time Collision Detection, 5.1.5
+func closestOnTriangle(p math32.Vector3, tri [3]math32.Vector3) math32.Vector3 {
+	a, b, c := tri[0], tri[1], tri[2]
+	ab := b.Clone().Sub(&a)
+	ac := c.Clone().Sub(&a)
+	ap := p.Clone().Sub(&a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a
+	}
+	bp := p.Clone().Sub(&b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return *a.Clone().Add(ab.MultiplyScalar(d1 / (d1 - d3)))
+	}
+	cp := p.Clone().Sub(&c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return *a.Clone().Add(ac.MultiplyScalar(d2 / (d2 - d6)))
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		bc := c.Clone().Sub(&b)
+		return *b.Clone().Add(bc.MultiplyScalar((d4 - d3) / ((d4 - d3) + (d5 - d6))))
+	}
+	denom := 1 / (va + vb + vc)
+	return *a.Clone().Add(ab.MultiplyScalar(vb * denom)).Add(ac.MultiplyScalar(vc * denom))
+}
+
+// modelSDFs are the distance fields of the models, keyed like modelBVHs
+var modelSDFs = map[*core.Node]*SDF{}
+
+// modelSDF returns the distance field of model, computing it when the model's triangles
+// or the field's cell size changed; nil for models without triangles
+func modelSDF(model *core.Node) *SDF {
+	bvh := modelBVH(model)
+	if bvh == nil {
+		return nil
+	}
+	// the grid is in the model's frame, its spacing follows the field's in world units
+	model.UpdateMatrixWorld()
+	world := model.MatrixWorld()
+	scale := world.GetMaxScaleOnAxis()
+	if scale <= 0 {
+		return nil
+	}
+	cell := vectorField.CellSize() / 2 / scale
+	if s, ok := modelSDFs[model]; ok && s.bvh == bvh && s.wanted >= cell*0.99 && s.wanted <= cell*1.01 {
+		return s
+	}
+	s := buildSDF(bvh, cell)
+	modelSDFs[model] = s
+	log.Printf("SDF: %dx%dx%d nodes of %.3g", s.dims[0], s.dims[1], s.dims[2], s.cell)
+	return s
+}
+
+// sdfContact returns how deep the world space pos lies inside model and the outward
+// normal there, both in world space. ok is false when the model has no distance field.
+func sdfContact(model *core.Node, pos math32.Vector3) (depth float32, normal math32.Vector3, ok bool) {
+	s := modelSDF(model)
+	if s == nil {
+		return 0, normal, false
+	}
+	world := model.MatrixWorld()
+	var inverse math32.Matrix4
+	if err := inverse.GetInverse(&world); err != nil {
+		return 0, normal, false
+	}
+	local := *pos.Clone().ApplyMatrix4(&inverse)
+	depth = -s.Distance(local) * world.GetMaxScaleOnAxis()
+	g := s.Gradient(local)
+	tip := local.Clone().Add(&g).ApplyMatrix4(&world)
+	normal = *tip.Sub(local.ApplyMatrix4(&world)).Normalize()
+	return depth, normal, true
+}
+
+// fillFromSDF occupies the cells of grid whose centers lie within half a cell diagonal
+// of model's surface or inside it, as the voxelizer's Fill
+func fillFromSDF(model *core.Node, grid *voxel.Grid) bool {
+	s := modelSDF(model)
+	if s == nil {
+		return false
+	}
+	world := model.MatrixWorld()
+	var inverse math32.Matrix4
+	if err := inverse.GetInverse(&world); err != nil {
+		return false
+	}
+	scale := world.GetMaxScaleOnAxis()
+	reach := grid.CellSize * 0.87 / scale // half the cell diagonal, in the model's frame
+
+	// only the cells over the model's bounds can be reached
+	bounds := s.bvh.nodes[0].box
+	bounds.ApplyMatrix4(&world)
+	lo, _ := grid.CellAt(bounds.Min)
+	hi, _ := grid.CellAt(bounds.Max)
+	for a := 0; a < 3; a++ {
+		lo[a] = clampInt(lo[a]-1, 0, grid.Dims[a]-1)
+		hi[a] = clampInt(hi[a]+1, 0, grid.Dims[a]-1)
+	}
+	for i := lo[0]; i <= hi[0]; i++ {
+		for j := lo[1]; j <= hi[1]; j++ {
+			for k := lo[2]; k <= hi[2]; k++ {
+				center := math32.Vector3{
+					X: grid.Origin.X + (float32(i)+0.5)*grid.CellSize,
+					Y: grid.Origin.Y + (float32(j)+0.5)*grid.CellSize,
+					Z: grid.Origin.Z + (float32(k)+0.5)*grid.CellSize,
+				}
+				if s.Distance(*center.ApplyMatrix4(&inverse)) < reach {
+					grid.Cells[grid.Index([3]int{i, j, k})] = true
+				}
+			}
+		}
+	}
+	return true
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// SensitivityStudy perturbs the selected parameters one at a time, down and up by a
+// fraction of their range around the current setup, and records how much the metrics
+// move. Every configuration is measured by an evaluation like the optimizer's.
+type SensitivityStudy struct {
+	Selected     []bool  // parallel to optimizerParams
+	Perturbation float32 // fraction of each parameter's range
+	SettleTime   float32 // simulated seconds per evaluation
+
+	running  bool
+	baseline []float32 // parameter values of the current setup
+	base     [2]float32
+	queue    []sensitivityRun // runs still to do, the current one first
+	results  []SensitivityResult
+	run      evaluation
+}
+
+type sensitivityRun struct {
+	param int
+	value float32
+	high  bool
+}
+
+// SensitivityResult is the change of the metrics when a parameter moves down and up
+type SensitivityResult struct {
+	Param     int
+	Low, High float32    // the parameter values tried
+	DeltaLow  [2]float32 // metric change at Low, per OptimizerMetric
+	DeltaHigh [2]float32
+}
+
+// swing is the total range of the metric over the two perturbations
+func (r SensitivityResult) swing(m OptimizerMetric) float32 {
+	return math32.Abs(r.DeltaHigh[m] - r.DeltaLow[m])
+}
+
+var sensitivity = SensitivityStudy{
+	Selected:     make([]bool, len(optimizerParams)),
+	Perturbation: 0.1,
+	SettleTime:   4,
+}
+
+// sensitivityStatus shows the progress while the sensitivity dialog is open
+var sensitivityStatus *gui.Label
+
+// Running reports whether a study is in progress
+func (s *SensitivityStudy) Running() bool {
+	return s.running
+}
+
+// Start queues the perturbations of the selected parameters and measures the baseline
+func (s *SensitivityStudy) Start() error {
+	if s.running || optimizer.Running() {
+		return fmt.Errorf("a study is already running")
+	}
+	s.baseline = make([]float32, len(optimizerParams))
+	s.queue = nil
+	s.results = nil
+	for i, p := range optimizerParams {
+		s.baseline[i] = p.get()
+		if !s.Selected[i] {
+			continue
+		}
+		delta := s.Perturbation * (p.Max - p.Min)
+		low := math32.Max(s.baseline[i]-delta, p.Min)
+		high := math32.Min(s.baseline[i]+delta, p.Max)
+		s.queue = append(s.queue, sensitivityRun{i, low, false}, sensitivityRun{i, high, true})
+		s.results = append(s.results, SensitivityResult{Param: i, Low: low, High: high})
+	}
+	if len(s.queue) == 0 {
+		return fmt.Errorf("no parameter selected")
+	}
+	// the baseline goes first
+	s.queue = append([]sensitivityRun{{param: -1}}, s.queue...)
+	s.running = true
+	s.begin()
+	log.Printf("Sensitivity study started, %d runs", len(s.queue))
+	return nil
+}
+
+// Stop abandons the study and restores the setup
+func (s *SensitivityStudy) Stop() {
+	if !s.running {
+		return
+	}
+	s.running = false
+	s.restore()
+	s.refreshStatus("Sensitivity study stopped")
+}
+
+func (s *SensitivityStudy) restore() {
+	applyParams(s.Selected, s.baseline)
+}
+
+// begin sets up the run at the head of the queue
+func (s *SensitivityStudy) begin() {
+	r := s.queue[0]
+	s.restore()
+	text := "Run 1: baseline"
+	if r.param >= 0 {
+		optimizerParams[r.param].set(r.value)
+		text = fmt.Sprintf("Run %d: %s %.2f", len(s.results)*2-len(s.queue)+2, optimizerParams[r.param].Name, r.value)
+	}
+	s.run.start(s.SettleTime)
+	s.refreshStatus(text)
+}
+
+// Update advances the run in progress by a simulated dt, called once per frame
+func (s *SensitivityStudy) Update(dt float32) {
+	if !s.running || !s.run.advance(dt) {
+		return
+	}
+	r := s.queue[0]
+	var scores [2]float32
+	for m := range scores {
+		scores[m] = s.run.score(OptimizerMetric(m))
+	}
+	if r.param < 0 {
+		s.base = scores
+	} else {
+		for n := range s.results {
+			if s.results[n].Param != r.param {
+				continue
+			}
+			for m := range scores {
+				if r.high {
+					s.results[n].DeltaHigh[m] = scores[m] - s.base[m]
+				} else {
+					s.results[n].DeltaLow[m] = scores[m] - s.base[m]
+				}
+			}
+		}
+	}
+	s.queue = s.queue[1:]
+	if len(s.queue) > 0 {
+		s.begin()
+		return
+	}
+	s.running = false
+	s.restore()
+	for _, res := range s.results {
+		log.Printf("Sensitivity of %s (%.2f .. %.2f): drag %+.3f / %+.3f N, plane flow %+.3f / %+.3f m³/s",
+			optimizerParams[res.Param].Name, res.Low, res.High,
+			res.DeltaLow[MetricDrag], res.DeltaHigh[MetricDrag], res.DeltaLow[MetricPlaneFlow], res.DeltaHigh[MetricPlaneFlow])
+	}
+	overlays.Notify("Sensitivity study done")
+	s.refreshStatus("Done")
+	showTornadoChart(MetricDrag)
+}
+
+func (s *SensitivityStudy) refreshStatus(text string) {
+	if sensitivityStatus != nil {
+		sensitivityStatus.SetText(text)
+	}
+}
+
+// Tornado chart layout
+const (
+	tornadoWidth    = 420
+	tornadoNameW    = 130 // the parameter names left of the bars
+	tornadoBarH     = 16
+	tornadoRowH     = 24
+	tornadoBarSpace = tornadoWidth - tornadoNameW - 20
+)
+
+// showTornadoChart draws the results as a tornado chart: one row per parameter with the
+// largest swing on top, the metric change when it goes down in blue and up in red,
+// either side of the baseline in the middle
+func showTornadoChart(metric OptimizerMetric) {
+	results := append([]SensitivityResult(nil), sensitivity.results...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].swing(metric) > results[j].swing(metric)
+	})
+	height := float32(100 + tornadoRowH*len(results))
+	dialog := gui.NewPanel(tornadoWidth, height)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel(fmt.Sprintf("Sensitivity of %s, baseline %.3f", optimizerMetricNames[metric], sensitivity.base[metric]))
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	var scale float32
+	for _, r := range results {
+		scale = math32.Max(scale, math32.Max(math32.Abs(r.DeltaLow[metric]), math32.Abs(r.DeltaHigh[metric])))
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	axis := float32(tornadoNameW + tornadoBarSpace/2)
+	half := float32(tornadoBarSpace / 2)
+	bar := func(y, delta float32, color math32.Color4) {
+		w := math32.Abs(delta) / scale * half
+		if w < 1 {
+			return
+		}
+		b := gui.NewPanel(w, tornadoBarH)
+		b.SetColor4(&color)
+		x := axis
+		if delta < 0 {
+			x -= w
+		}
+		b.SetPosition(x, y)
+		dialog.Add(b)
+	}
+	axisLine := gui.NewPanel(1, float32(tornadoRowH*len(results)))
+	axisLine.SetColor4(&math32.Color4{R: 0.8, G: 0.8, B: 0.8, A: 1})
+	axisLine.SetPosition(axis, 35)
+	dialog.Add(axisLine)
+
+	y := float32(38)
+	for _, r := range results {
+		name := gui.NewLabel(optimizerParams[r.Param].Name)
+		name.SetPosition(10, y)
+		dialog.Add(name)
+		bar(y, r.DeltaLow[metric], math32.Color4{R: 0.3, G: 0.5, B: 1, A: 0.9})
+		bar(y, r.DeltaHigh[metric], math32.Color4{R: 1, G: 0.35, B: 0.3, A: 0.9})
+		y += tornadoRowH
+	}
+
+	legend := gui.NewLabel(fmt.Sprintf("blue: parameter down, red: up; bars span ±%.3f", scale))
+	legend.SetPosition(10, y+5)
+	dialog.Add(legend)
+
+	metricBtn := gui.NewButton(optimizerMetricNames[(metric+1)%2])
+	metricBtn.SetPosition(10, height-32)
+	metricBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		showTornadoChart((metric + 1) % 2)
+	})
+	dialog.Add(metricBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(tornadoWidth-60, height-32)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+// showSensitivityDialog picks the parameters and runs the study
+func showSensitivityDialog(scene *core.Node) {
+	dialog := gui.NewPanel(360, 270)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Sensitivity study")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	y := float32(35)
+	for i, p := range optimizerParams {
+		i := i
+		check := gui.NewCheckBox(p.Name)
+		check.SetPosition(10, y)
+		check.SetValue(sensitivity.Selected[i])
+		check.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+			sensitivity.Selected[i] = check.Value()
+		})
+		dialog.Add(check)
+		y += 22
+	}
+	y += 5
+
+	perturbLabel := gui.NewLabel("Perturbation")
+	perturbLabel.SetPosition(10, y+3)
+	dialog.Add(perturbLabel)
+	perturbInput := NewNumericInput(sensitivity.Perturbation*100, 1, 50, 1, "% of range", func(value float32) {
+		sensitivity.Perturbation = value / 100
+	})
+	perturbInput.SetPosition(120, y)
+	dialog.Add(perturbInput)
+	y += 27
+
+	settleLabel := gui.NewLabel("Time per run")
+	settleLabel.SetPosition(10, y+3)
+	dialog.Add(settleLabel)
+	settleInput := NewNumericInput(sensitivity.SettleTime, 0.5, 60, 0.5, "s", func(value float32) {
+		sensitivity.SettleTime = value
+	})
+	settleInput.SetPosition(120, y)
+	dialog.Add(settleInput)
+	y += 30
+
+	sensitivityStatus = gui.NewLabel("")
+	sensitivityStatus.SetPosition(10, y)
+	dialog.Add(sensitivityStatus)
+
+	startBtn := gui.NewButton("Start")
+	startBtn.SetPosition(10, 235)
+	startBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := sensitivity.Start(); err != nil {
+			overlays.Notify("Could not start the study: " + err.Error())
+		}
+	})
+	dialog.Add(startBtn)
+
+	stopBtn := gui.NewButton("Stop")
+	stopBtn.SetPosition(70, 235)
+	stopBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		sensitivity.Stop()
+	})
+	dialog.Add(stopBtn)
+
+	chartBtn := gui.NewButton("Chart")
+	chartBtn.SetPosition(125, 235)
+	chartBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if sensitivity.Running() || len(sensitivity.results) == 0 {
+			overlays.Notify("No finished sensitivity study")
+			return
+		}
+		showTornadoChart(MetricDrag)
+	})
+	dialog.Add(chartBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(300, 235)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		sensitivityStatus = nil
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeSensitivityUI(scene *core.Node) {
+	sensitivityBtn := gui.NewButton("Sensitivity...")
+	sensitivityBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showSensitivityDialog(scene)
+	})
+	addSidebarWidget(scene, sensitivityBtn)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+)
+
+// clearScene stops whatever is running and removes the wind sources, the model, the
+// particles and the labels, leaving an empty ground plane to start over from
+func clearScene(scene *core.Node, ml *ModelLoader) {
+	optimizer.Stop()
+	sensitivity.Stop()
+	validation.Stop()
+	removeWindSourceMarkers(scene)
+	windSources = nil
+	for _, input := range windSpeedInputs {
+		removeControlWidget(scene, input)
+	}
+	windSpeedInputs = nil
+	emissionCounts = nil
+
+	// only this scene's particles, those of the other tabs stay
+	for _, m := range windParticles.Mesh {
+		releaseWindView(m)
+	}
+	windParticles.Clear()
+	removeFluidParticles()
+	objects.RemoveKind("scrub particle")
+	scrubMeshes = nil
+	history.Clear()
+
+	if mesh != nil {
+		objects.Remove(mesh)
+		forgetModel(mesh)
+		mesh = nil
+	}
+	ml.models = nil
+	ml.path = ""
+	modelDecorative = false
+	rigidBody.Reset(nil)
+	clearContextModels()
+	clearScenarioObstacles()
+
+	clearAnnotations(scene)
+	quiz.clearMarkers()
+	log.Println("Scene cleared")
+}
+
+// newScene clears the scene and also throws away the flow field, the recording, the
+// markers and the playback history, so nothing from the previous setup carries over
+func newScene(scene *core.Node, ml *ModelLoader) {
+	clearScene(scene, ml)
+	resetVectorField()
+	fieldAverage.Reset()
+
+	simulationData = nil
+	eventMarkers = nil
+	historyClock = 0
+	lastHistorySample = -1
+	recordingStopped = false
+	convergence.Reset()
+	log.Println("New scene started")
+}
+
+func initializeSessionUI(scene *core.Node, ml *ModelLoader) {
+	newBtn := gui.NewButton("New Scene")
+	newBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.Confirm("Discard the scene and the recording?", func() {
+			newScene(scene, ml)
+			overlays.Notify("New scene")
+		})
+	})
+	addSidebarWidget(scene, newBtn)
+
+	clearBtn := gui.NewButton("Stop & Clear")
+	clearBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.Confirm("Stop the simulation and clear the scene?", func() {
+			clearScene(scene, ml)
+			overlays.Notify("Scene cleared")
+		})
+	})
+	addSidebarWidget(scene, clearBtn)
+
+	exitBtn := gui.NewButton("Exit")
+	exitBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.Confirm("Exit the program?", func() {
+			// the recording is saved once the application loop returns
+			app.App().Exit()
+		})
+	})
+	addSidebarWidget(scene, exitBtn)
+}
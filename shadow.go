@@ -0,0 +1,183 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/light"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// g3n has no shadow maps, so shadows are planar projections of the casters onto the ground
+// along the sun direction, drawn as translucent dark geometry just above the floor.
+const shadowPlaneY = 0.01
+const shadowOpacity = 0.35
+
+// clusterShadowCell is the ground cell size used to find dense particle clusters
+const clusterShadowCell = 1.0
+
+// clusterShadowMinCount is the particle count above which a cell casts a shadow
+const clusterShadowMinCount = 4
+
+var sunLight *light.Directional
+var modelShadow *graphic.Mesh
+var modelShadowMatrix math32.Matrix4
+var particleShadow *graphic.Mesh
+
+// particleShadowPositions and particleShadowNormals are the buffers of particleShadow,
+// refilled every frame instead of building a new mesh
+var particleShadowPositions, particleShadowNormals math32.ArrayF32
+
+func initializeSunLight(scene *core.Node) {
+	sunLight = light.NewDirectional(&math32.Color{R: 1, G: 1, B: 1}, 0.6)
+	sunLight.SetPosition(4, 10, 3)
+	scene.Add(sunLight)
+}
+
+// sunDirection returns the normalized direction the sun light travels in
+func sunDirection() math32.Vector3 {
+	pos := sunLight.Position()
+	dir := pos.Negate().Normalize()
+	return *dir
+}
+
+// projectToGround moves p along dir until it reaches the shadow plane
+func projectToGround(p math32.Vector3, dir math32.Vector3) math32.Vector3 {
+	t := (p.Y - shadowPlaneY) / -dir.Y
+	if t < 0 {
+		t = 0
+	}
+	return math32.Vector3{X: p.X + dir.X*t, Y: shadowPlaneY, Z: p.Z + dir.Z*t}
+}
+
+func newShadowMesh(positions math32.ArrayF32) *graphic.Mesh {
+	normals := math32.NewArrayF32(0, len(positions))
+	for i := 0; i < len(positions); i += 3 {
+		normals.Append(0, 1, 0)
+	}
+	geom := geometry.NewGeometry()
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+
+	mat := material.NewStandard(math32.NewColor("Black"))
+	mat.SetOpacity(shadowOpacity)
+	mat.SetTransparent(true)
+	mat.SetDepthMask(false)
+	mat.SetSide(material.SideDouble)
+	return graphic.NewMesh(geom, mat)
+}
+
+func removeShadowMesh(scene *core.Node, m *graphic.Mesh) {
+	if m != nil {
+		objects.Remove(m)
+	}
+}
+
+// updateShadows refreshes the projected shadows of the model and of dense particle clusters
+func updateShadows(scene *core.Node) {
+	if sunLight == nil {
+		return
+	}
+	dir := sunDirection()
+	updateModelShadow(scene, dir)
+	updateParticleShadow(scene, dir)
+}
+
+func updateModelShadow(scene *core.Node, dir math32.Vector3) {
+	if !renderSettings.Shadows || mesh == nil || modelRenderMode(mesh) == RenderHidden {
+		removeShadowMesh(scene, modelShadow)
+		modelShadow = nil
+		return
+	}
+
+	// Only rebuild the silhouette when the model has moved
+	mesh.UpdateMatrixWorld()
+	world := mesh.MatrixWorld()
+	if modelShadow != nil && world == modelShadowMatrix {
+		return
+	}
+	modelShadowMatrix = world
+
+	positions := math32.NewArrayF32(0, 0)
+	forEachWorldTriangle(mesh, func(a, b, c math32.Vector3) {
+		pa := projectToGround(a, dir)
+		pb := projectToGround(b, dir)
+		pc := projectToGround(c, dir)
+		positions.AppendVector3(&pa, &pb, &pc)
+	})
+
+	removeShadowMesh(scene, modelShadow)
+	modelShadow = nil
+	if len(positions) == 0 {
+		return
+	}
+	modelShadow = newShadowMesh(positions)
+	objects.Add(modelShadow, "shadow")
+}
+
+func updateParticleShadow(scene *core.Node, dir math32.Vector3) {
+	if !renderSettings.Shadows || !renderSettings.ParticleShadows {
+		hideParticleShadow()
+		return
+	}
+
+	type cluster struct {
+		sum   math32.Vector3
+		count int
+	}
+	clusters := make(map[[2]int]*cluster)
+	for _, p := range fluidParticles {
+		key := [2]int{int(math32.Floor(p.X / clusterShadowCell)), int(math32.Floor(p.Z / clusterShadowCell))}
+		c, ok := clusters[key]
+		if !ok {
+			c = &cluster{}
+			clusters[key] = c
+		}
+		c.sum.Add(&math32.Vector3{X: p.X, Y: p.Y, Z: p.Z})
+		c.count++
+	}
+
+	positions := particleShadowPositions[:0]
+	const half = clusterShadowCell * 0.4
+	for _, c := range clusters {
+		if c.count < clusterShadowMinCount {
+			continue
+		}
+		center := projectToGround(*c.sum.DivideScalar(float32(c.count)), dir)
+		v0 := math32.Vector3{X: center.X - half, Y: shadowPlaneY, Z: center.Z - half}
+		v1 := math32.Vector3{X: center.X + half, Y: shadowPlaneY, Z: center.Z - half}
+		v2 := math32.Vector3{X: center.X + half, Y: shadowPlaneY, Z: center.Z + half}
+		v3 := math32.Vector3{X: center.X - half, Y: shadowPlaneY, Z: center.Z + half}
+		positions.AppendVector3(&v0, &v2, &v1, &v0, &v3, &v2)
+	}
+	particleShadowPositions = positions
+	if len(positions) == 0 {
+		hideParticleShadow()
+		return
+	}
+
+	// every vertex faces up, the normals only grow with the positions
+	for len(particleShadowNormals) < len(positions) {
+		particleShadowNormals.Append(0, 1, 0)
+	}
+	normals := particleShadowNormals[:len(positions)]
+	if particleShadow == nil {
+		particleShadow = newShadowMesh(positions)
+		// the clusters move every frame, the bounds the renderer culls by would be stale
+		particleShadow.SetCullable(false)
+		objects.Add(particleShadow, "shadow")
+	}
+	geom := particleShadow.GetGeometry()
+	geom.VBO(gls.VertexPosition).SetBuffer(positions)
+	geom.VBO(gls.VertexNormal).SetBuffer(normals)
+	particleShadow.SetVisible(true)
+}
+
+// hideParticleShadow hides the cluster shadows, keeping their mesh for when they return
+func hideParticleShadow() {
+	if particleShadow != nil {
+		particleShadow.SetVisible(false)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// The simulation advances in fixed steps of 1/StepRate simulated seconds, however long
+// the frames are, so a run comes out the same on any display. Frame time is collected
+// in stepAccumulator and whole steps are taken from it; the particle meshes are drawn
+// between the last two steps by the fraction left over. The flow field is the expensive
+// part and is stepped every few steps only, with the time gathered since its last step.
+
+const (
+	windSpawnInterval = 0.1 // simulated seconds between particles from a wind source
+	maxStepWork       = 100 * time.Millisecond
+	maxInterpolation  = 2.0 // m, longer moves are wraps and mirrors and jump instead
+)
+
+var (
+	stepAccumulator float32 // simulated time not stepped yet
+	stepCount       int     // steps taken since the start, for the field cadence
+	fieldBacklog    float32 // simulated time since the flow field was last stepped
+	spawnClock      float32 // simulated time since the wind sources last spawned
+)
+
+// stepLength returns the length of one fixed step in seconds
+func stepLength() float32 {
+	return 1 / math32.Max(simConfig.StepRate, 1)
+}
+
+// fieldEvery returns how many fixed steps pass per flow field step
+func fieldEvery() int {
+	rate := math32.Min(math32.Max(simConfig.FieldRate, 1), simConfig.StepRate)
+	return int(math32.Max(math32.Round(simConfig.StepRate/rate), 1))
+}
+
+// advanceSimulation takes as many fixed steps as fit in the frame time. When the steps
+// take longer than maxStepWork the rest of the backlog is dropped, the simulation then
+// runs slower than real time instead of falling further behind every frame.
+func advanceSimulation(frameDt float32, scene *core.Node) {
+	dt := stepLength()
+	stepAccumulator += frameDt
+	start := time.Now()
+	steps := 0
+	for stepAccumulator >= dt {
+		stepSimulation(dt, scene)
+		stepAccumulator -= dt
+		steps++
+		if time.Since(start) > maxStepWork {
+			if stepAccumulator >= dt {
+				log.Printf("Simulation behind, dropped %.3f s after %d steps", stepAccumulator, steps)
+				stepAccumulator = math32.Mod(stepAccumulator, dt)
+			}
+			break
+		}
+	}
+	if steps > 0 {
+		drawParticles()
+	}
+}
+
+// stepSimulation advances everything that moves by one fixed step dt
+func stepSimulation(dt float32, scene *core.Node) {
+	// Continuous particle generation from wind sources
+	if windEnabled {
+		spawnClock += dt
+		if spawnClock >= spawnInterval() {
+			for i, wind := range windSources {
+				windParticles.Add(createWindParticle(i))
+				log.Printf("Spawning particle from wind source at: %v, Direction: %v", wind.Position, wind.Direction)
+			}
+			spawnClock -= spawnInterval()
+		}
+	}
+
+	applyBindings(historyClock)
+	applyGusts(historyClock, dt)
+	if mesh != nil && !modelDecorative {
+		updatePhysics(mesh, windSources, dt)
+	}
+	obstacles := obstacleModels()
+	refreshTriangleCache(obstacles)
+	updateWindParticles(dt, scene, obstacles)
+
+	// Simulate fluid dynamics
+	stepCount++
+	fieldBacklog += dt
+	if stepCount%fieldEvery() == 0 {
+		advanceFlowField(fieldBacklog)
+		fieldBacklog = 0
+	}
+	simulateFluid(dt)
+	collideParticles()
+	optimizer.Update(dt)
+	sensitivity.Update(dt)
+	validation.Update(dt)
+	recordHistoryFrame(dt)
+}
+
+// stepAlpha is how far the display is between the last two steps, from 0 to 1
+func stepAlpha() float32 {
+	return math32.Min(stepAccumulator/stepLength(), 1)
+}
+
+// interpolateParticles places the particle meshes in view alpha of the way from their
+// previous to their current simulated position
+func interpolateParticles(alpha float32) {
+	w := &windParticles
+	for i, pos := range w.Position {
+		if inView(pos) {
+			w.Mesh[i].SetPositionVec(blendPosition(w.Previous[i], pos, alpha))
+		}
+	}
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		current := math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}
+		if p.Mesh == nil || !inView(current) {
+			continue
+		}
+		previous := math32.Vector3{X: p.OX, Y: p.OY, Z: p.OZ}
+		p.Mesh.SetPositionVec(blendPosition(previous, current, alpha))
+	}
+	updateStreaks()
+}
+
+func blendPosition(from, to math32.Vector3, alpha float32) *math32.Vector3 {
+	if from.DistanceTo(&to) > maxInterpolation {
+		return &to
+	}
+	return from.Lerp(&to, alpha)
+}
@@ -11,31 +11,106 @@ import (
 )
 
 type SimulationData struct {
-	Time            float32
+	Time            float64 // Unix time in seconds, float32 lost minutes at today's dates
+	SimTime         float32 // simulated seconds, the clock of the history and the event markers
 	Acceleration    math32.Vector3
 	WindPower       float32
 	AngularMomentum math32.Vector3
 	DampingEffect   float32
+
+	// Instantaneous aerodynamic loads on the model
+	DragForce float32        // force along the wind direction
+	LiftForce float32        // force perpendicular to the wind, towards +Y
+	Moment    math32.Vector3 // moment of the aerodynamic force about the origin
+
+	// The loads as coefficients of the reference area, chord and freestream speed
+	Cd, Cl, Cm float32
+
+	// Particles emitted by each wind source since the previous frame
+	EmissionCounts []int
+}
+
+// SimulationRecording is the content of a saved simulation data file
+type SimulationRecording struct {
+	Version int
+	Frames  []SimulationData
+	Markers []EventMarker
+
+	// FinalField is the flow field when the recording was saved, older files have none
+	FinalField *FieldSnapshot `json:",omitempty"`
 }
 
+// simulationDataMigrations upgrade older recordings, indexed by the version they start from
+var simulationDataMigrations = []migration{
+	// v0 files are a bare array of frames without markers
+	func(doc interface{}) (interface{}, error) {
+		if frames, ok := doc.([]interface{}); ok {
+			return map[string]interface{}{"Frames": frames}, nil
+		}
+		return doc, nil
+	},
+}
+
+var simulationDataVersion = len(simulationDataMigrations)
+
 var simulationData []SimulationData
 
-func recordSimulationData(dt float32, acceleration math32.Vector3, windPower float32, angularMomentum math32.Vector3, dampingEffect float32) {
-	simulationData = append(simulationData, SimulationData{
-		Time:            float32(time.Now().UnixNano()) / 1e9,
-		Acceleration:    acceleration,
-		WindPower:       windPower,
-		AngularMomentum: angularMomentum,
-		DampingEffect:   dampingEffect,
-	})
+// recordingStopped stops frames from being recorded, e.g. once the run converged
+var recordingStopped bool
+
+// emissionCounts accumulates emitted particles per wind source until the next recorded frame
+var emissionCounts []int
+
+func countEmission(source int) {
+	for len(emissionCounts) <= source {
+		emissionCounts = append(emissionCounts, 0)
+	}
+	emissionCounts[source]++
+}
+
+// recordSimulationData stamps frame with the current wall and simulated time and the
+// emission counts and appends it
+func recordSimulationData(frame SimulationData) {
+	if recordingStopped || previewQuality {
+		return
+	}
+	frame.Time = float64(time.Now().UnixNano()) / 1e9
+	frame.SimTime = historyClock
+	frame.EmissionCounts = make([]int, len(windSources))
+	copy(frame.EmissionCounts, emissionCounts)
+	for i := range emissionCounts {
+		emissionCounts[i] = 0
+	}
+	simulationData = append(simulationData, frame)
+	enforceRecordingLimits()
 }
 
-func saveSimulationData() {
+// saveSimulationData writes the recording to a new file and returns its name
+func saveSimulationData() string {
 	filename := fmt.Sprintf("simulation_data_%d.json", time.Now().UnixNano())
 	file, err := os.Create(filename)
 	if err != nil {
 		log.Fatal("Error creating simulation data file: ", err)
 	}
 	defer file.Close()
-	json.NewEncoder(file).Encode(simulationData)
+	json.NewEncoder(file).Encode(SimulationRecording{
+		Version:    simulationDataVersion,
+		Frames:     simulationData,
+		Markers:    eventMarkers,
+		FinalField: vectorField.Snapshot(),
+	})
+	return filename
+}
+
+// loadSimulationData reads a recording written by any version of saveSimulationData
+func loadSimulationData(path string) (*SimulationRecording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec SimulationRecording
+	if err := decodeVersioned(data, simulationDataMigrations, &rec); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return &rec, nil
 }
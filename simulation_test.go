@@ -0,0 +1,288 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/g3n/engine/math32"
+)
+
+// fakeView stands in for the mesh of a particle or the marker of a wind source
+type fakeView struct {
+	position math32.Vector3
+	visible  bool
+}
+
+func (v *fakeView) SetPositionVec(pos *math32.Vector3) { v.position = *pos }
+func (v *fakeView) SetVisible(visible bool)            { v.visible = visible }
+
+// useFakeViews replaces the view constructors with ones building fakeViews, and the
+// simulation state the tests touch with an empty scene, until the test ends
+func useFakeViews(t *testing.T) *[]*fakeView {
+	var views []*fakeView
+	savedWind, savedFluid, savedMarker := newWindParticleView, newFluidParticleView, newSourceMarker
+	savedSources, savedField, savedConfig := windSources, vectorField, simConfig
+	t.Cleanup(func() {
+		newWindParticleView, newFluidParticleView, newSourceMarker = savedWind, savedFluid, savedMarker
+		windSources, vectorField, simConfig = savedSources, savedField, savedConfig
+		windParticles.Clear()
+		windViewPool = nil
+		emissionCounts = nil
+	})
+	newWindParticleView = func(position, direction math32.Vector3) ParticleView {
+		v := &fakeView{position: position, visible: true}
+		views = append(views, v)
+		return v
+	}
+	newFluidParticleView = func(pos math32.Vector3) ParticleView {
+		v := &fakeView{position: pos, visible: true}
+		views = append(views, v)
+		return v
+	}
+	newSourceMarker = func(pos math32.Vector3) SourceMarker {
+		v := &fakeView{position: pos, visible: true}
+		views = append(views, v)
+		return v
+	}
+	windParticles.Clear()
+	windViewPool = nil
+	emissionCounts = nil
+	return &views
+}
+
+// testField returns an 8 m cube of 1 m cells at rest
+func testField() VectorField {
+	f := initVectorField(8, 8, 8, 8, 8, 8)
+	f.prepare()
+	return f
+}
+
+func near(a, b float32) bool {
+	return math32.Abs(a-b) < 1e-4
+}
+
+func TestApplySourcesStampsField(t *testing.T) {
+	inside := [3]int{4, 4, 4}
+	tests := []struct {
+		name  string
+		speed float32
+		want  float32
+	}{
+		{"slow source", 5, 5},
+		{"source faster than the field allows", 100, maxFieldSpeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testField()
+			f.applySources([]WindSource{{
+				Position:  f.cellCenter(inside[0], inside[1], inside[2]),
+				Radius:    1.2,
+				Speed:     tt.speed,
+				Direction: math32.Vector3{X: 2},
+			}})
+			if got := f.face(axisX, inside[0], inside[1], inside[2]); !near(got, tt.want) {
+				t.Errorf("VX inside the source = %v, want %v", got, tt.want)
+			}
+			if got := f.face(axisY, inside[0], inside[1], inside[2]); got != 0 {
+				t.Errorf("VY inside the source = %v, want 0", got)
+			}
+			if got := f.face(axisX, 1, 1, 1); got != 0 {
+				t.Errorf("VX outside the source = %v, want 0", got)
+			}
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		value, min, max, want float32
+	}{
+		{0.5, 0, 1, 0.5},
+		{-2, 0, 1, 0},
+		{3, 0, 1, 1},
+		{1, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := clamp(tt.value, tt.min, tt.max); got != tt.want {
+			t.Errorf("clamp(%v, %v, %v) = %v, want %v", tt.value, tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestClampCell(t *testing.T) {
+	f := testField()
+	tests := []struct {
+		cell, want [3]int
+	}{
+		{[3]int{3, 4, 5}, [3]int{3, 4, 5}},
+		{[3]int{-1, 3, 9}, [3]int{0, 3, 7}},
+		{[3]int{8, -5, 0}, [3]int{7, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := f.clampCell(tt.cell); got != tt.want {
+			t.Errorf("clampCell(%v) = %v, want %v", tt.cell, got, tt.want)
+		}
+	}
+}
+
+func TestSampleVelocityInterpolates(t *testing.T) {
+	f := testField()
+	// VX grows by 1 m/s per m along x: the low x face of cell i sits at x = i - 4
+	f.forEachCell(func(c [3]int) {
+		f.cell(c).VX = float32(c[0])
+		f.cell(c).VZ = 2
+	})
+	tests := []struct {
+		name string
+		pos  math32.Vector3
+		want math32.Vector3
+	}{
+		{"on a face", math32.Vector3{X: 0, Y: 3.5, Z: 0.5}, math32.Vector3{X: 4, Z: 2}},
+		{"between faces", math32.Vector3{X: 0.3, Y: 2.7, Z: 0.2}, math32.Vector3{X: 4.3, Z: 2}},
+		{"outside the domain", math32.Vector3{X: 100, Y: 2, Z: 0}, math32.Vector3{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.SampleVelocity(tt.pos)
+			if !near(got.X, tt.want.X) || !near(got.Y, tt.want.Y) || !near(got.Z, tt.want.Z) {
+				t.Errorf("SampleVelocity(%v) = %v, want %v", tt.pos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeparateParticles(t *testing.T) {
+	tests := []struct {
+		name       string
+		velocities []math32.Vector3
+		want       []math32.Vector3
+	}{
+		{
+			"approaching pair stops along the contact",
+			[]math32.Vector3{{X: 1, Y: 1}, {X: -1}},
+			[]math32.Vector3{{Y: 1}, {}},
+		},
+		{
+			"separating pair keeps its speed",
+			[]math32.Vector3{{X: -1}, {X: 1}},
+			[]math32.Vector3{{X: -1}, {X: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := []math32.Vector3{{}, {X: 0.1}}
+			velocities := append([]math32.Vector3(nil), tt.velocities...)
+			separateParticles(positions, velocities, 0.1)
+			if d := positions[0].DistanceTo(&positions[1]); !near(d, 0.2) {
+				t.Errorf("distance after separating = %v, want 0.2", d)
+			}
+			for i := range velocities {
+				if !near(velocities[i].X, tt.want[i].X) || !near(velocities[i].Y, tt.want[i].Y) {
+					t.Errorf("velocity %d = %v, want %v", i, velocities[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWindParticleViewsAreReleased(t *testing.T) {
+	views := useFakeViews(t)
+	vectorField = initVectorField(20, 20, 20, 10, 10, 10)
+	simConfig.Solver = SolverGrid
+	simConfig.TurbulenceModel = TurbulenceToy
+	source := math32.Vector3{Y: 2}
+	windSources = []WindSource{{Position: source, Radius: 1, Speed: 5, Direction: math32.Vector3{X: 1}}}
+
+	windParticles.Add(createWindParticle(0))
+	if len(*views) != 1 || !(*views)[0].visible {
+		t.Fatalf("spawning made %d views, want one shown", len(*views))
+	}
+	// in still air the particle keeps the speed it was emitted with
+	updateWindParticles(1, nil, nil)
+	want := math32.Vector3{X: 2, Y: 2}
+	if got := windParticles.Position[0]; !near(got.X, want.X) || !near(got.Y, want.Y) {
+		t.Errorf("position after 1 s = %v, want %v", got, want)
+	}
+	for i := 0; i < 4; i++ {
+		updateWindParticles(1, nil, nil)
+	}
+	if windParticles.Len() != 0 {
+		t.Fatalf("%d particles left after their lifespan", windParticles.Len())
+	}
+	view := (*views)[0]
+	if view.visible || len(windViewPool) != 1 {
+		t.Fatalf("expired view visible %v, %d pooled; want hidden and pooled", view.visible, len(windViewPool))
+	}
+
+	// the next particle reuses the pooled view
+	windParticles.Add(createWindParticle(0))
+	if len(*views) != 1 || !view.visible || view.position != source {
+		t.Errorf("respawn made %d views, reused one visible %v at %v", len(*views), view.visible, view.position)
+	}
+}
+
+func TestRecordSimulationData(t *testing.T) {
+	useFakeViews(t)
+	savedData, savedMarkers := simulationData, eventMarkers
+	savedStopped, savedPreview := recordingStopped, previewQuality
+	t.Cleanup(func() {
+		simulationData, eventMarkers = savedData, savedMarkers
+		recordingStopped, previewQuality = savedStopped, savedPreview
+	})
+	simulationData, eventMarkers = nil, nil
+	recordingStopped, previewQuality = false, false
+	vectorField = testField()
+	windSources = []WindSource{{Direction: math32.Vector3{X: 1}}, {Direction: math32.Vector3{X: 1}}}
+
+	countEmission(0)
+	countEmission(0)
+	countEmission(1)
+	recordSimulationData(SimulationData{DragForce: 3})
+	recordSimulationData(SimulationData{DragForce: 4})
+	if len(simulationData) != 2 {
+		t.Fatalf("recorded %d frames, want 2", len(simulationData))
+	}
+	counts := [][]int{{2, 1}, {0, 0}}
+	for i, frame := range simulationData {
+		if len(frame.EmissionCounts) != 2 || frame.EmissionCounts[0] != counts[i][0] || frame.EmissionCounts[1] != counts[i][1] {
+			t.Errorf("frame %d emission counts %v, want %v", i, frame.EmissionCounts, counts[i])
+		}
+	}
+
+	// the saved file reads back the same
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(dir)
+	rec, err := loadSimulationData(saveSimulationData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Version != simulationDataVersion || len(rec.Frames) != 2 || rec.Frames[1].DragForce != 4 {
+		t.Errorf("read back version %d with %d frames, want version %d with 2", rec.Version, len(rec.Frames), simulationDataVersion)
+	}
+	if rec.FinalField == nil || rec.FinalField.AreaWidth != 8 {
+		t.Errorf("read back final field %+v, want the 8 cell field", rec.FinalField)
+	}
+}
+
+func TestRunReportWithoutTopology(t *testing.T) {
+	useFakeViews(t)
+	// a field the grid solver never stepped, as with the SPH solver or after a reset
+	vectorField = initVectorField(8, 8, 8, 8, 8, 8)
+	r := (&RunStats{start: time.Now()}).Report("")
+	if r.FieldCells != 0 {
+		t.Errorf("unprepared field reported %d cells, want 0", r.FieldCells)
+	}
+
+	// the solver leaves out the inlet, outlet, side and top layers
+	vectorField = testField()
+	if r := (&RunStats{start: time.Now()}).Report(""); r.FieldCells != 6*7*6 {
+		t.Errorf("prepared field reported %d cells, want %d", r.FieldCells, 6*7*6)
+	}
+}
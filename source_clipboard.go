@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// WindSourceParams is the part of a wind source that is copied between sources.
+// The position stays with the source that is pasted onto.
+type WindSourceParams struct {
+	Radius      float32
+	Speed       float32
+	Direction   math32.Vector3
+	Temperature float32
+}
+
+// copiedSource is the last copied parameter set, used when the clipboard holds something else
+var copiedSource *WindSourceParams
+
+func windSourceParams(wind *WindSource) WindSourceParams {
+	return WindSourceParams{
+		Radius:      wind.Radius,
+		Speed:       wind.Speed,
+		Direction:   wind.Direction,
+		Temperature: wind.Temperature,
+	}
+}
+
+func (p WindSourceParams) applyTo(wind *WindSource) {
+	wind.Radius = p.Radius
+	wind.Speed = p.Speed
+	wind.Direction = p.Direction
+	wind.Temperature = p.Temperature
+}
+
+// copyWindSource copies the parameters of source index and puts them on the system clipboard as JSON
+func copyWindSource(index int) error {
+	if index < 0 || index >= len(windSources) {
+		return fmt.Errorf("no wind source %d", index)
+	}
+	params := windSourceParams(&windSources[index])
+	copiedSource = &params
+
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	if win, ok := window.Get().(*window.GlfwWindow); ok {
+		win.SetClipboardString(string(data))
+	}
+	log.Printf("Copied wind source %d: %+v", index, params)
+	return nil
+}
+
+// pasteWindSource applies the parameters from the clipboard, or the last copied ones when
+// the clipboard does not hold a wind source, to source index
+func pasteWindSource(index int) error {
+	if index < 0 || index >= len(windSources) {
+		return fmt.Errorf("no wind source %d", index)
+	}
+
+	var params WindSourceParams
+	found := false
+	if win, ok := window.Get().(*window.GlfwWindow); ok {
+		if text := win.GetClipboardString(); text != "" {
+			found = json.Unmarshal([]byte(text), &params) == nil && params.Radius > 0
+		}
+	}
+	if !found {
+		if copiedSource == nil {
+			return fmt.Errorf("nothing to paste")
+		}
+		params = *copiedSource
+	}
+
+	params.applyTo(&windSources[index])
+	if index < len(windSpeedInputs) {
+		windSpeedInputs[index].SetValue(params.Speed)
+	}
+	log.Printf("Pasted onto wind source %d: %+v", index, params)
+	return nil
+}
+
+func initializeSourceClipboardUI(scene *core.Node) {
+	sourceIndex := 0
+	indexInput := NewNumericInput(0, 0, 99, 1, "source", func(value float32) {
+		sourceIndex = int(value)
+	})
+	addSidebarWidget(scene, indexInput)
+
+	copyBtn := gui.NewButton("Copy Source")
+	copyBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := copyWindSource(sourceIndex); err != nil {
+			log.Println("Error copying wind source:", err)
+			overlays.Notify("Could not copy: " + err.Error())
+		}
+	})
+	addSidebarWidget(scene, copyBtn)
+
+	pasteBtn := gui.NewButton("Paste Source")
+	pasteBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := pasteWindSource(sourceIndex); err != nil {
+			log.Println("Error pasting wind source:", err)
+			overlays.Notify("Could not paste: " + err.Error())
+		}
+	})
+	addSidebarWidget(scene, pasteBtn)
+}
@@ -0,0 +1,58 @@
+package main
+
+import "github.com/g3n/engine/math32"
+
+// SpatialHash buckets points by the cube of side Cell they fall in, so the points near
+// a position are found by looking at the few cubes around it instead of at all of them.
+// Build it again whenever the points moved; the buckets keep their memory between
+// builds.
+type SpatialHash struct {
+	Cell    float32
+	Points  int // bucketed by the last build
+	buckets map[[3]int32][]int32
+	used    [][3]int32 // keys filled by the last build
+}
+
+func (h *SpatialHash) key(p math32.Vector3) [3]int32 {
+	return [3]int32{
+		int32(math32.Floor(p.X / h.Cell)),
+		int32(math32.Floor(p.Y / h.Cell)),
+		int32(math32.Floor(p.Z / h.Cell)),
+	}
+}
+
+// Build buckets points with cubes of side cell
+func (h *SpatialHash) Build(points []math32.Vector3, cell float32) {
+	if h.buckets == nil {
+		h.buckets = make(map[[3]int32][]int32)
+	}
+	for _, k := range h.used {
+		h.buckets[k] = h.buckets[k][:0]
+	}
+	h.used = h.used[:0]
+	h.Cell, h.Points = cell, len(points)
+	for i, p := range points {
+		k := h.key(p)
+		bucket := h.buckets[k]
+		if len(bucket) == 0 {
+			h.used = append(h.used, k)
+		}
+		h.buckets[k] = append(bucket, int32(i))
+	}
+}
+
+// Near calls fn with the index of every point in the cubes within radius of pos, which
+// includes all the points within radius and some a bit further
+func (h *SpatialHash) Near(pos math32.Vector3, radius float32, fn func(j int)) {
+	lo := h.key(math32.Vector3{X: pos.X - radius, Y: pos.Y - radius, Z: pos.Z - radius})
+	hi := h.key(math32.Vector3{X: pos.X + radius, Y: pos.Y + radius, Z: pos.Z + radius})
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				for _, j := range h.buckets[[3]int32{x, y, z}] {
+					fn(int(j))
+				}
+			}
+		}
+	}
+}
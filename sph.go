@@ -0,0 +1,164 @@
+package main
+
+import (
+	"github.com/g3n/engine/math32"
+)
+
+// In SPH mode the wind particles are the fluid: each one is a parcel of air with a mass,
+// and the density, pressure and viscous forces come from kernel sums over the neighbors
+// within sphRadius (Müller et al. 2003). The pressure follows a weakly compressible
+// equation of state with an artificial speed of sound far below the real one, so the
+// steps can stay at a frame's length. The wind sources push the parcels inside them
+// toward their speed, like they set the grid faces in grid mode. The neighbors come from
+// a spatial hash with cubes of sphRadius, so a step costs the particles times the
+// neighbors each instead of the square of the particles.
+
+const (
+	sphRadius       = 0.6  // smoothing length h in m
+	sphSoundSpeed   = 10.0 // artificial, in m/s; the stiffness of the air is its square
+	sphSourceTime   = 0.1  // seconds for a source to bring a parcel up to its speed
+	sphMaxSubsteps  = 8
+	sphCourantRatio = 0.4 // fraction of h a pressure wave may cross per substep
+)
+
+// sphMass is the mass of one parcel in kg: at rest density a parcel fills a cube of half
+// the smoothing length
+func sphMass() float32 {
+	return simConfig.AirDensity * (sphRadius / 2) * (sphRadius / 2) * (sphRadius / 2)
+}
+
+// the kernel normalizations for the smoothing length
+var (
+	poly6Norm      = float32(315 / (64 * math32.Pi * pow9(sphRadius)))
+	spikyGradNorm  = float32(45 / (math32.Pi * pow6(sphRadius)))
+	viscLaplacNorm = spikyGradNorm
+)
+
+// sphHash buckets the parcels at their last positions
+var sphHash SpatialHash
+
+func pow6(x float64) float64 { return x * x * x * x * x * x }
+func pow9(x float64) float64 { return pow6(x) * x * x * x }
+
+// poly6 weighs the density contribution of a neighbor at squared distance r2
+func poly6(r2 float32) float32 {
+	d := sphRadius*sphRadius - r2
+	if d <= 0 {
+		return 0
+	}
+	return poly6Norm * d * d * d
+}
+
+// updateSPH moves the wind particles by dt under their pressure, viscosity and the wind
+// sources, splitting dt into substeps short enough for the artificial sound speed
+func updateSPH(w *WindParticles, dt float32) {
+	if w.Len() == 0 || dt <= 0 {
+		return
+	}
+	substeps := int(math32.Ceil(dt * sphSoundSpeed / (sphCourantRatio * sphRadius)))
+	if substeps < 1 {
+		substeps = 1
+	}
+	if substeps > sphMaxSubsteps {
+		substeps = sphMaxSubsteps
+	}
+	h := dt / float32(substeps)
+
+	accel := make([]math32.Vector3, w.Len())
+	for s := 0; s < substeps; s++ {
+		sphDensities(w)
+		sphForces(w, accel)
+		for i := range w.Position {
+			v := &w.Velocity[i]
+			v.Add(accel[i].MultiplyScalar(h))
+			sphDriveBySources(v, w.Position[i], h)
+			if v.Length() > maxFieldSpeed {
+				v.Normalize().MultiplyScalar(maxFieldSpeed)
+			}
+			w.Position[i].Add(v.Clone().MultiplyScalar(h))
+		}
+	}
+	// for sphVelocityAt, which the fluid particles call after the step
+	sphHash.Build(w.Position, sphRadius)
+}
+
+// sphDensities sums the kernel weighted masses around every particle and derives its
+// pressure, which is never negative so sparse parcels don't pull together
+func sphDensities(w *WindParticles) {
+	positions := w.Position
+	sphHash.Build(positions, sphRadius)
+	for i := range positions {
+		density := sphMass() * poly6(0)
+		sphHash.Near(positions[i], sphRadius, func(j int) {
+			if j != i {
+				density += sphMass() * poly6(positions[i].DistanceToSquared(&positions[j]))
+			}
+		})
+		w.Density[i] = density
+		w.Pressure[i] = math32.Max(sphSoundSpeed*sphSoundSpeed*(density-simConfig.AirDensity), 0)
+	}
+}
+
+// sphForces computes the pressure and viscous acceleration of every particle, with the
+// hash as sphDensities left it
+func sphForces(w *WindParticles, accel []math32.Vector3) {
+	positions, density, pressure := w.Position, w.Density, w.Pressure
+	for i := range positions {
+		accel[i] = math32.Vector3{}
+		sphHash.Near(positions[i], sphRadius, func(j int) {
+			if j == i {
+				return
+			}
+			delta := positions[i].Clone().Sub(&positions[j])
+			r := delta.Length()
+			if r >= sphRadius || r < 1e-6 {
+				return
+			}
+			// symmetric pressure term along the spiky kernel gradient, pushing i away from j
+			falloff := (sphRadius - r) * (sphRadius - r)
+			push := sphMass() * (pressure[i]/(density[i]*density[i]) + pressure[j]/(density[j]*density[j])) * spikyGradNorm * falloff
+			accel[i].Add(delta.MultiplyScalar(push / r))
+
+			// viscosity pulls the velocities of neighbors together
+			relative := w.Velocity[j].Clone().Sub(&w.Velocity[i])
+			accel[i].Add(relative.MultiplyScalar(simConfig.Viscosity * sphMass() / density[j] * viscLaplacNorm * (sphRadius - r)))
+		})
+	}
+}
+
+// sphDriveBySources relaxes the velocity of a parcel inside a wind source toward the
+// source's wind
+func sphDriveBySources(velocity *math32.Vector3, pos math32.Vector3, dt float32) {
+	for s := range windSources {
+		wind := &windSources[s]
+		if pos.DistanceTo(&wind.Position) > wind.Radius {
+			continue
+		}
+		target := wind.Direction.Clone().Normalize().MultiplyScalar(math32.Min(wind.Speed, maxFieldSpeed))
+		velocity.Add(target.Sub(velocity).MultiplyScalar(math32.Min(dt/sphSourceTime, 1)))
+	}
+}
+
+// sphVelocityAt interpolates the velocity of the parcels around pos, zero where there
+// are none; the fluid particles trace the SPH flow with it
+func sphVelocityAt(pos math32.Vector3) math32.Vector3 {
+	var sum math32.Vector3
+	var weight float32
+	particles := &windParticles
+	if sphHash.Points != particles.Len() {
+		sphHash.Build(particles.Position, sphRadius)
+	}
+	sphHash.Near(pos, sphRadius, func(i int) {
+		w := poly6(pos.DistanceToSquared(&particles.Position[i]))
+		if w == 0 || particles.Density[i] == 0 {
+			return
+		}
+		w *= sphMass() / particles.Density[i]
+		sum.Add(particles.Velocity[i].Clone().MultiplyScalar(w))
+		weight += w
+	})
+	if weight == 0 {
+		return sum
+	}
+	return *sum.DivideScalar(weight)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/gls"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// Stagnation points are where the air comes to rest against the model, on the faces
+// turned into the wind. They are found by reading the flow one cell off each collision
+// triangle, like the surface forces do, and keeping the slowest spots. From each one the
+// dividing stream surface is traced upstream: a rake of seeds across the wind through
+// the point, followed against the flow, gives a sheet that parts the air passing on one
+// side of the model from the air passing on the other.
+
+const (
+	stagnationSpeedRatio = 0.1  // of the fastest flow along the surface, at most
+	stagnationSpacing    = 2    // cells between two stagnation points, at least
+	stagnationMaxPoints  = 4    // sheets drawn, the slowest points first
+	stagnationRakeSeeds  = 9    // streamlines across each sheet
+	stagnationSteps      = 80   // half cells traced upstream along each streamline
+	stagnationRefresh    = 1.0  // seconds between searches while shown
+	stagnationOpacity    = 0.35 // of the sheets
+)
+
+// StagnationPoint is a spot on the model where the flow stops
+type StagnationPoint struct {
+	Position math32.Vector3 // on the surface
+	Normal   math32.Vector3 // outward
+	Speed    float32        // of the flow one cell off the surface, m/s
+}
+
+var (
+	showStagnation   bool
+	stagnationPoints []StagnationPoint
+	stagnationNodes  []core.INode // sheets and markers in the scene
+	stagnationSearch time.Time    // when the points were last looked for
+)
+
+// syncStagnationControls shows the number of points found on the stagnation button
+var syncStagnationControls = func() {}
+
+// findStagnationPoints returns the stagnation points on model in f, slowest first
+func findStagnationPoints(f *VectorField, model *core.Node) []StagnationPoint {
+	h := f.CellSize()
+	wind := freestreamDirection()
+	var candidates []StagnationPoint
+	var fastest float32
+	for _, tri := range cachedTriangles(model) {
+		edge1 := tri[1].Clone().Sub(&tri[0])
+		edge2 := tri[2].Clone().Sub(&tri[0])
+		normal := edge1.Cross(edge2)
+		if normal.LengthSq() == 0 {
+			continue
+		}
+		normal.Normalize()
+		centroid := tri[0].Clone().Add(&tri[1]).Add(&tri[2]).DivideScalar(3)
+		probe := centroid.Clone().Add(normal.Clone().MultiplyScalar(h))
+		velocity := f.SampleVelocity(*probe)
+		speed := velocity.Length()
+		fastest = math32.Max(fastest, speed)
+		// the air only stops on the faces it blows against
+		if normal.Dot(&wind) < 0 {
+			candidates = append(candidates, StagnationPoint{Position: *centroid, Normal: *normal, Speed: speed})
+		}
+	}
+	if fastest == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Speed < candidates[j].Speed })
+
+	var points []StagnationPoint
+	for _, c := range candidates {
+		if c.Speed > stagnationSpeedRatio*fastest || len(points) == stagnationMaxPoints {
+			break
+		}
+		near := false
+		for _, p := range points {
+			if p.Position.DistanceTo(&c.Position) < stagnationSpacing*h {
+				near = true
+				break
+			}
+		}
+		if !near {
+			points = append(points, c)
+		}
+	}
+	return points
+}
+
+// traceUpstream follows the flow backwards from seed in steps of half a cell, stopping
+// where the air is still or at the edge of the domain; the result always has
+// stagnationSteps+1 points, the last repeated where the trace stopped
+func traceUpstream(f *VectorField, seed math32.Vector3) []math32.Vector3 {
+	step := f.CellSize() / 2
+	line := make([]math32.Vector3, 0, stagnationSteps+1)
+	pos := seed
+	for len(line) <= stagnationSteps {
+		line = append(line, pos)
+		v := f.SampleVelocity(pos)
+		if v.Length() < 1e-3 {
+			continue // still air, the line ends here
+		}
+		// midpoint step against the flow
+		mid := pos.Clone().Sub(v.Normalize().MultiplyScalar(step / 2))
+		vm := f.SampleVelocity(*mid)
+		if vm.Length() < 1e-3 {
+			continue
+		}
+		next := pos.Clone().Sub(vm.Normalize().MultiplyScalar(step))
+		if _, _, _, inside := f.cellAt(*next); inside {
+			pos = *next
+		}
+	}
+	return line
+}
+
+// dividingSurface traces the rake through p and returns the sheet between its
+// streamlines as triangle positions
+func dividingSurface(f *VectorField, p StagnationPoint, halfWidth float32) math32.ArrayF32 {
+	wind := freestreamDirection()
+	span := wind.Clone().Cross(&math32.Vector3{Y: 1})
+	if span.LengthSq() < 1e-6 {
+		span = wind.Clone().Cross(&math32.Vector3{Z: 1})
+	}
+	span.Normalize()
+	// the seeds sit one cell off the surface, where the field was read
+	center := p.Position.Clone().Add(p.Normal.Clone().MultiplyScalar(f.CellSize()))
+	lines := make([][]math32.Vector3, stagnationRakeSeeds)
+	for s := range lines {
+		t := float32(s)/float32(stagnationRakeSeeds-1)*2 - 1
+		lines[s] = traceUpstream(f, *center.Clone().Add(span.Clone().MultiplyScalar(t * halfWidth)))
+	}
+	positions := math32.NewArrayF32(0, (stagnationRakeSeeds-1)*stagnationSteps*18)
+	for s := 0; s+1 < len(lines); s++ {
+		a, b := lines[s], lines[s+1]
+		for n := 0; n < stagnationSteps; n++ {
+			positions.AppendVector3(&a[n], &b[n], &b[n+1], &a[n], &b[n+1], &a[n+1])
+		}
+	}
+	return positions
+}
+
+func newStagnationSheet(positions math32.ArrayF32) *graphic.Mesh {
+	normals := math32.NewArrayF32(0, len(positions))
+	for i := 0; i+8 < len(positions); i += 9 {
+		var a, b, c math32.Vector3
+		positions.GetVector3(i, &a)
+		positions.GetVector3(i+3, &b)
+		positions.GetVector3(i+6, &c)
+		n := b.Sub(&a).Cross(c.Sub(&a))
+		if n.LengthSq() > 0 {
+			n.Normalize()
+		} else {
+			n = &math32.Vector3{Y: 1}
+		}
+		normals.AppendVector3(n, n, n)
+	}
+	geom := geometry.NewGeometry()
+	geom.AddVBO(gls.NewVBO(positions).AddAttrib(gls.VertexPosition))
+	geom.AddVBO(gls.NewVBO(normals).AddAttrib(gls.VertexNormal))
+
+	mat := material.NewStandard(math32.NewColor("Orange"))
+	mat.SetOpacity(stagnationOpacity)
+	mat.SetTransparent(true)
+	mat.SetDepthMask(false)
+	mat.SetSide(material.SideDouble)
+	return graphic.NewMesh(geom, mat)
+}
+
+func removeStagnationNodes() {
+	for _, n := range stagnationNodes {
+		objects.Remove(n)
+	}
+	stagnationNodes = nil
+}
+
+// refreshStagnation finds the stagnation points of the model again and redraws their
+// markers and sheets
+func refreshStagnation() {
+	removeStagnationNodes()
+	stagnationPoints = nil
+	if !surfaceForcesAvailable(mesh) {
+		return
+	}
+	f := &vectorField
+	stagnationPoints = findStagnationPoints(f, mesh)
+
+	bounds := mesh.BoundingBox()
+	size := boxSize(&bounds)
+	halfWidth := math32.Max(size.X, math32.Max(size.Y, size.Z)) / 2
+	for _, p := range stagnationPoints {
+		marker := graphic.NewMesh(geometry.NewSphere(float64(f.CellSize()/4), 12, 12), material.NewStandard(math32.NewColor("Red")))
+		marker.SetPositionVec(&p.Position)
+		objects.Add(marker, "stagnation point")
+		sheet := newStagnationSheet(dividingSurface(f, p, halfWidth))
+		objects.Add(sheet, "stream surface")
+		stagnationNodes = append(stagnationNodes, marker, sheet)
+	}
+	syncStagnationControls()
+}
+
+// updateStagnation searches for the stagnation points again every stagnationRefresh
+// seconds while they are shown, called once per frame
+func updateStagnation() {
+	if !showStagnation {
+		return
+	}
+	if time.Since(stagnationSearch).Seconds() < stagnationRefresh {
+		return
+	}
+	stagnationSearch = time.Now()
+	refreshStagnation()
+}
+
+func stagnationButtonText() string {
+	if !showStagnation {
+		return "Stagnation: off"
+	}
+	return fmt.Sprintf("Stagnation: %d found", len(stagnationPoints))
+}
+
+func initializeStagnationUI(scene *core.Node) {
+	stagnationBtn := gui.NewButton(stagnationButtonText())
+	stagnationBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if !showStagnation && !surfaceForcesAvailable(mesh) {
+			overlays.Notify("Stagnation points need a model in the grid solver")
+			return
+		}
+		showStagnation = !showStagnation
+		if showStagnation {
+			refreshStagnation()
+			stagnationSearch = time.Now()
+			log.Printf("Stagnation points: %d", len(stagnationPoints))
+		} else {
+			removeStagnationNodes()
+			stagnationPoints = nil
+		}
+		syncStagnationControls()
+	})
+	addSidebarWidget(scene, stagnationBtn)
+	syncStagnationControls = func() {
+		stagnationBtn.Label.SetText(stagnationButtonText())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// statusBar runs along the top of the window while the wind is on, showing how long the
+// run has been going in simulated time and how much has been recorded
+var (
+	statusBar      *gui.Panel
+	statusBarLabel *gui.Label
+)
+
+// statusText describes the simulation clock and the recording
+func statusText() string {
+	text := fmt.Sprintf("Simulated %s   Recorded %d frames, ~%s",
+		formatDuration(historyClock), len(simulationData), formatSize(int64(estimatedRecordingSize())))
+	if mesh != nil {
+		c := lastCoefficients
+		text += fmt.Sprintf("   Cd %.3f  Cl %.3f  Cm %.3f  Re %s", c.Cd, c.Cl, c.Cm, formatReynolds(reynoldsNumber()))
+	}
+	switch {
+	case simulationPaused:
+		text += "   (paused)"
+	case previewQuality:
+		text += "   (preview, not recording)"
+	case recordingStopped:
+		text += "   (not recording)"
+	}
+	if fieldUpdatesPending() {
+		text += "   (updating field)"
+	}
+	return text
+}
+
+// formatDuration shows seconds as m:ss.s, with hours once the run is that long
+func formatDuration(seconds float32) string {
+	total := int(seconds)
+	tenths := int((seconds - float32(total)) * 10)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d.%d", total/3600, total/60%60, total%60, tenths)
+	}
+	return fmt.Sprintf("%d:%02d.%d", total/60, total%60, tenths)
+}
+
+// updateStatusBar is called once per frame
+func updateStatusBar() {
+	if statusBar == nil {
+		return
+	}
+	statusBar.SetVisible(windEnabled && !quiz.readoutsHidden)
+	if !statusBar.Visible() {
+		return
+	}
+	if text := statusText(); statusBarLabel.Text() != text {
+		statusBarLabel.SetText(text)
+		statusBar.SetWidth(statusBarLabel.Width() + 2*layoutMargin)
+	}
+}
+
+func initializeStatusBar(scene *core.Node) {
+	statusBarLabel = gui.NewLabel("")
+	statusBarLabel.SetPosition(layoutMargin, 4)
+	statusBar = gui.NewPanel(0, statusBarLabel.Height()+8)
+	statusBar.SetColor4(&math32.Color4{R: 0.2, G: 0.2, B: 0.2, A: 0.6})
+	statusBar.Add(statusBarLabel)
+	statusBar.SetVisible(false)
+	// above the control column, which starts lower down
+	statusBar.SetPosition(100, layoutMargin)
+	scene.Add(statusBar)
+}
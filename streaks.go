@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// With streaks on, every particle is stretched along its velocity over the distance it
+// travels in streakSeconds, so fast air shows as long trails and slow air as dots, even
+// in a still screenshot. Meshes are scaled along their velocity; point sprites are drawn
+// by their shader as ellipses along the velocity's direction on screen. Whether streaks
+// are drawn is chosen for meshes and sprites separately.
+
+const (
+	streakSeconds   = 0.15 // of travel covered by a streak
+	streakMaxLength = 8    // streaks are at most this many particle sizes long
+)
+
+// Lengths of the particle meshes along their axis, which streaks stretch
+const (
+	windParticleLength  = 0.5
+	fluidParticleLength = 0.2
+)
+
+// StreakView is a ParticleView that draws its own streaks
+type StreakView interface {
+	SetStreak(velocity *math32.Vector3)
+}
+
+// streaksEnabled reports whether streaks are drawn in the current visualization mode
+func streaksEnabled() bool {
+	if renderSettings.PointSprites {
+		return renderSettings.SpriteStreaks
+	}
+	return renderSettings.MeshStreaks
+}
+
+// setStreak stretches view along velocity; a zero velocity gives the particle its
+// normal shape. length is the size of a mesh along its axis.
+func setStreak(view ParticleView, velocity math32.Vector3, length float32) {
+	switch v := view.(type) {
+	case StreakView:
+		v.SetStreak(&velocity)
+	case core.INode:
+		node := v.GetNode()
+		speed := velocity.Length()
+		if speed == 0 {
+			node.SetScale(1, 1, 1)
+			return
+		}
+		// the meshes are built along their Y axis
+		var q math32.Quaternion
+		q.SetFromUnitVectors(&math32.Vector3{Y: 1}, velocity.Clone().DivideScalar(speed))
+		node.SetQuaternionQuat(&q)
+		stretch := 1 + math32.Min(speed*streakSeconds/length, streakMaxLength-1)
+		node.SetScale(1, stretch, 1)
+	}
+}
+
+// updateStreaks stretches the particles in view of the current tab along their
+// velocities; called each frame after they are placed
+func updateStreaks() {
+	if !streaksEnabled() {
+		return
+	}
+	w := &windParticles
+	for i, pos := range w.Position {
+		if inView(pos) {
+			setStreak(w.Mesh[i], w.Velocity[i], windParticleLength)
+		}
+	}
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		if p.Mesh != nil && inView(math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}) {
+			setStreak(p.Mesh, math32.Vector3{X: p.VX, Y: p.VY, Z: p.VZ}, fluidParticleLength)
+		}
+	}
+}
+
+// setStreaks turns streaks on or off for the current visualization mode, giving the
+// particles of every tab their normal shape when off
+func setStreaks(enabled bool) {
+	if renderSettings.PointSprites {
+		renderSettings.SpriteStreaks = enabled
+	} else {
+		renderSettings.MeshStreaks = enabled
+	}
+	if !enabled {
+		for i, tab := range tabs {
+			w, fluid := &tab.windParticles, tab.fluidParticles
+			if i == activeTab {
+				w, fluid = &windParticles, fluidParticles
+			}
+			for _, m := range w.Mesh {
+				setStreak(m, math32.Vector3{}, windParticleLength)
+			}
+			for _, p := range fluid {
+				if p.Mesh != nil {
+					setStreak(p.Mesh, math32.Vector3{}, fluidParticleLength)
+				}
+			}
+		}
+	}
+	log.Printf("Streaks %v", enabled)
+}
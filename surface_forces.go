@@ -0,0 +1,120 @@
+package main
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// SurfaceForces are the aerodynamic loads on a model from integrating the flow over its
+// triangles: the pressure pushing on each triangle along its normal and the viscous
+// shear dragging it along the flow beside it
+type SurfaceForces struct {
+	Pressure math32.Vector3 // N
+	Shear    math32.Vector3 // N
+	Moment   math32.Vector3 // N m about the origin
+	Area     float32        // m² of wetted surface
+}
+
+// Total returns the whole force in N
+func (s SurfaceForces) Total() math32.Vector3 {
+	return *s.Pressure.Clone().Add(&s.Shear)
+}
+
+// SamplePressure returns the pressure at pos in Pa, trilinearly interpolated between the
+// surrounding cell centers. Solid cells have no meaningful pressure and are left out of
+// the interpolation, so points just off a surface read the air beside it.
+func (f *VectorField) SamplePressure(pos math32.Vector3) float32 {
+	o := f.origin()
+	h := f.CellSize()
+	gx := (pos.X-o.X)/h - 0.5
+	gy := (pos.Y-o.Y)/h - 0.5
+	gz := (pos.Z-o.Z)/h - 0.5
+	i0, j0, k0 := int(math32.Floor(gx)), int(math32.Floor(gy)), int(math32.Floor(gz))
+	fx, fy, fz := gx-float32(i0), gy-float32(j0), gz-float32(k0)
+
+	var sum, weights float32
+	for corner := 0; corner < 8; corner++ {
+		c := f.wrapPeriodic([3]int{i0 + corner&1, j0 + corner>>1&1, k0 + corner>>2&1})
+		if !f.isInterior(c) || f.isSolid(c) {
+			continue
+		}
+		w := lerpWeight(fx, corner&1) * lerpWeight(fy, corner>>1&1) * lerpWeight(fz, corner>>2&1)
+		sum += w * f.PressureAt(c)
+		weights += w
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// lerpWeight is the weight of the low (0) or high (1) end at fraction t
+func lerpWeight(t float32, high int) float32 {
+	if high == 1 {
+		return t
+	}
+	return 1 - t
+}
+
+// surfaceForces integrates the pressure and shear of the field over the collision
+// triangles of model. The triangles are taken to wind counter-clockwise seen from
+// outside, as exported models do. The flow is read one cell off each triangle, outside
+// the cells the model covers.
+func surfaceForces(f *VectorField, model *core.Node) SurfaceForces {
+	var s SurfaceForces
+	h := f.CellSize()
+	unit := simConfig.LengthUnit
+	mu := simConfig.AirDensity * simConfig.Viscosity // dynamic viscosity in Pa s
+	for _, tri := range cachedTriangles(model) {
+		edge1 := tri[1].Clone().Sub(&tri[0])
+		edge2 := tri[2].Clone().Sub(&tri[0])
+		normal := edge1.Cross(edge2)
+		area := normal.Length() / 2
+		if area == 0 {
+			continue
+		}
+		normal.Normalize()
+		centroid := tri[0].Clone().Add(&tri[1]).Add(&tri[2]).DivideScalar(3)
+		probe := centroid.Clone().Add(normal.Clone().MultiplyScalar(h))
+		area *= unit * unit
+
+		// the air pushes against the outward normal
+		pressure := normal.Clone().MultiplyScalar(-f.SamplePressure(*probe) * area)
+
+		// shear from the velocity gradient between the wall, at rest, and the probe
+		velocity := f.SampleVelocity(*probe)
+		tangential := velocity.Clone().Sub(normal.Clone().MultiplyScalar(velocity.Dot(normal)))
+		shear := tangential.MultiplyScalar(mu / (h * unit) * area)
+
+		s.Pressure.Add(pressure)
+		s.Shear.Add(shear)
+		force := pressure.Add(shear)
+		s.Moment.Add(centroid.MultiplyScalar(unit).Cross(force))
+		s.Area += area
+	}
+	return s
+}
+
+// freestreamDirection is the direction the wind blows in overall, for splitting the
+// surface force into drag and lift: the ambient wind when it blows, else the speed
+// weighted mean of the sources
+func freestreamDirection() math32.Vector3 {
+	if ambientWind.Enabled && ambientWind.Speed > 0 {
+		return ambientWind.direction()
+	}
+	var sum math32.Vector3
+	for _, w := range windSources {
+		dir := w.Direction.Clone().Normalize().MultiplyScalar(w.Speed)
+		sum.Add(dir)
+	}
+	if sum.Length() < 1e-6 {
+		return math32.Vector3{X: 1}
+	}
+	return *sum.Normalize()
+}
+
+// surfaceForcesAvailable reports whether the field resolves the model, so its forces
+// can be integrated over the surface instead of estimated from a drag coefficient
+func surfaceForcesAvailable(model *core.Node) bool {
+	return simConfig.Solver == SolverGrid && !modelDecorative && model != nil && vectorField.solid != nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/g3n/engine/app"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+	"github.com/g3n/engine/window"
+)
+
+// Several scenes can be open at once, each in a tab with its own wind sources, model,
+// flow field, particles and recording. Only the tab in front runs; the others keep
+// their state, with their meshes hidden, until they are switched to. The settings, the
+// camera, the context models and the annotations are shared by all tabs.
+
+// Simulation holds the state of a tab while it is in the background. The tab in front
+// keeps its state in the globals the rest of the program works on.
+type Simulation struct {
+	Name string
+
+	windSources     []WindSource
+	windSpeedInputs []*NumericInput
+	emissionCounts  []int
+	windParticles   WindParticles
+	fluidParticles  []Particle
+	spawnClock      float32
+	fieldBacklog    float32
+
+	mesh            *core.Node
+	models          []*core.Node
+	modelPath       string
+	modelDecorative bool
+	rigidBody       RigidBody
+	coefficients    AeroCoefficients
+
+	vectorField     VectorField
+	vortexParticles []VortexParticle
+	fieldAverage    FieldAverage
+
+	simulationData    []SimulationData
+	eventMarkers      []EventMarker
+	history           *HistoryBuffer
+	historyClock      float32
+	lastHistorySample float32
+	recordingStopped  bool
+	convergence       ConvergenceMonitor
+}
+
+var (
+	tabs      = []*Simulation{{Name: "Scene 1"}}
+	activeTab int
+	tabCount  = 1 // tabs opened so far, for naming new ones
+)
+
+// syncTabControls shows the tab in front on the tab button
+var syncTabControls = func() {}
+
+// newSimulation returns an empty scene over the default domain
+func newSimulation(name string) *Simulation {
+	nx, ny, nz := fieldCells()
+	s := &Simulation{
+		Name:              name,
+		rigidBody:         RigidBody{Inertia: math32.Vector3{X: 1, Y: 1, Z: 1}},
+		vectorField:       initVectorField(20, 5, 20, nx, ny, nz),
+		history:           NewHistoryBuffer(historySeconds * historySampleRate),
+		lastHistorySample: -1,
+		convergence:       convergence, // same settings, no samples
+	}
+	s.convergence.Reset()
+	return s
+}
+
+// save takes the state of the tab in front from the globals
+func (s *Simulation) save(ml *ModelLoader) {
+	s.windSources, s.windSpeedInputs, s.emissionCounts = windSources, windSpeedInputs, emissionCounts
+	s.windParticles, s.fluidParticles = windParticles, fluidParticles
+	s.spawnClock, s.fieldBacklog = spawnClock, fieldBacklog
+	s.mesh, s.models, s.modelPath, s.modelDecorative = mesh, ml.models, ml.path, modelDecorative
+	s.rigidBody, s.coefficients = rigidBody, lastCoefficients
+	s.vectorField, s.vortexParticles, s.fieldAverage = vectorField, vortexParticles, fieldAverage
+	s.simulationData, s.eventMarkers, s.history = simulationData, eventMarkers, history
+	s.historyClock, s.lastHistorySample, s.recordingStopped = historyClock, lastHistorySample, recordingStopped
+	s.convergence = convergence
+}
+
+// restore puts the state of s into the globals, bringing it to the front
+func (s *Simulation) restore(ml *ModelLoader) {
+	windSources, windSpeedInputs, emissionCounts = s.windSources, s.windSpeedInputs, s.emissionCounts
+	windParticles, fluidParticles = s.windParticles, s.fluidParticles
+	spawnClock, fieldBacklog = s.spawnClock, s.fieldBacklog
+	mesh, ml.models, ml.path, modelDecorative = s.mesh, s.models, s.modelPath, s.modelDecorative
+	rigidBody, lastCoefficients = s.rigidBody, s.coefficients
+	vectorField, vortexParticles, fieldAverage = s.vectorField, s.vortexParticles, s.fieldAverage
+	simulationData, eventMarkers, history = s.simulationData, s.eventMarkers, s.history
+	historyClock, lastHistorySample, recordingStopped = s.historyClock, s.lastHistorySample, s.recordingStopped
+	convergence = s.convergence
+	convergence.refreshLabel()
+}
+
+// showCurrentScene shows or hides the meshes and the speed inputs of the tab in front
+func showCurrentScene(scene *core.Node, ml *ModelLoader, visible bool) {
+	for _, w := range windSources {
+		if node, ok := w.Node.(core.INode); ok {
+			node.GetNode().SetVisible(visible)
+		}
+	}
+	for _, m := range windParticles.Mesh {
+		m.SetVisible(visible)
+	}
+	for _, p := range fluidParticles {
+		if p.Mesh != nil {
+			p.Mesh.SetVisible(visible)
+		}
+	}
+	for _, m := range ml.models {
+		m.SetVisible(visible)
+	}
+	for _, input := range windSpeedInputs {
+		if visible {
+			addControlWidget(scene, input)
+		} else {
+			detachControlWidget(scene, input)
+		}
+	}
+}
+
+// stopTabWork stops the studies, playback and overlays of the tab in front, which don't
+// carry over to another tab
+func stopTabWork() {
+	optimizer.Stop()
+	sensitivity.Stop()
+	validation.Stop()
+	stopPlaybackRecording()
+	closePlayback()
+	objects.RemoveKind("scrub particle")
+	scrubMeshes = nil
+	removeStagnationNodes()
+	removeBoundaryLayerLine()
+}
+
+// leaveTab stops what runs on the tab in front and puts it in the background
+func leaveTab(scene *core.Node, ml *ModelLoader) {
+	stopTabWork()
+	showCurrentScene(scene, ml, false)
+	tabs[activeTab].save(ml)
+}
+
+// enterTab brings tab i to the front
+func enterTab(scene *core.Node, ml *ModelLoader, i int) {
+	activeTab = i
+	tabs[i].restore(ml)
+	showCurrentScene(scene, ml, true)
+	syncTabControls()
+	log.Printf("Switched to tab %q", tabs[i].Name)
+}
+
+func switchTab(scene *core.Node, ml *ModelLoader, i int) {
+	if i == activeTab || i < 0 || i >= len(tabs) {
+		return
+	}
+	leaveTab(scene, ml)
+	enterTab(scene, ml, i)
+}
+
+// openTab adds a tab with an empty scene and brings it to the front
+func openTab(scene *core.Node, ml *ModelLoader) {
+	leaveTab(scene, ml)
+	tabCount++
+	tabs = append(tabs, newSimulation(fmt.Sprintf("Scene %d", tabCount)))
+	enterTab(scene, ml, len(tabs)-1)
+}
+
+// closeTab discards the tab in front and brings the next one forward. The last tab
+// can't be closed.
+func closeTab(scene *core.Node, ml *ModelLoader) {
+	if len(tabs) < 2 {
+		return
+	}
+	closed := tabs[activeTab].Name
+	stopTabWork()
+	removeWindSourceMarkers(scene)
+	for _, input := range windSpeedInputs {
+		removeControlWidget(scene, input)
+	}
+	for _, m := range windParticles.Mesh {
+		releaseWindView(m)
+	}
+	removeFluidParticles()
+	for _, m := range ml.models {
+		objects.Remove(m)
+		forgetModel(m)
+	}
+
+	tabs = append(tabs[:activeTab], tabs[activeTab+1:]...)
+	next := activeTab
+	if next >= len(tabs) {
+		next = len(tabs) - 1
+	}
+	enterTab(scene, ml, next)
+	log.Printf("Closed tab %q", closed)
+}
+
+func tabButtonText() string {
+	return fmt.Sprintf("Tab: %s (%d/%d)", tabs[activeTab].Name, activeTab+1, len(tabs))
+}
+
+// showTabComparison lists the loads and the recording of every tab side by side
+func showTabComparison(ml *ModelLoader) {
+	tabs[activeTab].save(ml)
+
+	const rowHeight = 22
+	dialog := gui.NewPanel(560, float32(95+rowHeight*len(tabs)))
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Compare tabs")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	columns := []float32{10, 110, 160, 270, 340, 410, 470}
+	row := func(y float32, cells ...string) {
+		for i, text := range cells {
+			label := gui.NewLabel(text)
+			label.SetPosition(columns[i], y)
+			dialog.Add(label)
+		}
+	}
+	row(35, "Tab", "Sources", "Model", "Simulated", "Cd", "Cl", "Cm")
+	for i, s := range tabs {
+		model := "-"
+		switch {
+		case s.modelPath != "":
+			model = filepath.Base(s.modelPath)
+		case s.mesh != nil:
+			model = s.mesh.Name()
+		}
+		name := s.Name
+		if i == activeTab {
+			name += " *"
+		}
+		c := s.coefficients
+		row(float32(35+rowHeight*(i+1)), name, fmt.Sprint(len(s.windSources)), model,
+			formatDuration(s.historyClock),
+			fmt.Sprintf("%.3f", c.Cd), fmt.Sprintf("%.3f", c.Cl), fmt.Sprintf("%.3f", c.Cm))
+	}
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(500, dialog.Height()-35)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeTabsUI(scene *core.Node, ml *ModelLoader) {
+	tabBtn := gui.NewButton(tabButtonText())
+	tabBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		switchTab(scene, ml, (activeTab+1)%len(tabs))
+	})
+	addSidebarWidget(scene, tabBtn)
+	syncTabControls = func() {
+		tabBtn.Label.SetText(tabButtonText())
+	}
+
+	newTabBtn := gui.NewButton("New Tab")
+	newTabBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		openTab(scene, ml)
+		overlays.Notify(tabs[activeTab].Name + " opened")
+	})
+	addSidebarWidget(scene, newTabBtn)
+
+	closeTabBtn := gui.NewButton("Close Tab")
+	closeTabBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if len(tabs) < 2 {
+			overlays.Notify("The last tab stays open")
+			return
+		}
+		overlays.Confirm("Discard "+tabs[activeTab].Name+" and its recording?", func() {
+			closeTab(scene, ml)
+		})
+	})
+	addSidebarWidget(scene, closeTabBtn)
+
+	compareBtn := gui.NewButton("Compare Tabs...")
+	compareBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showTabComparison(ml)
+	})
+	addSidebarWidget(scene, compareBtn)
+
+	// Ctrl+Tab brings the next tab to the front
+	app.App().Subscribe(window.OnKeyDown, func(evname string, ev interface{}) {
+		kev := ev.(*window.KeyEvent)
+		if overlays.Blocking() {
+			return
+		}
+		if kev.Key == window.KeyTab && kev.Mods&window.ModControl != 0 {
+			switchTab(scene, ml, (activeTab+1)%len(tabs))
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/g3n/engine/math32"
+)
+
+// The temperature field holds the air temperature of every cell in °C. The wind sources
+// heat or cool the cells they cover, the flow carries the heat along and it spreads by
+// diffusion. Air warmer than the ambient rises, by the Boussinesq approximation: only the
+// buoyancy g*beta*(T - ambient) depends on the temperature, with beta = 1/T in kelvin.
+
+// thermalDiffusivity is in m²/s. Air's own is far below what the grid resolves, this is
+// the mixing by eddies smaller than a cell.
+const thermalDiffusivity = 0.01
+
+const kelvin = 273.15
+
+// resetTemperature fills the temperature field with the ambient temperature
+func (f *VectorField) resetTemperature() {
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	f.temperature = make([]float32, n)
+	f.tempScratch = make([]float32, n)
+	for i := range f.temperature {
+		f.temperature[i] = defaultTemperature
+	}
+}
+
+// TemperatureAt returns the interpolated air temperature at pos in °C
+func (f *VectorField) TemperatureAt(pos math32.Vector3) float32 {
+	if f.temperature == nil {
+		return defaultTemperature
+	}
+	return f.sampleCell(f.temperature, pos)
+}
+
+// applyBuoyancy accelerates the vertical faces by the buoyancy of the cells around them
+func (f *VectorField) applyBuoyancy(dt float32) {
+	if !simConfig.Buoyancy || f.temperature == nil {
+		return
+	}
+	beta := float32(1 / (defaultTemperature + kelvin))
+	for _, face := range f.topology.openFaces {
+		if face.axis != axisY {
+			continue
+		}
+		below := face.c
+		below[axisY]--
+		below = f.clampCell(below)
+		t := (f.temperature[f.index(face.c[0], face.c[1], face.c[2])] + f.temperature[f.index(below[0], below[1], below[2])]) / 2
+		v := f.face(axisY, face.c[0], face.c[1], face.c[2]) - dt*simConfig.Gravity*beta*(t-defaultTemperature)
+		f.setFace(axisY, face.c, clamp(v, -maxFieldSpeed, maxFieldSpeed))
+	}
+}
+
+// updateTemperature sets the temperature of the source cells and moves the heat with the
+// flow (semi-Lagrangian) and by diffusion. Boundary and solid cells keep theirs, so fresh
+// ambient air comes in through the open faces.
+func (f *VectorField) updateTemperature(dt float32, sources []WindSource) {
+	if f.temperature == nil {
+		f.resetTemperature()
+	}
+	t := f.topology
+	h := f.CellSize()
+	prev := f.tempScratch
+	copy(prev, f.temperature)
+
+	// explicit diffusion, limited to its stable step
+	alpha := math32.Min(thermalDiffusivity*dt/(h*h), 1.0/6)
+	for m, c := range t.fluidCoords {
+		i := t.fluidCells[m]
+		center := f.cellCenter(c[0], c[1], c[2])
+		vel := f.cellVelocity(c)
+		back := center.Clone().Sub(vel.MultiplyScalar(dt))
+		value := f.sampleCell(prev, *back)
+
+		var laplacian float32
+		for a := 0; a < 3; a++ {
+			lo, hi := c, c
+			lo[a]--
+			hi[a]++
+			lo, hi = f.clampCell(lo), f.clampCell(hi)
+			laplacian += prev[f.index(lo[0], lo[1], lo[2])] + prev[f.index(hi[0], hi[1], hi[2])] - 2*prev[i]
+		}
+		f.temperature[i] = value + alpha*laplacian
+	}
+
+	for s := range sources {
+		wind := &sources[s]
+		for m, c := range t.fluidCoords {
+			center := f.cellCenter(c[0], c[1], c[2])
+			if center.DistanceTo(&wind.Position) <= wind.Radius {
+				f.temperature[t.fluidCells[m]] = wind.Temperature
+			}
+		}
+	}
+}
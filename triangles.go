@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/g3n/demos/hellog3n/voxel"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/math32"
+)
+
+// forEachWorldTriangle calls cb with every triangle of the meshes under node, transformed to world space
+func forEachWorldTriangle(node core.INode, cb func(a, b, c math32.Vector3)) {
+	voxel.ForEachWorldTriangle(node.GetNode(), cb)
+}
+
+// boxSize returns the extent of b. Box3.Size of g3n v0.2.0 returns Min - Max, the
+// negative of it.
+func boxSize(b *math32.Box3) math32.Vector3 {
+	return *b.Max.Clone().Sub(&b.Min)
+}
+
+// worldTriangles are the collision triangles of a model in world space, as of the
+// transform and collision mesh they were computed with
+type worldTriangles struct {
+	matrix    math32.Matrix4
+	collision *CollisionMesh
+	triangles [][3]math32.Vector3
+}
+
+// triangleCache holds the world space triangles of the obstacles, transformed once per
+// step instead of by every consumer
+var triangleCache = map[*core.Node]*worldTriangles{}
+
+// cachedTriangles returns the world space collision triangles of model, transforming
+// them again only when the model moved or its collision mesh changed
+func cachedTriangles(model *core.Node) [][3]math32.Vector3 {
+	model.UpdateMatrixWorld()
+	world := model.MatrixWorld()
+	var collision *CollisionMesh
+	if collisionMesh != nil && collisionMesh.model == model {
+		collision = collisionMesh
+	}
+	cached, ok := triangleCache[model]
+	if ok && cached.matrix == world && cached.collision == collision {
+		return cached.triangles
+	}
+	if !ok {
+		cached = &worldTriangles{}
+		triangleCache[model] = cached
+	}
+	cached.matrix = world
+	cached.collision = collision
+	cached.triangles = cached.triangles[:0]
+	add := func(a, b, c math32.Vector3) {
+		cached.triangles = append(cached.triangles, [3]math32.Vector3{a, b, c})
+	}
+	if collision == nil {
+		forEachWorldTriangle(model, add)
+		return cached.triangles
+	}
+	for _, tri := range collision.Triangles {
+		a, b, c := tri[0], tri[1], tri[2]
+		a.ApplyMatrix4(&world)
+		b.ApplyMatrix4(&world)
+		c.ApplyMatrix4(&world)
+		add(a, b, c)
+	}
+	return cached.triangles
+}
+
+// refreshTriangleCache brings the cache up to date with the obstacles, called once per
+// simulation step; models that are no longer obstacles are dropped
+func refreshTriangleCache(models []*core.Node) {
+	live := make(map[*core.Node]bool, len(models))
+	for _, model := range models {
+		live[model] = true
+		cachedTriangles(model)
+	}
+	for model := range triangleCache {
+		if !live[model] {
+			delete(triangleCache, model)
+		}
+	}
+}
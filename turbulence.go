@@ -0,0 +1,235 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/g3n/engine/math32"
+)
+
+// TurbulenceModel selects how the unresolved small eddies are represented
+type TurbulenceModel int
+
+const (
+	// TurbulenceToy jitters the particles by a fixed random amount
+	TurbulenceToy TurbulenceModel = iota
+	// TurbulenceKEpsilon solves the standard two-equation k-epsilon model on the grid. The
+	// eddy viscosity it gives is added to the diffusion and the particles are dispersed by
+	// the local turbulent kinetic energy.
+	TurbulenceKEpsilon
+	// TurbulenceLES resolves the large eddies on the grid and models the ones smaller than a
+	// cell with the Smagorinsky eddy viscosity. It needs a fine grid to be worthwhile.
+	TurbulenceLES
+)
+
+var turbulenceModelNames = []string{"toy", "k-epsilon", "LES"}
+
+// Standard k-epsilon constants
+const (
+	kEpsCmu     = 0.09
+	kEpsC1      = 1.44
+	kEpsC2      = 1.92
+	kEpsSigmaK  = 1.0
+	kEpsSigmaE  = 1.3
+	maxEddyVisc = 1.0 // m²/s, keeps the diffusion from swamping the flow in tiny cells
+)
+
+// Inflow turbulence: the wind sources blow with this intensity and eddies of this size
+// relative to their radius. The domain starts with the ambient level.
+const (
+	sourceTurbulenceIntensity = 0.05
+	sourceLengthScale         = 0.07
+	ambientTKE                = 1e-4
+	ambientLengthScale        = 0.5
+	minTKE                    = 1e-8
+)
+
+// Smagorinsky constant and the constant relating the subgrid viscosity to the subgrid
+// kinetic energy, nu = Ck * delta * sqrt(k)
+const (
+	smagorinskyCs = 0.17
+	subgridCk     = 0.094
+)
+
+// toyJitter is the amplitude of the random particle velocity in the toy model
+const toyJitter = 0.1
+
+// dissipationFor returns the epsilon that goes with turbulent kinetic energy k in eddies of size l
+func dissipationFor(k, l float32) float32 {
+	return math32.Pow(kEpsCmu, 0.75) * math32.Pow(k, 1.5) / l
+}
+
+// resetTurbulence fills the k and epsilon fields with the ambient level
+func (f *VectorField) resetTurbulence() {
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	f.tke = make([]float32, n)
+	f.dissipation = make([]float32, n)
+	f.turbScratch = make([]float32, 2*n)
+	for i := range f.tke {
+		f.tke[i] = ambientTKE
+		f.dissipation[i] = dissipationFor(ambientTKE, ambientLengthScale)
+	}
+}
+
+// eddyViscosity returns the turbulent viscosity of a cell: Cmu*k²/epsilon with k-epsilon,
+// the subgrid viscosity with LES, zero otherwise or before the model has run
+func (f *VectorField) eddyViscosity(c [3]int) float32 {
+	c = f.clampCell(c)
+	i := f.index(c[0], c[1], c[2])
+	switch simConfig.TurbulenceModel {
+	case TurbulenceKEpsilon:
+		if f.tke == nil || f.dissipation[i] <= 0 {
+			return 0
+		}
+		k := f.tke[i]
+		return math32.Min(kEpsCmu*k*k/f.dissipation[i], maxEddyVisc)
+	case TurbulenceLES:
+		if f.subgridViscosity == nil {
+			return 0
+		}
+		return f.subgridViscosity[i]
+	}
+	return 0
+}
+
+// strainRate returns 2 S:S, twice the squared norm of the strain rate tensor, from a
+// velocity gradient
+func strainRate(d [3][3]float32) float32 {
+	var strain float32
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 3; b++ {
+			s := (d[a][b] + d[b][a]) / 2
+			strain += 2 * s * s
+		}
+	}
+	return strain
+}
+
+// updateSubgridViscosity computes the Smagorinsky viscosity (Cs*delta)²*|S| of every fluid
+// cell, with the cell size as the filter width delta
+func (f *VectorField) updateSubgridViscosity() {
+	n := f.AreaWidth * f.AreaHeight * f.AreaDepth
+	if len(f.subgridViscosity) != n {
+		f.subgridViscosity = make([]float32, n)
+	}
+	lengthScale := smagorinskyCs * f.CellSize()
+	for m, c := range f.topology.fluidCoords {
+		s := math32.Sqrt(strainRate(f.velocityGradient(c)))
+		f.subgridViscosity[f.topology.fluidCells[m]] = math32.Min(lengthScale*lengthScale*s, maxEddyVisc)
+	}
+}
+
+// sampleCell trilinearly interpolates a per cell quantity at pos
+func (f *VectorField) sampleCell(values []float32, pos math32.Vector3) float32 {
+	o := f.origin()
+	h := f.CellSize()
+	g := [3]float32{(pos.X-o.X)/h - 0.5, (pos.Y-o.Y)/h - 0.5, (pos.Z-o.Z)/h - 0.5}
+	var base [3]int
+	var t [3]float32
+	for a := 0; a < 3; a++ {
+		fl := math32.Floor(g[a])
+		base[a] = int(fl)
+		t[a] = g[a] - fl
+	}
+	var sum float32
+	for corner := 0; corner < 8; corner++ {
+		c := base
+		w := float32(1)
+		for a := 0; a < 3; a++ {
+			if corner>>a&1 == 1 {
+				c[a]++
+				w *= t[a]
+			} else {
+				w *= 1 - t[a]
+			}
+		}
+		c = f.clampCell(c)
+		sum += w * values[f.index(c[0], c[1], c[2])]
+	}
+	return sum
+}
+
+// updateTurbulence advances k and epsilon by dt: inflow from the sources, semi-Lagrangian
+// advection, diffusion, production by the shear of the flow and dissipation. The sink
+// terms are treated implicitly so the stiff decay can't overshoot below zero.
+func (f *VectorField) updateTurbulence(dt float32, sources []WindSource) {
+	if f.tke == nil {
+		f.resetTurbulence()
+	}
+	t := f.topology
+	h := f.CellSize()
+	n := len(f.tke)
+	prevK, prevE := f.turbScratch[:n], f.turbScratch[n:]
+	copy(prevK, f.tke)
+	copy(prevE, f.dissipation)
+
+	for m, c := range t.fluidCoords {
+		i := t.fluidCells[m]
+		center := f.cellCenter(c[0], c[1], c[2])
+
+		vel := f.cellVelocity(c)
+		back := center.Clone().Sub(vel.MultiplyScalar(dt))
+		k := math32.Max(f.sampleCell(prevK, *back), minTKE)
+		e := math32.Max(f.sampleCell(prevE, *back), minTKE)
+
+		// production from the strain rate: nu_t * 2 S:S
+		nut := math32.Min(kEpsCmu*k*k/e, maxEddyVisc)
+		production := nut * strainRate(f.velocityGradient(c))
+
+		var lapK, lapE float32
+		for a := 0; a < 3; a++ {
+			lo, hi := c, c
+			lo[a]--
+			hi[a]++
+			lo, hi = f.clampCell(lo), f.clampCell(hi)
+			li, hiIdx := f.index(lo[0], lo[1], lo[2]), f.index(hi[0], hi[1], hi[2])
+			lapK += prevK[li] + prevK[hiIdx] - 2*prevK[i]
+			lapE += prevE[li] + prevE[hiIdx] - 2*prevE[i]
+		}
+		// explicit diffusion, limited to its stable step
+		diffK := math32.Min((simConfig.Viscosity+nut/kEpsSigmaK)*dt/(h*h), 1.0/6)
+		diffE := math32.Min((simConfig.Viscosity+nut/kEpsSigmaE)*dt/(h*h), 1.0/6)
+
+		f.tke[i] = math32.Max((k+diffK*lapK+dt*production)/(1+dt*e/k), minTKE)
+		f.dissipation[i] = math32.Max((e+diffE*lapE+dt*kEpsC1*e/k*production)/(1+dt*kEpsC2*e/k), minTKE)
+	}
+
+	for s := range sources {
+		wind := &sources[s]
+		k := 1.5 * math32.Pow(wind.Speed*sourceTurbulenceIntensity, 2)
+		e := dissipationFor(k, math32.Max(sourceLengthScale*wind.Radius, 0.01))
+		for m, c := range t.fluidCoords {
+			center := f.cellCenter(c[0], c[1], c[2])
+			if center.DistanceTo(&wind.Position) <= wind.Radius {
+				i := t.fluidCells[m]
+				f.tke[i], f.dissipation[i] = k, e
+			}
+		}
+	}
+}
+
+// turbulentFluctuation returns a random velocity for a particle at pos standing in for the
+// eddies the grid can't resolve
+func (f *VectorField) turbulentFluctuation(pos math32.Vector3) *math32.Vector3 {
+	var k float32
+	switch {
+	case simConfig.TurbulenceModel == TurbulenceKEpsilon && f.tke != nil:
+		k = f.sampleCell(f.tke, pos)
+	case simConfig.TurbulenceModel == TurbulenceLES && f.subgridViscosity != nil:
+		// only the eddies smaller than a cell are missing from the resolved flow
+		sqrtK := f.sampleCell(f.subgridViscosity, pos) / (subgridCk * f.CellSize())
+		k = sqrtK * sqrtK
+	default:
+		return math32.NewVector3(
+			(rand.Float32()-0.5)*toyJitter,
+			(rand.Float32()-0.5)*toyJitter,
+			(rand.Float32()-0.5)*toyJitter,
+		)
+	}
+	// isotropic turbulence puts 2k/3 of variance in each component
+	sigma := math32.Sqrt(2 * math32.Max(k, 0) / 3)
+	return math32.NewVector3(
+		float32(rand.NormFloat64())*sigma,
+		float32(rand.NormFloat64())*sigma,
+		float32(rand.NormFloat64())*sigma,
+	)
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"github.com/g3n/engine/app"
 	"github.com/g3n/engine/math32"
 	"log"
-	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/g3n/engine/camera"
 	"github.com/g3n/engine/core"
@@ -14,10 +13,8 @@ import (
 	"github.com/g3n/engine/window"
 )
 
-func initializeUI(scene *core.Node, windSources []WindSource, ml *ModelLoader, cam camera.ICamera) {
-	windEnabled := false
+func initializeUI(scene *core.Node, ml *ModelLoader, cam camera.ICamera) {
 	btn := gui.NewButton("Wind OFF")
-	btn.SetPosition(100, 40)
 	btn.SetSize(80, 40)
 	btn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
 		windEnabled = !windEnabled
@@ -27,50 +24,76 @@ func initializeUI(scene *core.Node, windSources []WindSource, ml *ModelLoader, c
 			btn.Label.SetText("Wind OFF")
 		}
 	})
-	scene.Add(btn)
+	addControlWidget(scene, btn)
 
 	emptyBtn := gui.NewButton("Import an object")
-	emptyBtn.SetSize(120, 40)
-	scene.Add(emptyBtn)
+	addSidebarWidget(scene, emptyBtn)
 
 	addWindBtn := gui.NewButton("Add Wind Source")
-	addWindBtn.SetSize(120, 40)
-	scene.Add(addWindBtn)
+	addSidebarWidget(scene, addWindBtn)
 
-	waitingForWindPlacement := false
-
-	updateButtonLayout := func(w, h int) {
-		const minWidth, minHeight = 400, 200
-		if w < minWidth || h < minHeight {
-			emptyBtn.SetVisible(false)
-			addWindBtn.SetVisible(false)
+	saveSceneBtn := gui.NewButton("Save Scene")
+	saveSceneBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := saveScene(defaultSceneFile, ml); err != nil {
+			log.Println("Error saving scene:", err)
+			overlays.Notify("Could not save scene: " + err.Error())
 			return
 		}
-		emptyBtn.SetVisible(true)
-		addWindBtn.SetVisible(true)
-
-		btnWidth := float32(w) * 0.15
-		btnHeight := float32(h) * 0.05
-		btnX := float32(w) - btnWidth - float32(w)*0.05
-		btnY := float32(h) * 0.1
-
-		emptyBtn.SetSize(btnWidth, btnHeight)
-		emptyBtn.SetPosition(btnX, btnY)
-
-		addWindBtn.SetSize(btnWidth, btnHeight)
-		addWindBtn.SetPosition(btnX, btnY+btnHeight+10)
-
-		addWindBtn.SetSize(btnWidth, btnHeight)
-		addWindBtn.SetPosition(btnX, btnY+btnHeight+10)
-	}
+		markFileWritten(defaultSceneFile)
+		overlays.Notify("Scene saved to " + defaultSceneFile)
+	})
+	addSidebarWidget(scene, saveSceneBtn)
 
-	app.App().Subscribe(window.OnWindowSize, func(evname string, ev interface{}) {
-		w, h := app.App().GetSize()
-		updateButtonLayout(w, h)
+	loadSceneBtn := gui.NewButton("Load Scene")
+	loadSceneBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		if err := loadScene(defaultSceneFile, scene, ml); err != nil {
+			log.Println("Error loading scene:", err)
+			overlays.Notify("Could not load scene: " + err.Error())
+			return
+		}
+		watchFile(defaultSceneFile, func() error {
+			return loadScene(defaultSceneFile, scene, ml)
+		})
+		overlays.Notify("Scene loaded from " + defaultSceneFile)
 	})
+	addSidebarWidget(scene, loadSceneBtn)
+
+	initializeModelRenderUI(scene)
+	initializeOrientationUI(scene)
+	initializeContextModelsUI(scene)
+	initializeFlowSettingsUI(scene)
+	initializePreviewUI(scene)
+	initializePhysicsUI(scene)
+	initializeRigidBodyUI(scene)
+	initializeAmbientWindUI(scene)
+	initializeWindAudioUI(scene, cam)
+	initializeBindingsUI(scene)
+	initializeGustUI(scene)
+	initializeFieldSnapshotUI(scene)
+	initializeStagnationUI(scene)
+	initializeBoundaryLayerUI(scene, cam)
+	initializeOptimizerUI(scene)
+	initializeSensitivityUI(scene)
+	initializeUnitsUI(scene)
+	initializeAnnotationUI(scene, cam)
+	initializeQuizUI(scene, cam)
+	initializeScreenshotUI(scene, cam, ml)
+	initializeMarkerUI(scene)
+	initializeRecordingLimitsUI(scene)
+	initializeSourceClipboardUI(scene)
+	initializeBundleUI(scene, ml)
+	initializeHTMLExportUI(scene)
+	initializeUSDExportUI(scene)
+	initializePlaybackUI(scene)
+	initializeSessionUI(scene, ml)
+	initializeTabsUI(scene, ml)
+	initializeValidationUI(scene, ml)
+	initializeScenarioUI(scene, ml)
+	initializeAboutUI(scene)
 
-	w, h := app.App().GetSize()
-	updateButtonLayout(w, h)
+	waitingForWindPlacement := false
+
+	onLayout(updateSidebarLayout)
 
 	emptyBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
 		filePath, err := openFileDialog()
@@ -81,28 +104,8 @@ func initializeUI(scene *core.Node, windSources []WindSource, ml *ModelLoader, c
 
 		log.Println("Selected file:", filePath)
 
-		// Remove old model
-		if mesh != nil {
-			scene.Remove(mesh)
-			mesh = nil
-		}
-		ml.models = nil
-
-		// Load new model
-		if err := ml.LoadModel(filePath); err != nil {
+		if err := ml.ReplaceModel(filePath); err != nil {
 			log.Println("Error loading model:", err)
-			return
-		}
-
-		if len(ml.models) > 0 {
-			mesh = ml.models[0]
-			scene.Add(mesh)
-			// Set position directly (remove centering logic for now)
-			mesh.SetPosition(0, 1, 0)
-			log.Printf("New mesh loaded and added to scene at position: %v", mesh.Position())
-		} else {
-			log.Println("No models were loaded.")
-			mesh = nil
 		}
 	})
 
@@ -119,7 +122,7 @@ func initializeUI(scene *core.Node, windSources []WindSource, ml *ModelLoader, c
 		log.Println("Click on the scene to place the wind source")
 	})
 	app.App().Subscribe(window.OnMouseDown, func(evname string, ev interface{}) {
-		if !waitingForWindPlacement {
+		if !waitingForWindPlacement || overlays.Blocking() {
 			return
 		}
 
@@ -128,145 +131,208 @@ func initializeUI(scene *core.Node, windSources []WindSource, ml *ModelLoader, c
 			return
 		}
 
-		// Get the mouse position in normalized device coordinates
-		w, h := app.App().GetSize()
-		x := float32(mev.Xpos)/float32(w)*2 - 1
-		y := -(float32(mev.Ypos)/float32(h)*2 - 1)
-
-		// Get the projection and view matrices
-		projMatrix := &math32.Matrix4{}
-		viewMatrix := &math32.Matrix4{}
-		cam.ProjMatrix(projMatrix)
-		cam.ViewMatrix(viewMatrix)
-
-		// Compute the combined view-projection matrix
-		viewProjMatrix := &math32.Matrix4{}
-		viewProjMatrix.MultiplyMatrices(projMatrix, viewMatrix)
-
-		// Compute the inverse of the view-projection matrix
-		invViewProjMatrix := &math32.Matrix4{}
-		err := invViewProjMatrix.GetInverse(viewProjMatrix)
-		if err != nil {
-			log.Println("failed to invert view-projection matrix")
-			return
-		}
-
-		// Define near and far points in NDC
-		nearNDC := math32.NewVector4(x, y, 0, 1) // Near plane (z=0 in NDC)
-		farNDC := math32.NewVector4(x, y, 1, 1)  // Far plane (z=1 in NDC)
-
-		nearWorld := &math32.Vector4{}
-		farWorld := &math32.Vector4{}
-		nearNDC.ApplyMatrix4(invViewProjMatrix)
-		farNDC.ApplyMatrix4(invViewProjMatrix)
-		nearWorld.Copy(nearNDC)
-		farWorld.Copy(farNDC)
-
-		// Perspective divide to convert from homogeneous coordinates to 3
-		// Perspective divide to convert from homogeneous coordinates to 3D
-		near := &math32.Vector3{}
-		far := &math32.Vector3{}
-		if nearWorld.W != 0 {
-			near.X = nearWorld.X / nearWorld.W
-			near.Y = nearWorld.Y / nearWorld.W
-			near.Z = nearWorld.Z / nearWorld.W
-		}
-		if farWorld.W != 0 {
-			far.X = farWorld.X / farWorld.W
-			far.Y = farWorld.Y / farWorld.W
-			far.Z = farWorld.Z / farWorld.W
-		}
-
-		// Compute the ray direction from near to far
-		direction := far.Sub(near).Normalize()
-
-		// Compute intersection with the ground plane (y=0)
-		origin := cam.(*camera.Camera).GetNode().Position()
-		t := -origin.Y / direction.Y // Solve for t where y=0: origin.Y + t*direction.Y = 0
-		if t < 0 {
-			log.Println("No intersection with ground plane")
+		intersectPoint, ok := pickGroundPoint(cam, mev.Xpos, mev.Ypos)
+		if !ok {
 			return
 		}
 
-		// Compute the intersection point
-		intersectPoint := &math32.Vector3{}
-		intersectPoint.X = origin.X + t*direction.X
-		intersectPoint.Y = 0 // Ground plane
-		intersectPoint.Z = origin.Z + t*direction.Z
-
 		// Spawn the wind source at the intersected point
-		addWindSource(windSources, scene, *intersectPoint)
+		windSources = addWindSource(windSources, scene, *intersectPoint)
 
-		newIndex := len(windSources) - 1
-		windSpeedInput := createNumericInput((windSources)[newIndex].Speed, 100, 200+float32(newIndex*50), func(value float32) {
-			(windSources)[newIndex].Speed = value
-		})
-		scene.Add(windSpeedInput)
+		addWindSpeedInput(scene, len(windSources)-1)
 
 		log.Printf("Wind source added at position: %v", intersectPoint)
 		waitingForWindPlacement = false
 	})
 
 	// Use global mass and dragCoefficient from physics.go
-	massInput := createNumericInput(mass, 100, 100, func(value float32) {
+	massInput := NewNumericInput(mass, 0.01, 10000, 0.1, "kg", func(value float32) {
 		mass = value
 	})
-	scene.Add(massInput)
+	addControlWidget(scene, massInput)
 
-	dragInput := createNumericInput(dragCoefficient, 100, 150, func(value float32) {
+	dragInput := NewNumericInput(dragCoefficient, 0.01, 5, 0.05, "Cd", func(value float32) {
 		dragCoefficient = value
 	})
-	scene.Add(dragInput)
+	addControlWidget(scene, dragInput)
 
-	for i, wind := range windSources {
-		windSpeedInput := createNumericInput(wind.Speed, 100, 200+float32(i*50), func(value float32) {
-			windSources[i].Speed = value
-		})
-		scene.Add(windSpeedInput)
+	viscosityInput := NewNumericInput(simConfig.Viscosity, 0, 5, 0.01, "m2/s", func(value float32) {
+		simConfig.Viscosity = value
+	})
+	addControlWidget(scene, viscosityInput)
+
+	for i := range windSources {
+		addWindSpeedInput(scene, i)
 	}
 }
 
-func createNumericInput(defaultValue float32, x, y float32, onChange func(value float32)) *gui.Edit {
-	textInput := gui.NewEdit(100, fmt.Sprintf("%.2f", defaultValue))
-	textInput.SetPosition(x, y)
+// windSpeedInputs holds the speed field of each wind source, by source index
+var windSpeedInputs []*NumericInput
 
-	textInput.Subscribe(gui.OnChange, func(name string, ev interface{}) {
-		text := textInput.Text()
-		filteredText := filterNumericInput(text)
-		if text != filteredText {
-			textInput.SetText(filteredText)
-		}
+// addWindSpeedInput adds the speed field of wind source i
+func addWindSpeedInput(scene *core.Node, i int) {
+	windSpeedInput := NewNumericInput(windSources[i].Speed, 0.1, 100, 0.5, "m/s", func(value float32) {
+		windSources[i].Speed = value
 	})
+	addControlWidget(scene, windSpeedInput)
+	windSpeedInputs = append(windSpeedInputs, windSpeedInput)
+}
 
-	textInput.Subscribe(gui.OnKeyDown, func(name string, ev interface{}) {
-		kev := ev.(*window.KeyEvent)
-		if kev.Key == window.KeyEnter {
-			text := textInput.Text()
-			if value, err := strconv.ParseFloat(text, 32); err == nil && value > 0 {
-				onChange(float32(value))
-			} else {
-				textInput.SetText(fmt.Sprintf("%.2f", defaultValue))
-			}
+// setWindSources replaces the wind sources with sources, each with its marker and
+// speed field
+func setWindSources(scene *core.Node, sources []WindSource) {
+	removeWindSourceMarkers(scene)
+	for _, input := range windSpeedInputs {
+		removeControlWidget(scene, input)
+	}
+	windSpeedInputs = nil
+	emissionCounts = nil
+	windSources = sources
+	for i := range windSources {
+		attachWindSourceMarker(scene, &windSources[i])
+		addWindSpeedInput(scene, i)
+	}
+}
+
+// sidebarWidgets are stacked top to bottom in the right-hand column
+var sidebarWidgets []gui.IPanel
+
+func addSidebarWidget(scene *core.Node, widget gui.IPanel) {
+	sidebarWidgets = append(sidebarWidgets, widget)
+	scene.Add(widget)
+}
+
+func updateSidebarLayout(w, h int) {
+	const minWidth, minHeight = 400, 200
+	if w < minWidth || h < minHeight {
+		for _, widget := range sidebarWidgets {
+			widget.SetVisible(false)
 		}
-	})
+		return
+	}
 
-	return textInput
+	btnWidth := float32(w) * 0.15
+	btnHeight := float32(h) * 0.05
+	btnX := float32(w) - btnWidth - float32(w)*0.05
+	btnY := float32(h) * 0.1
+
+	for _, widget := range sidebarWidgets {
+		widget.SetVisible(true)
+		if btn, ok := widget.(*gui.Button); ok {
+			btn.SetSize(btnWidth, btnHeight)
+		} else {
+			widget.GetPanel().SetWidth(btnWidth)
+		}
+		widget.GetPanel().SetPosition(btnX, btnY)
+		btnY += widget.GetPanel().Height() + 10
+	}
 }
 
+// pickRay returns the ray from the camera through the given window position
+func pickRay(cam camera.ICamera, xpos, ypos float32) (origin, direction math32.Vector3, ok bool) {
+	// Get the mouse position in normalized device coordinates
+	w, h := app.App().GetSize()
+	x := xpos/float32(w)*2 - 1
+	y := -(ypos/float32(h)*2 - 1)
+
+	// Get the projection and view matrices
+	projMatrix := &math32.Matrix4{}
+	viewMatrix := &math32.Matrix4{}
+	cam.ProjMatrix(projMatrix)
+	cam.ViewMatrix(viewMatrix)
+
+	// Compute the combined view-projection matrix
+	viewProjMatrix := &math32.Matrix4{}
+	viewProjMatrix.MultiplyMatrices(projMatrix, viewMatrix)
+
+	// Compute the inverse of the view-projection matrix
+	invViewProjMatrix := &math32.Matrix4{}
+	err := invViewProjMatrix.GetInverse(viewProjMatrix)
+	if err != nil {
+		log.Println("failed to invert view-projection matrix")
+		return origin, direction, false
+	}
+
+	// Define near and far points in NDC
+	nearNDC := math32.NewVector4(x, y, 0, 1) // Near plane (z=0 in NDC)
+	farNDC := math32.NewVector4(x, y, 1, 1)  // Far plane (z=1 in NDC)
+
+	nearWorld := &math32.Vector4{}
+	farWorld := &math32.Vector4{}
+	nearNDC.ApplyMatrix4(invViewProjMatrix)
+	farNDC.ApplyMatrix4(invViewProjMatrix)
+	nearWorld.Copy(nearNDC)
+	farWorld.Copy(farNDC)
+
+	// Perspective divide to convert from homogeneous coordinates to 3
+	// Perspective divide to convert from homogeneous coordinates to 3D
+	near := &math32.Vector3{}
+	far := &math32.Vector3{}
+	if nearWorld.W != 0 {
+		near.X = nearWorld.X / nearWorld.W
+		near.Y = nearWorld.Y / nearWorld.W
+		near.Z = nearWorld.Z / nearWorld.W
+	}
+	if farWorld.W != 0 {
+		far.X = farWorld.X / farWorld.W
+		far.Y = farWorld.Y / farWorld.W
+		far.Z = farWorld.Z / farWorld.W
+	}
+
+	// Compute the ray direction from near to far
+	direction = *far.Sub(near).Normalize()
+	origin = cam.(*camera.Camera).GetNode().Position()
+	return origin, direction, true
+}
+
+// pickGroundPoint casts a ray from the camera through the given window position
+// and returns where it hits the ground plane (y=0)
+func pickGroundPoint(cam camera.ICamera, xpos, ypos float32) (*math32.Vector3, bool) {
+	origin, direction, ok := pickRay(cam, xpos, ypos)
+	if !ok {
+		return nil, false
+	}
+
+	// Compute intersection with the ground plane (y=0)
+	t := -origin.Y / direction.Y // Solve for t where y=0: origin.Y + t*direction.Y = 0
+	if t < 0 {
+		log.Println("No intersection with ground plane")
+		return nil, false
+	}
+
+	// Compute the intersection point
+	intersectPoint := &math32.Vector3{}
+	intersectPoint.X = origin.X + t*direction.X
+	intersectPoint.Y = 0 // Ground plane
+	intersectPoint.Z = origin.Z + t*direction.Z
+
+	return intersectPoint, true
+}
+
+// filterNumericInput keeps a number and the unit typed after it, dropping anything else
 func filterNumericInput(input string) string {
 	var builder strings.Builder
 	dotCount := 0
+	inUnit := false
 
 	for i, char := range input {
-		if char >= '0' && char <= '9' {
+		isUnitChar := unicode.IsLetter(char) || char == '°' || char == '/' || char == ' '
+		if inUnit {
+			if isUnitChar || (char >= '0' && char <= '9') {
+				builder.WriteRune(char)
+			}
+		} else if char >= '0' && char <= '9' {
 			builder.WriteRune(char)
 		} else if char == '.' && dotCount == 0 {
 			builder.WriteRune(char)
 			dotCount++
 		} else if char == '-' && i == 0 {
 			builder.WriteRune(char)
+		} else if isUnitChar && builder.Len() > 0 {
+			builder.WriteRune(char)
+			inUnit = true
 		}
 	}
 
 	return builder.String()
-}
\ No newline at end of file
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+)
+
+// UnitSystem picks the units values are shown in. Everything is stored and simulated in
+// SI; input fields accept any known unit of their quantity and convert it.
+type UnitSystem int
+
+const (
+	UnitsMetric UnitSystem = iota
+	UnitsImperial
+)
+
+var unitSystemNames = []string{"metric", "imperial"}
+
+var unitSystem UnitSystem
+
+// unit converts to SI as si = value*scale + offset
+type unit struct {
+	name          string
+	scale, offset float64
+}
+
+func (u unit) toSI(value float64) float64   { return value*u.scale + u.offset }
+func (u unit) fromSI(value float64) float64 { return (value - u.offset) / u.scale }
+
+// quantityUnits lists the units accepted for each SI unit, the SI unit first
+var quantityUnits = map[string][]unit{
+	"m":     {{"m", 1, 0}, {"cm", 0.01, 0}, {"mm", 0.001, 0}, {"km", 1000, 0}, {"ft", 0.3048, 0}, {"in", 0.0254, 0}, {"yd", 0.9144, 0}},
+	"m/s":   {{"m/s", 1, 0}, {"km/h", 1 / 3.6, 0}, {"kph", 1 / 3.6, 0}, {"mph", 0.44704, 0}, {"kn", 1852.0 / 3600, 0}, {"ft/s", 0.3048, 0}},
+	"kg":    {{"kg", 1, 0}, {"g", 0.001, 0}, {"lb", 0.45359237, 0}, {"oz", 0.028349523125, 0}},
+	"s":     {{"s", 1, 0}, {"ms", 0.001, 0}, {"min", 60, 0}, {"h", 3600, 0}},
+	"°C":    {{"°C", 1, 0}, {"C", 1, 0}, {"°F", 5.0 / 9, -32 * 5.0 / 9}, {"F", 5.0 / 9, -32 * 5.0 / 9}, {"K", 1, -273.15}},
+	"m2/s":  {{"m2/s", 1, 0}, {"ft2/s", 0.09290304, 0}},
+	"m2":    {{"m2", 1, 0}, {"cm2", 1e-4, 0}, {"ft2", 0.09290304, 0}, {"in2", 0.00064516, 0}},
+	"m/s2":  {{"m/s2", 1, 0}, {"ft/s2", 0.3048, 0}, {"g", 9.80665, 0}},
+	"kg/m3": {{"kg/m3", 1, 0}, {"g/l", 1, 0}, {"lb/ft3", 16.018463, 0}},
+}
+
+// imperialUnits names the unit shown for an SI unit in the imperial system
+var imperialUnits = map[string]string{
+	"m":     "ft",
+	"m/s":   "mph",
+	"kg":    "lb",
+	"°C":    "°F",
+	"m2/s":  "ft2/s",
+	"m2":    "ft2",
+	"m/s2":  "ft/s2",
+	"kg/m3": "lb/ft3",
+}
+
+// displayUnit returns the unit values of the SI unit si are shown in, ok is false when
+// si is only a label without conversions
+func displayUnit(si string) (unit, bool) {
+	units, ok := quantityUnits[si]
+	if !ok {
+		return unit{name: si, scale: 1}, false
+	}
+	if unitSystem == UnitsImperial {
+		if name, ok := imperialUnits[si]; ok {
+			for _, u := range units {
+				if u.name == name {
+					return u, true
+				}
+			}
+		}
+	}
+	return units[0], true
+}
+
+// parseQuantity reads a number optionally followed by a unit, like "12 mph", and returns
+// it in the SI unit si. A bare number is in the displayed unit.
+func parseQuantity(text, si string) (float32, error) {
+	text = strings.TrimSpace(text)
+	end := 0
+	for end < len(text) && strings.ContainsRune("+-.0123456789", rune(text[end])) {
+		end++
+	}
+	value, err := strconv.ParseFloat(text[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", text)
+	}
+	name := strings.TrimSpace(text[end:])
+	shown, _ := displayUnit(si)
+	if name == "" || name == shown.name {
+		return float32(shown.toSI(value)), nil
+	}
+	for _, u := range quantityUnits[si] {
+		if u.name == name {
+			return float32(u.toSI(value)), nil
+		}
+	}
+	// a second chance for "MPH" or "Km/h"
+	for _, u := range quantityUnits[si] {
+		if strings.EqualFold(u.name, name) {
+			return float32(u.toSI(value)), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit %q for %s", name, si)
+}
+
+// unitInputs are the inputs to refresh when the unit system changes. Inputs of closed
+// dialogs have no parent any more and are dropped.
+var unitInputs []*NumericInput
+
+func registerUnitInput(n *NumericInput) {
+	unitInputs = append(unitInputs, n)
+}
+
+func refreshUnitInputs() {
+	live := unitInputs[:0]
+	for _, n := range unitInputs {
+		if n.Parent() == nil {
+			continue
+		}
+		n.refreshUnit()
+		live = append(live, n)
+	}
+	unitInputs = live
+}
+
+func initializeUnitsUI(scene *core.Node) {
+	unitsBtn := gui.NewButton("Units: " + unitSystemNames[unitSystem])
+	unitsBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		unitSystem = (unitSystem + 1) % UnitSystem(len(unitSystemNames))
+		unitsBtn.Label.SetText("Units: " + unitSystemNames[unitSystem])
+		refreshUnitInputs()
+	})
+	addSidebarWidget(scene, unitsBtn)
+
+	csvBtn := gui.NewButton("CSV: " + csvLocaleNames[csvLocale])
+	csvBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		csvLocale = (csvLocale + 1) % CSVLocale(len(csvLocaleNames))
+		csvBtn.Label.SetText("CSV: " + csvLocaleNames[csvLocale])
+	})
+	addSidebarWidget(scene, csvBtn)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// The USD export writes the playback history as a text USD stage (.usda) that Blender,
+// Houdini and the other DCC tools import: the wind and fluid particles as Points prims
+// with their positions sampled per frame, and the model as a Mesh moved by a sampled
+// translation. Alembic would need its binary container, USD says the same in text.
+
+// usdParticleWidth is the diameter the particles are rendered with, in scene units
+const usdParticleWidth = 0.05
+
+// exportUSD writes the history to path
+func exportUSD(path string) error {
+	if history.Len() == 0 {
+		return fmt.Errorf("nothing recorded yet, run the simulation first")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	last := history.Len() - 1
+	fmt.Fprintf(w, "#usda 1.0\n(\n")
+	fmt.Fprintf(w, "    defaultPrim = \"Airflow\"\n")
+	fmt.Fprintf(w, "    upAxis = \"Y\"\n")
+	fmt.Fprintf(w, "    metersPerUnit = %g\n", simConfig.LengthUnit)
+	fmt.Fprintf(w, "    timeCodesPerSecond = %d\n", historySampleRate)
+	fmt.Fprintf(w, "    startTimeCode = 0\n    endTimeCode = %d\n)\n\n", last)
+	fmt.Fprintf(w, "def Xform \"Airflow\"\n{\n")
+
+	// the particles that were drawn, see visibleRecorded
+	writeUSDPoints(w, "WindParticles", math32.Color{R: 0, G: 1, B: 1}, func(frame *HistoryFrame) []math32.Vector3 {
+		return visibleRecorded(frame.WindPosition, frame.WindAccepted)
+	})
+	writeUSDPoints(w, "FluidParticles", math32.Color{R: 0.3, G: 0.5, B: 1}, func(frame *HistoryFrame) []math32.Vector3 {
+		return visibleRecorded(frame.FluidPosition, frame.FluidAccepted)
+	})
+	if mesh != nil {
+		writeUSDModel(w, mesh)
+	}
+
+	fmt.Fprintf(w, "}\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Exported %d frames to %s", history.Len(), path)
+	return nil
+}
+
+// writeUSDPoints writes a Points prim with the positions of every frame
+func writeUSDPoints(w *bufio.Writer, name string, color math32.Color, positions func(*HistoryFrame) []math32.Vector3) {
+	fmt.Fprintf(w, "    def Points \"%s\"\n    {\n", name)
+	fmt.Fprintf(w, "        color3f[] primvars:displayColor = [(%g, %g, %g)] (interpolation = \"constant\")\n", color.R, color.G, color.B)
+	fmt.Fprintf(w, "        point3f[] points.timeSamples = {\n")
+	for i := 0; i < history.Len(); i++ {
+		fmt.Fprintf(w, "            %d: [", i)
+		for n, p := range positions(history.At(i)) {
+			if n > 0 {
+				w.WriteString(", ")
+			}
+			fmt.Fprintf(w, "(%g, %g, %g)", roundMM(p.X), roundMM(p.Y), roundMM(p.Z))
+		}
+		w.WriteString("],\n")
+	}
+	fmt.Fprintf(w, "        }\n")
+	// a constant width needs no sample per particle
+	fmt.Fprintf(w, "        float[] widths = [%g] (interpolation = \"constant\")\n", usdParticleWidth)
+	fmt.Fprintf(w, "    }\n\n")
+}
+
+// writeUSDModel writes the model as a mesh relative to its position and the recorded
+// positions as a sampled translation
+func writeUSDModel(w *bufio.Writer, model *core.Node) {
+	origin := model.Position()
+	var points []math32.Vector3
+	forEachWorldTriangle(model, func(a, b, c math32.Vector3) {
+		points = append(points, *a.Sub(&origin), *b.Sub(&origin), *c.Sub(&origin))
+	})
+
+	fmt.Fprintf(w, "    def Mesh \"Model\"\n    {\n")
+	fmt.Fprintf(w, "        float3 xformOp:translate.timeSamples = {\n")
+	for i := 0; i < history.Len(); i++ {
+		p := history.At(i).ModelPosition
+		fmt.Fprintf(w, "            %d: (%g, %g, %g),\n", i, p.X, p.Y, p.Z)
+	}
+	fmt.Fprintf(w, "        }\n")
+	fmt.Fprintf(w, "        uniform token[] xformOpOrder = [\"xformOp:translate\"]\n")
+	w.WriteString("        int[] faceVertexCounts = [")
+	for i := 0; i < len(points)/3; i++ {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString("3")
+	}
+	w.WriteString("]\n        int[] faceVertexIndices = [")
+	for i := range points {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		fmt.Fprintf(w, "%d", i)
+	}
+	w.WriteString("]\n        point3f[] points = [")
+	for i, p := range points {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		fmt.Fprintf(w, "(%g, %g, %g)", roundMM(p.X), roundMM(p.Y), roundMM(p.Z))
+	}
+	w.WriteString("]\n")
+	// g3n winds its triangles counter-clockwise like USD's default
+	fmt.Fprintf(w, "        uniform token orientation = \"rightHanded\"\n")
+	fmt.Fprintf(w, "        uniform token subdivisionScheme = \"none\"\n")
+	fmt.Fprintf(w, "    }\n")
+}
+
+func initializeUSDExportUI(scene *core.Node) {
+	exportBtn := gui.NewButton("Export USD")
+	exportBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		path := fmt.Sprintf("airflow_%d.usda", time.Now().UnixNano())
+		if err := exportUSD(path); err != nil {
+			log.Println("Error exporting USD:", err)
+			overlays.Notify("Could not export USD: " + err.Error())
+			return
+		}
+		overlays.Notify("Particles exported to " + path)
+	})
+	addSidebarWidget(scene, exportBtn)
+}
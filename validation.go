@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// CylinderValidation runs the classic check of a flow solver: a cylinder standing across
+// the whole domain height in a uniform stream. The mean drag coefficient and the vortex
+// shedding frequency, as a Strouhal number, are compared with the published values for
+// the Reynolds number of the run.
+type CylinderValidation struct {
+	Diameter    float32 // m
+	Speed       float32 // m/s of the stream
+	SettleTime  float32 // simulated seconds before measuring
+	MeasureTime float32
+
+	running  bool
+	cylinder *core.Node
+	elapsed  float32
+	saved    struct {
+		ambient AmbientWind
+		solver  SolverMode
+	}
+
+	// measurements
+	dragSum   float32 // drag integrated over the measured time
+	measured  float32
+	lastSide  float32 // sign of the cross-stream velocity at the probe
+	crossings int
+}
+
+// ValidationResult compares a run with the reference values
+type ValidationResult struct {
+	Reynolds              float32
+	Cd, CdReference       float32
+	Strouhal, StReference float32
+}
+
+// cylinderReference holds measured drag coefficients and Strouhal numbers of a smooth
+// circular cylinder over the Reynolds number, from the collections of Roshko (1954)
+// and Schlichting's Boundary Layer Theory
+var cylinderReference = []struct{ re, cd, st float32 }{
+	{40, 1.55, 0.12},
+	{100, 1.35, 0.164},
+	{300, 1.22, 0.20},
+	{1000, 1.0, 0.21},
+	{1e4, 1.15, 0.20},
+	{1e5, 1.2, 0.20},
+}
+
+// referenceAt interpolates the reference values in log Re, clamped to the table
+func referenceAt(re float32) (cd, st float32) {
+	table := cylinderReference
+	if re <= table[0].re {
+		return table[0].cd, table[0].st
+	}
+	for i := 1; i < len(table); i++ {
+		if re <= table[i].re {
+			a, b := table[i-1], table[i]
+			t := float32(math.Log(float64(re/a.re)) / math.Log(float64(b.re/a.re)))
+			return a.cd + t*(b.cd-a.cd), a.st + t*(b.st-a.st)
+		}
+	}
+	last := table[len(table)-1]
+	return last.cd, last.st
+}
+
+var validation = CylinderValidation{
+	Diameter:    2,
+	Speed:       5,
+	SettleTime:  8,
+	MeasureTime: 20,
+}
+
+// Running reports whether a validation is in progress
+func (v *CylinderValidation) Running() bool {
+	return v.running
+}
+
+// Start places the cylinder in the middle of the domain and turns on a uniform stream
+// along +X. The scene should be empty, the caller clears it.
+func (v *CylinderValidation) Start() {
+	v.Stop()
+	height := float32(vectorField.Height)
+	radius := v.Diameter / 2 / simConfig.LengthUnit
+	cylinderMesh := graphic.NewMesh(geometry.NewCylinder(float64(radius), float64(height), 32, 1, true, true),
+		material.NewStandard(math32.NewColor("LightGray")))
+	v.cylinder = core.NewNode()
+	v.cylinder.Add(cylinderMesh)
+	v.cylinder.SetPosition(0, height/2, 0)
+	objects.Add(v.cylinder, "validation cylinder")
+
+	v.saved.ambient = ambientWind
+	v.saved.solver = simConfig.Solver
+	// a power law without exponent is uniform
+	ambientWind = AmbientWind{Enabled: true, Profile: ProfilePower, Speed: v.Speed, RefHeight: 1}
+	simConfig.Solver = SolverGrid
+	resetVectorField()
+	vectorField.seedAmbientWind()
+
+	v.running = true
+	v.elapsed = 0
+	v.dragSum, v.measured = 0, 0
+	v.lastSide, v.crossings = 0, 0
+	log.Printf("Cylinder validation started: D %.2f m, U %.2f m/s", v.Diameter, v.Speed)
+}
+
+// Stop abandons the validation, restores the settings and removes the cylinder
+func (v *CylinderValidation) Stop() {
+	v.finish()
+	if v.cylinder != nil {
+		objects.Remove(v.cylinder)
+		v.cylinder = nil
+	}
+}
+
+func (v *CylinderValidation) finish() {
+	if !v.running {
+		return
+	}
+	v.running = false
+	ambientWind = v.saved.ambient
+	simConfig.Solver = v.saved.solver
+}
+
+// Update measures the step just taken, called once per simulation step
+func (v *CylinderValidation) Update(dt float32) {
+	if !v.running {
+		return
+	}
+	v.elapsed += dt
+	// a symmetric start can stay symmetric, turning the stream aside for the first
+	// second breaks it so the wake can start swinging
+	ambientWind.Heading = 0
+	if v.elapsed < 1 {
+		ambientWind.Heading = 10
+	}
+	if v.elapsed < v.SettleTime {
+		return
+	}
+	v.dragSum += vectorField.PressureForce().X * dt
+	v.measured += dt
+
+	// the wake swings from side to side once per shedding period
+	probe := math32.Vector3{X: 2.5 * v.Diameter / simConfig.LengthUnit, Y: float32(vectorField.Height) / 2}
+	cross := vectorField.SampleVelocity(probe).Z
+	const threshold = 0.01 // of the stream speed, ignores jitter around zero
+	if math32.Abs(cross) > threshold*v.Speed {
+		if v.lastSide != 0 && (cross > 0) != (v.lastSide > 0) {
+			v.crossings++
+		}
+		v.lastSide = cross
+	}
+
+	if v.measured >= v.MeasureTime {
+		v.finish()
+		result := v.result()
+		log.Printf("Cylinder validation: Re %.0f, Cd %.3f (reference %.3f, %+.1f%%), St %.3f (reference %.3f, %+.1f%%)",
+			result.Reynolds, result.Cd, result.CdReference, percentError(result.Cd, result.CdReference),
+			result.Strouhal, result.StReference, percentError(result.Strouhal, result.StReference))
+		overlays.Notify("Cylinder validation done")
+		showValidationReport(result)
+	}
+}
+
+func (v *CylinderValidation) result() ValidationResult {
+	span := float32(vectorField.Height) * simConfig.LengthUnit
+	dynamicPressure := 0.5 * simConfig.AirDensity * v.Speed * v.Speed
+	frequency := float32(v.crossings) / 2 / v.measured
+	r := ValidationResult{
+		Reynolds: v.Speed * v.Diameter / math32.Max(simConfig.Viscosity, 1e-6),
+		Cd:       v.dragSum / v.measured / (dynamicPressure * v.Diameter * span),
+		Strouhal: frequency * v.Diameter / v.Speed,
+	}
+	r.CdReference, r.StReference = referenceAt(r.Reynolds)
+	return r
+}
+
+func percentError(value, reference float32) float32 {
+	return (value - reference) / reference * 100
+}
+
+func showValidationReport(r ValidationResult) {
+	dialog := gui.NewPanel(360, 150)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	lines := []string{
+		fmt.Sprintf("Flow past a cylinder at Re %.0f", r.Reynolds),
+		fmt.Sprintf("Drag coefficient  %.3f, published %.3f (%+.1f%%)", r.Cd, r.CdReference, percentError(r.Cd, r.CdReference)),
+		fmt.Sprintf("Strouhal number  %.3f, published %.3f (%+.1f%%)", r.Strouhal, r.StReference, percentError(r.Strouhal, r.StReference)),
+	}
+	if r.Strouhal == 0 {
+		lines = append(lines, "No vortex shedding was detected")
+	}
+	for i, line := range lines {
+		label := gui.NewLabel(line)
+		label.SetPosition(10, float32(10+22*i))
+		dialog.Add(label)
+	}
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(300, 115)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+// showValidationDialog sets up and starts the validation run
+func showValidationDialog(scene *core.Node, ml *ModelLoader) {
+	dialog := gui.NewPanel(300, 190)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Cylinder validation")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	y := float32(35)
+	row := func(name string, input *NumericInput) {
+		label := gui.NewLabel(name)
+		label.SetPosition(10, y+3)
+		dialog.Add(label)
+		input.SetPosition(140, y)
+		dialog.Add(input)
+		y += 30
+	}
+	row("Diameter", NewNumericInput(validation.Diameter, 0.5, 5, 0.5, "m", func(value float32) {
+		validation.Diameter = value
+	}))
+	row("Stream speed", NewNumericInput(validation.Speed, 0.5, 20, 0.5, "m/s", func(value float32) {
+		validation.Speed = value
+	}))
+	row("Settle time", NewNumericInput(validation.SettleTime, 1, 60, 1, "s", func(value float32) {
+		validation.SettleTime = value
+	}))
+	row("Measure time", NewNumericInput(validation.MeasureTime, 5, 120, 5, "s", func(value float32) {
+		validation.MeasureTime = value
+	}))
+
+	startBtn := gui.NewButton("Start")
+	startBtn.SetPosition(10, 155)
+	startBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+		overlays.Confirm("The validation replaces the scene. Continue?", func() {
+			newScene(scene, ml)
+			validation.Start()
+			overlays.Notify("Validation running, unpause the simulation if paused")
+		})
+	})
+	dialog.Add(startBtn)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(240, 155)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeValidationUI(scene *core.Node, ml *ModelLoader) {
+	validateBtn := gui.NewButton("Validate...")
+	validateBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showValidationDialog(scene, ml)
+	})
+	addSidebarWidget(scene, validateBtn)
+}
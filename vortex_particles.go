@@ -0,0 +1,254 @@
+package main
+
+import (
+	"log"
+
+	"github.com/g3n/engine/math32"
+)
+
+// In vortex mode the flow is carried by vortex particles instead of the grid: each holds
+// a vector strength (vorticity times volume) and the flow is the freestream plus the
+// velocity all of them induce by the Biot-Savart law. The vorticity is born where the
+// flow slips along the solid cells, which is what a wake is made of, so wakes come out
+// without the numerical diffusion of the grid smearing them. The particles move with
+// the flow, are stretched and tilted by it and spread by the viscosity (core spreading).
+//
+// The induced velocity is summed with a treecode: far from a group of particles their
+// combined strength acts from the group's center, so a field evaluation costs about
+// log n instead of n. The velocities fill the faces of the grid, a short pressure solve
+// then keeps the flow out of the solid cells, and everything else samples the grid as
+// in the other modes.
+
+const (
+	maxVortexParticles = 4000
+	vortexTheta        = 0.5 // a node acts as one particle when its size over its distance is below
+	vortexLeafSize     = 8   // particles summed directly at a leaf of the tree
+)
+
+// VortexParticle is a blob of vorticity
+type VortexParticle struct {
+	Position math32.Vector3
+	Strength math32.Vector3 // vorticity times volume, in scene units
+	Sigma2   float32        // squared core radius, smooths the singular kernel
+}
+
+var vortexParticles []VortexParticle
+
+// updateVortexFlow sheds, moves and stretches the vortex particles by dt and refills the
+// field with the flow they induce
+func updateVortexFlow(dt float32) {
+	f := &vectorField
+	f.markSolids(obstacleModels())
+	f.prepare()
+	f.keepStepStart()
+	u := freestream(windSources)
+
+	f.moveVortexParticles(dt)
+	f.shedVorticity(dt)
+	f.fillFromVortices(u)
+
+	f.measureChange(dt)
+}
+
+// shedVorticity releases the vorticity of the slip along the solid cells: next to a
+// wall of normal n the flow u slipping along it is a vortex sheet of strength n × u,
+// and what the flow carries off the wall in dt leaves as a particle
+func (f *VectorField) shedVorticity(dt float32) {
+	h := f.CellSize()
+	for _, c := range f.topology.fluidCoords {
+		var n math32.Vector3
+		for a := 0; a < 3; a++ {
+			for _, d := range []int{-1, 1} {
+				nb := c
+				nb[a] += d
+				if f.isSolid(nb) {
+					// the normal points away from the solid
+					n.SetComponent(a, n.Component(a)-float32(d))
+				}
+			}
+		}
+		if n.LengthSq() == 0 {
+			continue
+		}
+		n.Normalize()
+		v := f.cellVelocity(c)
+		slip := v.Clone().Sub(n.Clone().MultiplyScalar(v.Dot(&n)))
+		speed := slip.Length()
+		if speed < 1e-4 {
+			continue
+		}
+		strength := n.Clone().Cross(slip).MultiplyScalar(h * h * math32.Min(1, speed*dt/h))
+		vortexParticles = append(vortexParticles, VortexParticle{
+			Position: f.cellCenter(c[0], c[1], c[2]),
+			Strength: *strength,
+			Sigma2:   h * h,
+		})
+	}
+	// the oldest have traveled furthest downstream and matter least
+	if over := len(vortexParticles) - maxVortexParticles; over > 0 {
+		vortexParticles = append(vortexParticles[:0], vortexParticles[over:]...)
+	}
+}
+
+// moveVortexParticles convects the particles with the flow, stretches and tilts their
+// strengths by its gradient and spreads their cores by the viscosity. Particles that
+// leave the domain or enter a solid are dropped.
+func (f *VectorField) moveVortexParticles(dt float32) {
+	h := f.CellSize()
+	spread := 4 * simConfig.Viscosity * dt / (simConfig.LengthUnit * simConfig.LengthUnit)
+	live := vortexParticles[:0]
+	for _, p := range vortexParticles {
+		// stretching, d(alpha)/dt = (alpha . grad) u, along the strength
+		if length := p.Strength.Length(); length > 0 {
+			along := p.Strength.Clone().MultiplyScalar(h / 2 / length)
+			ahead := f.SampleVelocity(*p.Position.Clone().Add(along))
+			behind := f.SampleVelocity(*p.Position.Clone().Sub(along))
+			stretch := ahead.Sub(&behind).MultiplyScalar(length / h * dt)
+			p.Strength.Add(stretch)
+		}
+		v := f.SampleVelocity(p.Position)
+		p.Position.Add(v.MultiplyScalar(dt))
+		p.Sigma2 += spread
+
+		i, j, k, inside := f.cellAt(p.Position)
+		if !inside || f.isSolid([3]int{i, j, k}) || p.Position.Y < 0 {
+			continue
+		}
+		live = append(live, p)
+	}
+	vortexParticles = live
+}
+
+// fillFromVortices sets the open faces to the freestream u plus the induced flow and
+// projects it, which stops the flow at the solid faces
+func (f *VectorField) fillFromVortices(u math32.Vector3) {
+	tree := buildVortexTree(vortexParticles)
+	h := f.CellSize()
+	values := [3]float32{u.X, u.Y, u.Z}
+	for _, face := range f.topology.openFaces {
+		pos := f.cellCenter(face.c[0], face.c[1], face.c[2])
+		pos.SetComponent(face.axis, pos.Component(face.axis)-h/2)
+		v := values[face.axis]
+		if tree != nil {
+			var induced math32.Vector3
+			tree.velocity(pos, &induced)
+			v += induced.Component(face.axis)
+		}
+		f.setFace(face.axis, face.c, clamp(v, -maxFieldSpeed, maxFieldSpeed))
+	}
+	f.project(simConfig.SolverIterations)
+	f.applyBoundaries()
+	// Bernoulli leaves out the unsteady part of the pressure, near enough for the readouts
+	f.substep = 0
+	f.storeBernoulliPressure(u)
+}
+
+// vortexNode is a cube of the treecode holding some of the particles
+type vortexNode struct {
+	center    math32.Vector3 // strength weighted center of its particles
+	strength  math32.Vector3 // their summed strength
+	sigma2    float32        // their largest core
+	size      float32        // edge of the cube
+	children  []*vortexNode
+	particles []VortexParticle // at a leaf
+}
+
+// buildVortexTree returns the root of the tree over particles, nil when there are none
+func buildVortexTree(particles []VortexParticle) *vortexNode {
+	if len(particles) == 0 {
+		return nil
+	}
+	lo, hi := particles[0].Position, particles[0].Position
+	for _, p := range particles[1:] {
+		lo.Min(&p.Position)
+		hi.Max(&p.Position)
+	}
+	size := math32.Max(hi.X-lo.X, math32.Max(hi.Y-lo.Y, hi.Z-lo.Z))
+	return newVortexNode(append([]VortexParticle(nil), particles...), lo, size)
+}
+
+func newVortexNode(particles []VortexParticle, lo math32.Vector3, size float32) *vortexNode {
+	n := &vortexNode{size: size}
+	var weight float32
+	for _, p := range particles {
+		w := p.Strength.Length()
+		n.center.Add(p.Position.Clone().MultiplyScalar(w))
+		n.strength.Add(&p.Strength)
+		n.sigma2 = math32.Max(n.sigma2, p.Sigma2)
+		weight += w
+	}
+	if weight > 0 {
+		n.center.DivideScalar(weight)
+	} else {
+		n.center = *lo.Clone().AddScalar(size / 2)
+	}
+	if len(particles) <= vortexLeafSize || size < 1e-6 {
+		n.particles = particles
+		return n
+	}
+
+	half := size / 2
+	var octants [8][]VortexParticle
+	for _, p := range particles {
+		o := 0
+		if p.Position.X >= lo.X+half {
+			o |= 1
+		}
+		if p.Position.Y >= lo.Y+half {
+			o |= 2
+		}
+		if p.Position.Z >= lo.Z+half {
+			o |= 4
+		}
+		octants[o] = append(octants[o], p)
+	}
+	for o, group := range octants {
+		if len(group) == 0 {
+			continue
+		}
+		corner := lo
+		if o&1 != 0 {
+			corner.X += half
+		}
+		if o&2 != 0 {
+			corner.Y += half
+		}
+		if o&4 != 0 {
+			corner.Z += half
+		}
+		n.children = append(n.children, newVortexNode(group, corner, half))
+	}
+	return n
+}
+
+// velocity adds the velocity the node's particles induce at x to out
+func (n *vortexNode) velocity(x math32.Vector3, out *math32.Vector3) {
+	r := x.Clone().Sub(&n.center)
+	if n.particles == nil && n.size*n.size < vortexTheta*vortexTheta*r.LengthSq() {
+		addBiotSavart(x, n.center, n.strength, n.sigma2, out)
+		return
+	}
+	for _, p := range n.particles {
+		addBiotSavart(x, p.Position, p.Strength, p.Sigma2, out)
+	}
+	for _, child := range n.children {
+		child.velocity(x, out)
+	}
+}
+
+// addBiotSavart adds the velocity a vortex blob of strength alpha at pos induces at x,
+// alpha × r / (4 pi (r² + sigma²)^(3/2))
+func addBiotSavart(x, pos, alpha math32.Vector3, sigma2 float32, out *math32.Vector3) {
+	r := x.Sub(&pos)
+	d2 := r.LengthSq() + sigma2
+	scale := 1 / (4 * math32.Pi * d2 * math32.Sqrt(d2))
+	out.Add(alpha.Cross(r).MultiplyScalar(scale))
+}
+
+// clearVortexParticles drops the vorticity, when the field or the solver changes
+func clearVortexParticles() {
+	if len(vortexParticles) > 0 {
+		log.Printf("Dropped %d vortex particles", len(vortexParticles))
+	}
+	vortexParticles = nil
+}
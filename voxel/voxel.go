@@ -0,0 +1,215 @@
+// Package voxel converts mesh hierarchies into occupancy grids: the cells a model's
+// surface passes through are occupied, and so are the cells the surface encloses.
+package voxel
+
+import (
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/math32"
+)
+
+// Grid is a box of cubic cells, each occupied or free. Cells are stored x major, then y,
+// then z.
+type Grid struct {
+	Origin   math32.Vector3 // world position of the low corner
+	CellSize float32
+	Dims     [3]int
+	Cells    []bool
+}
+
+// NewGrid returns an empty grid of dims cells of size cellSize starting at origin
+func NewGrid(origin math32.Vector3, cellSize float32, dims [3]int) *Grid {
+	return &Grid{
+		Origin:   origin,
+		CellSize: cellSize,
+		Dims:     dims,
+		Cells:    make([]bool, dims[0]*dims[1]*dims[2]),
+	}
+}
+
+// Index returns the position of cell c in Cells
+func (g *Grid) Index(c [3]int) int {
+	return (c[0]*g.Dims[1]+c[1])*g.Dims[2] + c[2]
+}
+
+// Contains reports whether c is a cell of the grid
+func (g *Grid) Contains(c [3]int) bool {
+	for a := 0; a < 3; a++ {
+		if c[a] < 0 || c[a] >= g.Dims[a] {
+			return false
+		}
+	}
+	return true
+}
+
+// CellAt returns the cell containing pos and whether it is inside the grid
+func (g *Grid) CellAt(pos math32.Vector3) ([3]int, bool) {
+	c := [3]int{
+		int(math32.Floor((pos.X - g.Origin.X) / g.CellSize)),
+		int(math32.Floor((pos.Y - g.Origin.Y) / g.CellSize)),
+		int(math32.Floor((pos.Z - g.Origin.Z) / g.CellSize)),
+	}
+	return c, g.Contains(c)
+}
+
+// Occupied reports whether cell c is occupied, false outside the grid
+func (g *Grid) Occupied(c [3]int) bool {
+	return g.Contains(c) && g.Cells[g.Index(c)]
+}
+
+// Count returns the number of occupied cells
+func (g *Grid) Count() int {
+	n := 0
+	for _, occupied := range g.Cells {
+		if occupied {
+			n++
+		}
+	}
+	return n
+}
+
+// Clear frees every cell
+func (g *Grid) Clear() {
+	for i := range g.Cells {
+		g.Cells[i] = false
+	}
+}
+
+// AddTriangle occupies the cells the triangle passes through, sampling it at half the
+// cell size so no cell it crosses is skipped
+func (g *Grid) AddTriangle(a, b, c math32.Vector3) {
+	longest := math32.Max(a.DistanceTo(&b), math32.Max(b.DistanceTo(&c), c.DistanceTo(&a)))
+	steps := int(math32.Ceil(2*longest/g.CellSize)) + 1
+	ab := b.Clone().Sub(&a)
+	ac := c.Clone().Sub(&a)
+	for u := 0; u <= steps; u++ {
+		for v := 0; u+v <= steps; v++ {
+			p := a
+			p.Add(ab.Clone().MultiplyScalar(float32(u) / float32(steps)))
+			p.Add(ac.Clone().MultiplyScalar(float32(v) / float32(steps)))
+			if cell, inside := g.CellAt(p); inside {
+				g.Cells[g.Index(cell)] = true
+			}
+		}
+	}
+}
+
+// FillEnclosed occupies the cells inside closed surfaces: a flood fill from the outer
+// layer of cells through the free ones reaches everything outside, the rest is enclosed.
+// With floor set the low y side is closed ground, so a shell standing open side down on
+// it is filled too. The outer layer itself ends up free, except the floor's.
+func (g *Grid) FillEnclosed(floor bool) {
+	outside := make([]bool, len(g.Cells))
+	var queue [][3]int
+	for i := 0; i < g.Dims[0]; i++ {
+		for j := 0; j < g.Dims[1]; j++ {
+			for k := 0; k < g.Dims[2]; k++ {
+				c := [3]int{i, j, k}
+				edge := i == 0 || i == g.Dims[0]-1 || j == g.Dims[1]-1 || k == 0 || k == g.Dims[2]-1
+				if !floor && j == 0 {
+					edge = true
+				}
+				if edge {
+					outside[g.Index(c)] = true
+					queue = append(queue, c)
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for a := 0; a < 3; a++ {
+			for _, d := range []int{-1, 1} {
+				nb := c
+				nb[a] += d
+				if !g.Contains(nb) {
+					continue
+				}
+				i := g.Index(nb)
+				if outside[i] || g.Cells[i] {
+					continue
+				}
+				outside[i] = true
+				queue = append(queue, nb)
+			}
+		}
+	}
+	for i := range g.Cells {
+		g.Cells[i] = !outside[i]
+	}
+}
+
+// ForEachWorldTriangle calls cb with every triangle of the meshes under node in world space
+func ForEachWorldTriangle(node *core.Node, cb func(a, b, c math32.Vector3)) {
+	node.UpdateMatrixWorld()
+	var walk func(inode core.INode)
+	walk = func(inode core.INode) {
+		if gr, ok := inode.(graphic.IGraphic); ok {
+			world := inode.GetNode().MatrixWorld()
+			gr.GetGeometry().ReadFaces(func(a, b, c math32.Vector3) bool {
+				a.ApplyMatrix4(&world)
+				b.ApplyMatrix4(&world)
+				c.ApplyMatrix4(&world)
+				cb(a, b, c)
+				return false
+			})
+		}
+		for _, child := range inode.Children() {
+			walk(child)
+		}
+	}
+	walk(node)
+}
+
+// Voxelizer keeps a grid occupied by a set of models, voxelizing them again only when one
+// of them moves or the set changes
+type Voxelizer struct {
+	Grid  *Grid
+	Floor bool // see FillEnclosed
+
+	// Triangles lists the world space triangles of a model, ForEachWorldTriangle by default;
+	// a simplified collision mesh can stand in for the model here
+	Triangles func(model *core.Node, cb func(a, b, c math32.Vector3))
+
+	// Fill, when set, occupies the cells of a model itself and reports whether it did, e.g.
+	// from a distance field; the models it leaves are voxelized from their triangles
+	Fill func(model *core.Node, grid *Grid) bool
+
+	models   []*core.Node
+	matrices []math32.Matrix4 // model transforms the grid was computed for
+}
+
+// NewVoxelizer returns a voxelizer filling grid
+func NewVoxelizer(grid *Grid) *Voxelizer {
+	return &Voxelizer{Grid: grid, Triangles: ForEachWorldTriangle}
+}
+
+// Update voxelizes models into the grid if they changed since the last update and reports
+// whether they did
+func (v *Voxelizer) Update(models []*core.Node) bool {
+	matrices := make([]math32.Matrix4, len(models))
+	changed := len(models) != len(v.models)
+	for i, model := range models {
+		model.UpdateMatrixWorld()
+		matrices[i] = model.MatrixWorld()
+		if !changed && (v.models[i] != model || v.matrices[i] != matrices[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	v.models = models
+	v.matrices = matrices
+
+	v.Grid.Clear()
+	for _, model := range models {
+		if v.Fill != nil && v.Fill(model, v.Grid) {
+			continue
+		}
+		v.Triangles(model, v.Grid.AddTriangle)
+	}
+	v.Grid.FillEnclosed(v.Floor)
+	return true
+}
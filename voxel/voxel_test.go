@@ -0,0 +1,104 @@
+package voxel
+
+import (
+	"testing"
+
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/geometry"
+	"github.com/g3n/engine/graphic"
+	"github.com/g3n/engine/material"
+	"github.com/g3n/engine/math32"
+)
+
+// testGrid is a 4 m cube of 0.5 m cells centered on the origin
+func testGrid() *Grid {
+	return NewGrid(math32.Vector3{X: -2, Y: -2, Z: -2}, 0.5, [3]int{8, 8, 8})
+}
+
+func newModel(geom *geometry.Geometry) *core.Node {
+	node := core.NewNode()
+	node.Add(graphic.NewMesh(geom, material.NewStandard(math32.NewColor("White"))))
+	return node
+}
+
+func TestVoxelizeOccupancy(t *testing.T) {
+	tests := []struct {
+		name     string
+		geom     *geometry.Geometry
+		count    int // occupied cells, -1 to skip
+		occupied [][3]int
+		free     [][3]int
+	}{
+		{
+			// the faces at ±0.9 m fall inside cells 2 and 5, which are occupied with all between
+			name:     "box",
+			geom:     geometry.NewBox(1.8, 1.8, 1.8),
+			count:    4 * 4 * 4,
+			occupied: [][3]int{{2, 2, 2}, {3, 4, 3}, {5, 5, 5}},
+			free:     [][3]int{{1, 3, 3}, {6, 3, 3}, {3, 6, 3}, {0, 0, 0}},
+		},
+		{
+			name:     "sphere",
+			geom:     geometry.NewSphere(1, 32, 16),
+			count:    -1,
+			occupied: [][3]int{{3, 3, 3}, {4, 4, 4}, {2, 3, 4}, {4, 5, 3}},
+			free:     [][3]int{{0, 0, 0}, {1, 1, 1}, {6, 6, 6}, {7, 4, 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVoxelizer(testGrid())
+			if !v.Update([]*core.Node{newModel(tt.geom)}) {
+				t.Fatal("first Update reported no change")
+			}
+			if tt.count >= 0 {
+				if got := v.Grid.Count(); got != tt.count {
+					t.Errorf("%d cells occupied, want %d", got, tt.count)
+				}
+			}
+			for _, c := range tt.occupied {
+				if !v.Grid.Occupied(c) {
+					t.Errorf("cell %v free, want occupied", c)
+				}
+			}
+			for _, c := range tt.free {
+				if v.Grid.Occupied(c) {
+					t.Errorf("cell %v occupied, want free", c)
+				}
+			}
+		})
+	}
+}
+
+func TestVoxelizerFollowsMovedModel(t *testing.T) {
+	model := newModel(geometry.NewBox(1.8, 1.8, 1.8))
+	v := NewVoxelizer(testGrid())
+	models := []*core.Node{model}
+	v.Update(models)
+	if v.Update(models) {
+		t.Error("Update of an unmoved model reported a change")
+	}
+
+	// one cell (0.5 m) along x moves the box from cells 2..5 to 3..6
+	model.SetPosition(0.5, 0, 0)
+	if !v.Update(models) {
+		t.Fatal("Update of a moved model reported no change")
+	}
+	tests := []struct {
+		cell     [3]int
+		occupied bool
+	}{
+		{[3]int{2, 3, 3}, false},
+		{[3]int{3, 3, 3}, true},
+		{[3]int{6, 3, 3}, true},
+		{[3]int{7, 3, 3}, false},
+	}
+	for _, tt := range tests {
+		if got := v.Grid.Occupied(tt.cell); got != tt.occupied {
+			t.Errorf("cell %v occupied %v after the move, want %v", tt.cell, got, tt.occupied)
+		}
+	}
+	if got := v.Grid.Count(); got != 4*4*4 {
+		t.Errorf("%d cells occupied after the move, want %d", got, 4*4*4)
+	}
+}
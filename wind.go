@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"math/rand"
+	"time"
 
+	"github.com/g3n/demos/hellog3n/voxel"
 	"github.com/g3n/engine/core"
 	"github.com/g3n/engine/geometry"
 	"github.com/g3n/engine/graphic"
@@ -12,136 +14,267 @@ import (
 )
 
 type WindSource struct {
-	Position  math32.Vector3
-	Radius    float32
-	Speed     float32
-	Direction math32.Vector3
-	Node      *graphic.Mesh
+	Position    math32.Vector3
+	Radius      float32
+	Speed       float32
+	Direction   math32.Vector3
+	Temperature float32      // air temperature at the source in °C
+	Node        SourceMarker `json:"-"`
+
+	// Expressions of the simulated time the speed in m/s and the heading in degrees
+	// follow when set, see bindings.go
+	SpeedExpr   string `json:",omitempty"`
+	HeadingExpr string `json:",omitempty"`
+
+	Gust Gust // varies the speed when no expression sets it
 }
 
+// defaultTemperature is the ambient air temperature in °C
+const defaultTemperature = 20.0
+
+// WindParticle is a single wind particle, the particles themselves are kept in
+// the arrays of WindParticles
 type WindParticle struct {
-	Mesh     *graphic.Mesh
-	Velocity math32.Vector3
-	Lifespan float32
-	Elapsed  float32
+	Mesh        ParticleView
+	Position    math32.Vector3
+	Previous    math32.Vector3
+	Velocity    math32.Vector3
+	Lifespan    float32
+	Elapsed     float32
+	Source      int
+	Temperature float32
+	Density     float32
+	Pressure    float32
 }
 
-var windParticles []*WindParticle
+var windParticles WindParticles
+var windSources []WindSource
 
 func initializeWindSources(scene *core.Node) []WindSource {
 	windSources := []WindSource{
-		{Position: *math32.NewVector3(5, 2, 5), Radius: 3.0, Speed: 8.0, Direction: *math32.NewVector3(-1, 0, -1).Normalize()}, // Diagonal wind
-		{Position: *math32.NewVector3(-5, 2, -5), Radius: 2.0, Speed: 6.0, Direction: *math32.NewVector3(1, 0, 1).Normalize()}, // Opposite diagonal
+		{Position: *math32.NewVector3(5, 2, 5), Radius: 3.0, Speed: 8.0, Direction: *math32.NewVector3(-1, 0, -1).Normalize(), Temperature: defaultTemperature}, // Diagonal wind
+		{Position: *math32.NewVector3(-5, 2, -5), Radius: 2.0, Speed: 6.0, Direction: *math32.NewVector3(1, 0, 1).Normalize(), Temperature: defaultTemperature}, // Opposite diagonal
 	}
 
 	for i := range windSources {
-
-		sphereGeom := geometry.NewSphere(0.2, 16, 16)
-		sphereMat := material.NewStandard(math32.NewColor("Red"))
-		sphereMesh := graphic.NewMesh(sphereGeom, sphereMat)
-		sphereMesh.SetPositionVec(&windSources[i].Position)
-		windSources[i].Node = sphereMesh // Store the mesh in the WindSource struct
-		scene.Add(sphereMesh)
-	} // a few changes in here as well
+		attachWindSourceMarker(scene, &windSources[i])
+	}
 
 	return windSources
 }
 
 func addWindSource(windSource []WindSource, scene *core.Node, position math32.Vector3) []WindSource {
 	newWind := WindSource{
-		Position:  position,
-		Radius:    2.0,
-		Speed:     5.0,
-		Direction: *math32.NewVector3(1, 0, 0).Normalize(),
+		Position:    position,
+		Radius:      2.0,
+		Speed:       5.0,
+		Direction:   *math32.NewVector3(1, 0, 0).Normalize(),
+		Temperature: defaultTemperature,
 	}
 
+	attachWindSourceMarker(scene, &newWind)
+
+	return append(windSource, newWind)
+}
+
+// attachWindSourceMarker creates the marker that shows a wind source in the scene
+func attachWindSourceMarker(scene *core.Node, wind *WindSource) {
+	wind.Node = newSourceMarker(wind.Position)
+}
+
+// newSourceMarkerMesh creates the red sphere of a wind source
+func newSourceMarkerMesh(pos math32.Vector3) SourceMarker {
 	sphereGeom := geometry.NewSphere(0.2, 16, 16)
 	sphereMat := material.NewStandard(math32.NewColor("Red"))
 	sphereMesh := graphic.NewMesh(sphereGeom, sphereMat)
-	sphereMesh.SetPositionVec(&newWind.Position)
-	newWind.Node = sphereMesh
-	scene.Add(sphereMesh)
+	sphereMesh.SetPositionVec(&pos)
+	objects.Add(sphereMesh, "wind source")
+	return sphereMesh
+}
 
-	return append(windSource, newWind)
+// removeWindSourceMarkers detaches and frees the marker spheres of all wind sources
+func removeWindSourceMarkers(scene *core.Node) {
+	for i := range windSources {
+		if windSources[i].Node != nil {
+			removeView(windSources[i].Node)
+			windSources[i].Node = nil
+		}
+	}
+}
+
+func createWindParticle(source int) WindParticle {
+	wind := &windSources[source]
+	position, direction := wind.Position, wind.Direction
+	countEmission(source)
+
+	log.Printf("Adding wind particle at position: %v, Direction: %v", position, direction)
+	return WindParticle{
+		Mesh:        acquireWindView(position, direction),
+		Position:    position,
+		Previous:    position,
+		Velocity:    *direction.Clone().MultiplyScalar(2.0), // Increase speed for visibility
+		Lifespan:    5.0,
+		Elapsed:     0,
+		Source:      source,
+		Temperature: wind.Temperature,
+	}
 }
 
-func createWindParticle(position, direction math32.Vector3) *WindParticle {
-	// Create a thin cylinder to represent wind direction
-	particleGeom := geometry.NewCylinder(0.05, 0.5, 8, 1, true, true) // Use integer values for segments
-	particleMat := material.NewStandard(math32.NewColor("Cyan"))      // Bright color for visibility
-	particleMesh := graphic.NewMesh(particleGeom, particleMat)        // Use NewMesh instead of MeshFromGeometry
+// newWindParticleMesh creates the thin cylinder of a wind particle, turned along its
+// direction
+func newWindParticleMesh(position, direction math32.Vector3) ParticleView {
+	particleGeom := geometry.NewCylinder(0.05, windParticleLength, 8, 1, true, true) // Use integer values for segments
+	particleMat := material.NewStandard(math32.NewColor("Cyan"))                     // Bright color for visibility
+	particleMesh := graphic.NewMesh(particleGeom, particleMat)                       // Use NewMesh instead of MeshFromGeometry
 
 	// Position the particle
 	particleMesh.SetPosition(position.X, position.Y, position.Z)
+	orientWindParticle(particleMesh.GetNode(), direction)
+
+	objects.Add(particleMesh, "wind particle")
+	return particleMesh
+}
 
+// orientWindParticle turns the node of a wind particle mesh along direction
+func orientWindParticle(node *core.Node, direction math32.Vector3) {
 	// Calculate rotation angles directly
 	yaw := math32.Atan2(direction.Z, direction.X)          // Rotation around Y-axis
 	pitch := math32.Asin(direction.Y / direction.Length()) // Rotation around X-axis
 
 	// Apply the rotation
-	particleMesh.SetRotation(pitch, yaw, 0)
-
-	log.Printf("Adding wind particle at position: %v, Direction: %v", position, direction)
-	scene.Add(particleMesh)
+	node.SetRotation(pitch, yaw, 0)
+}
 
-	return &WindParticle{
-		Mesh:     particleMesh,
-		Velocity: *direction.Clone().MultiplyScalar(2.0), // Increase speed for visibility
-		Lifespan: 5.0,
-		Elapsed:  0,
+// obstacleAt returns the obstacle whose bounding box contains pos, if any
+func obstacleAt(obstacles []*core.Node, pos math32.Vector3) *core.Node {
+	for _, obstacle := range obstacles {
+		meshPos := obstacle.Position()
+		meshBounds := obstacle.BoundingBox()
+		if meshBounds.Min.Equals(&meshBounds.Max) {
+			continue
+		}
+		center := math32.NewVector3(0, 0, 0)
+		meshBounds.Center(center)
+		size := boxSize(&meshBounds)
+		halfExtents := size.MultiplyScalar(0.5)
+		center.Add(&meshPos)
+
+		if math32.Abs(pos.X-center.X) < halfExtents.X &&
+			math32.Abs(pos.Y-center.Y) < halfExtents.Y &&
+			math32.Abs(pos.Z-center.Z) < halfExtents.Z {
+			// inside the box, a model with triangles decides by its actual shape
+			if depth, _, ok := sdfContact(obstacle, pos); ok && depth <= 0 {
+				continue
+			}
+			return obstacle
+		}
 	}
+	return nil
 }
 
-func updateWindParticles(deltaTime float32, scene *core.Node, mesh *core.Node) {
-	var newParticles []*WindParticle
-	log.Printf("Processing %d wind particles", len(windParticles))
+func updateWindParticles(deltaTime float32, scene *core.Node, obstacles []*core.Node) {
+	w := &windParticles
+	log.Printf("Processing %d wind particles", w.Len())
+	copy(w.Previous, w.Position)
+	sph := simConfig.Solver == SolverSPH
+	if sph {
+		// the parcels move themselves, and keep the temperature they were emitted with
+		updateSPH(w, deltaTime)
+	}
 
-	for _, particle := range windParticles {
-		particle.Elapsed += deltaTime
-		if particle.Elapsed >= particle.Lifespan {
-			log.Printf("Removing particle at position: %v", particle.Mesh.Position())
-			scene.Remove(particle.Mesh)
-			continue
+	// the turbulent fluctuations come from the shared random source, drawn here in
+	// particle order so a seeded run repeats whatever the number of workers
+	n := w.Len()
+	var fluctuations []math32.Vector3
+	if !sph && simConfig.TurbulenceModel != TurbulenceToy {
+		fluctuations = make([]math32.Vector3, n)
+		for i := range fluctuations {
+			fluctuations[i] = *vectorField.turbulentFluctuation(w.Position[i])
 		}
+	}
 
-		// Update position
-		pos := particle.Mesh.Position()
-		pos.Add(particle.Velocity.Clone().MultiplyScalar(deltaTime))
-		particle.Mesh.SetPositionVec(&pos)
-
-		// Check collision with mesh
-		if mesh != nil {
-			meshPos := mesh.Position()
-			meshBounds := mesh.BoundingBox()
-			if !meshBounds.Min.Equals(&meshBounds.Max) {
-				center := math32.NewVector3(0, 0, 0)
-				meshBounds.Center(center)
-				size := math32.NewVector3(0, 0, 0)
-				meshBounds.Size(size)
-				halfExtents := size.MultiplyScalar(0.5)
-				center.Add(&meshPos)
-
-				if math32.Abs(pos.X-center.X) < halfExtents.X &&
-					math32.Abs(pos.Y-center.Y) < halfExtents.Y &&
-					math32.Abs(pos.Z-center.Z) < halfExtents.Z {
-					normal := center.Sub(&pos).Normalize()
-					particle.Velocity.Reflect(normal).MultiplyScalar(0.7) // Bounce with reduced speed
-					continue
+	// the particles are moved on all cores; the field is not stepped meanwhile, so the
+	// workers read it as it stood, and each writes only its own particles
+	field := &vectorField
+	parallelRange(n, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			w.Elapsed[i] += deltaTime
+			if w.Elapsed[i] >= w.Lifespan[i] {
+				continue
+			}
+
+			// Update position, carried by the flow field
+			pos := w.Position[i]
+			velocity := &w.Velocity[i]
+			if !sph {
+				w.Temperature[i] = field.TemperatureAt(pos)
+				if flow := field.SampleVelocity(pos); flow.Length() > 0 {
+					var fluctuation math32.Vector3
+					if fluctuations != nil {
+						fluctuation = fluctuations[i]
+					}
+					pos, *velocity = integrate(pos, flow, deltaTime, field.SampleVelocity)
+					velocity.Add(&fluctuation)
+					pos.Add(fluctuation.MultiplyScalar(deltaTime))
+				} else {
+					pos.Add(velocity.Clone().MultiplyScalar(deltaTime))
+				}
+			}
+			field.mirrorAtSymmetryPlane(&pos, velocity)
+			field.wrapParticle(&pos)
+			w.Position[i] = pos
+		}
+	})
+
+	// removing and bouncing touch the scene graph and stay on the main thread; the
+	// surviving particles are moved down over the removed ones
+	kept := 0
+	for i := 0; i < n; i++ {
+		if w.Elapsed[i] >= w.Lifespan[i] {
+			log.Printf("Removing particle at position: %v", w.Position[i])
+			releaseWindView(w.Mesh[i])
+			continue
+		}
+		pos := w.Position[i]
+		velocity := &w.Velocity[i]
+
+		// Check collision with the obstacles
+		if obstacle := obstacleAt(obstacles, pos); obstacle != nil {
+			if depth, normal, ok := sdfContact(obstacle, pos); ok {
+				// out to the surface along the distance field, bouncing off it
+				w.Position[i].Add(normal.Clone().MultiplyScalar(depth))
+				if velocity.Dot(&normal) < 0 {
+					velocity.Reflect(&normal).MultiplyScalar(0.7)
 				}
+				w.move(kept, i)
+				kept++
+				continue
 			}
+			meshPos := obstacle.Position()
+			meshBounds := obstacle.BoundingBox()
+			center := math32.NewVector3(0, 0, 0)
+			meshBounds.Center(center)
+			center.Add(&meshPos)
+			normal := center.Sub(&pos).Normalize()
+			velocity.Reflect(normal).MultiplyScalar(0.7) // Bounce with reduced speed
+			// Keep tracking the particle, dropping it here left its mesh orphaned in the scene
+			w.move(kept, i)
+			kept++
+			continue
 		}
 
 		// Keep particle in scene bounds (optional)
 		if pos.Length() > 20 {
 			log.Printf("Particle out of bounds at: %v", pos)
-			scene.Remove(particle.Mesh)
+			releaseWindView(w.Mesh[i])
 			continue
 		}
 
-		newParticles = append(newParticles, particle)
+		w.move(kept, i)
+		kept++
 	}
 
-	windParticles = newParticles
+	w.truncate(kept)
 }
 
 type VectorField struct {
@@ -151,7 +284,26 @@ type VectorField struct {
 	AreaWidth  int
 	AreaHeight int
 	AreaDepth  int
-	Field      [][][]Vector // 3D grid of vectors
+	Field      []Vector // the cells, in the order of index
+
+	pressure         []float32 // per cell, from the last projection
+	divergence       []float32
+	solid            []bool // cells covered by an obstacle model
+	topology         *fieldTopology
+	voxelizer        *voxel.Voxelizer
+	vorticity        []math32.Vector3 // per cell, scratch for the vorticity confinement
+	confinement      []math32.Vector3
+	diffused         []float32 // per open face, scratch for the diffusion
+	faceAlpha        []float32
+	tke              []float32 // per cell, turbulent kinetic energy k in m²/s²
+	dissipation      []float32 // per cell, its dissipation rate epsilon in m²/s³
+	turbScratch      []float32
+	subgridViscosity []float32 // per cell, Smagorinsky viscosity in LES mode
+	substep          float32   // length of the last substep, for converting the pressure
+	temperature      []float32 // per cell, air temperature in °C
+	tempScratch      []float32
+	stepStart        []float32 // per open face, velocities when the last step began
+	changeRate       float32
 }
 
 type Vector struct {
@@ -161,6 +313,8 @@ type Vector struct {
 	VX_ float32
 	VY_ float32
 	VZ_ float32
+
+	P float32 // pressure in Pa relative to the open boundary, see storePressure
 }
 
 type Particle struct {
@@ -174,7 +328,11 @@ type Particle struct {
 	VY    float32
 	VZ    float32
 	Speed float32
-	Mesh  *graphic.Mesh
+	Mesh  ParticleView
+
+	Age         float32 // seconds since the particle was spawned
+	Source      int     // index of the wind source the particle was spawned from
+	Temperature float32
 }
 
 var fluidParticles []Particle
@@ -195,16 +353,8 @@ func calcMagnitude3D(x, y, z float32) float32 {
 }
 
 func initVectorField(width, height, depth, areaWidth, areaHeight, areaDepth int) VectorField {
-	field := make([][][]Vector, areaWidth)
-	for x := 0; x < areaWidth; x++ {
-		field[x] = make([][]Vector, areaHeight)
-		for y := 0; y < areaHeight; y++ {
-			field[x][y] = make([]Vector, areaDepth)
-			for z := 0; z < areaDepth; z++ {
-				field[x][y][z] = Vector{VX: 0, VY: 0, VZ: -5, VX_: 0, VY_: 0, VZ_: 0}
-			}
-		}
-	}
+	// the flow starts at rest
+	field := make([]Vector, areaWidth*areaHeight*areaDepth)
 	return VectorField{
 		Width:      width,
 		Height:     height,
@@ -239,13 +389,7 @@ func initParticles(count int, windSources []WindSource, scene *core.Node) []Part
 		position := wind.Position.Clone().Add(offset)
 
 		// Create a small sphere for visualization
-		sphereGeom := geometry.NewSphere(0.1, 8, 8)
-		sphereMat := material.NewStandard(math32.NewColor("Blue"))
-		sphereMesh := graphic.NewMesh(sphereGeom, sphereMat)
-
-		// Correct positioning using SetPosition instead of SetPositionVec
-		sphereMesh.SetPosition(position.X, position.Y, position.Z)
-		scene.Add(sphereMesh)
+		sphereMesh := newFluidParticleView(*position)
 
 		// Initialize particle velocity based on wind direction with some randomness
 		velocity := wind.Direction.Clone().MultiplyScalar(wind.Speed).Add(
@@ -264,70 +408,86 @@ func initParticles(count int, windSources []WindSource, scene *core.Node) []Part
 			VY:   velocity.Y,
 			VZ:   velocity.Z,
 			Mesh: sphereMesh,
+
+			Source:      i % sourceCount,
+			Temperature: wind.Temperature,
 		}
 	}
 	return particles
 }
 
-func updateParticles(deltaTime float32) {
+// newFluidParticleMesh creates the sphere of a fluid particle at pos
+func newFluidParticleMesh(pos math32.Vector3) ParticleView {
+	detail := renderSettings.ParticleDetail
+	sphereGeom := geometry.NewSphere(fluidParticleLength/2, detail, detail)
+	sphereMat := material.NewStandard(math32.NewColor("Blue"))
+	sphereMesh := graphic.NewMesh(sphereGeom, sphereMat)
+	sphereMesh.SetPositionVec(&pos)
+	objects.Add(sphereMesh, "fluid particle")
+	return sphereMesh
+}
+
+// removeFluidParticles removes the fluid particles of the current scene and their meshes
+func removeFluidParticles() {
+	for _, p := range fluidParticles {
+		if p.Mesh != nil {
+			removeView(p.Mesh)
+		}
+	}
+	fluidParticles = nil
+}
+
+// rebuildParticleMeshes replaces every fluid particle sphere so a new detail level shows immediately
+func rebuildParticleMeshes(scene *core.Node) {
 	for i := range fluidParticles {
 		p := &fluidParticles[i]
+		if p.Mesh != nil {
+			removeView(p.Mesh)
+		}
+		p.Mesh = newFluidParticleView(math32.Vector3{X: p.X, Y: p.Y, Z: p.Z})
+	}
+	log.Printf("Rebuilt %d particle meshes with detail %d", len(fluidParticles), renderSettings.ParticleDetail)
+}
 
-		// Random turbulence
-		p.VX += (rand.Float32() - 0.5) * 0.1
-		p.VY += (rand.Float32() - 0.5) * 0.1
-		p.VZ += (rand.Float32() - 0.5) * 0.1
-
-		// Friction
-		p.VX *= 0.9
-		p.VY *= 0.9
-		p.VZ *= 0.9
+func updateParticles(deltaTime float32) {
+	for i := range fluidParticles {
+		p := &fluidParticles[i]
+		p.Age += deltaTime
+
+		// Follow the flow field, with the unresolved turbulence on top
+		at := math32.Vector3{X: p.X, Y: p.Y, Z: p.Z}
+		sample := vectorField.SampleVelocity
+		var fluctuation math32.Vector3
+		if simConfig.Solver == SolverSPH {
+			sample = sphVelocityAt
+		} else {
+			fluctuation = *vectorField.turbulentFluctuation(at)
+			p.Temperature = vectorField.TemperatureAt(at)
+		}
 
 		// Update position
 		p.OX = p.X
 		p.OY = p.Y
 		p.OZ = p.Z
-		p.X += p.VX * deltaTime
-		p.Y += p.VY * deltaTime
-		p.Z += p.VZ * deltaTime
+		pos, vel := integrate(at, sample(at), deltaTime, sample)
+		vel.Add(&fluctuation)
+		pos.Add(fluctuation.MultiplyScalar(deltaTime))
+		vectorField.mirrorAtSymmetryPlane(&pos, &vel)
+		vectorField.wrapParticle(&pos)
+		p.X, p.Y, p.Z = pos.X, pos.Y, pos.Z
+		p.VX, p.VY, p.VZ = vel.X, vel.Y, vel.Z
 
 		// Constrain to a reasonable area
 		const maxX, maxY, maxZ = 10.0, 5.0, 10.0
 		p.X = clamp(p.X, -maxX, maxX)
 		p.Y = clamp(p.Y, 0.1, maxY) // Keep above ground, but with upper limit
 		p.Z = clamp(p.Z, -maxZ, maxZ)
-
-		// Update the sphere's position
-		if p.Mesh != nil {
-			p.Mesh.SetPosition(p.X, p.Y, p.Z)
-		}
 	}
 }
 
-func updateVectorField() {
-	for x := 0; x < vectorField.AreaWidth; x++ {
-		for y := 0; y < vectorField.AreaHeight; y++ {
-			for z := 0; z < vectorField.AreaDepth; z++ {
-				v := &vectorField.Field[x][y][z]
-				v.VX_ = (v.VX + rand.Float32()*0.1) * 0.9
-				v.VY_ = (v.VY + rand.Float32()*0.1) * 0.9
-				v.VZ_ = (v.VZ + rand.Float32()*0.1) * 0.9
-
-				// Limit velocity
-				magnitude := calcMagnitude3D(v.VX_, v.VY_, v.VZ_)
-				if magnitude > 1 {
-					scale := 1 / magnitude
-					v.VX_ *= scale
-					v.VY_ *= scale
-					v.VZ_ *= scale
-				}
-
-				v.VX = v.VX_
-				v.VY = v.VY_
-				v.VZ = v.VZ_
-			}
-		}
-	}
+func updateVectorField(deltaTime float32) {
+	vectorField.markSolids(obstacleModels())
+	vectorField.Step(deltaTime, windSources)
 }
 
 func drawParticles() {
@@ -337,12 +497,35 @@ func drawParticles() {
 }
 
 func initializeFluidSimulation(scene *core.Node, windSources []WindSource) {
-	vectorField = initVectorField(20, 20, 20, 10, 10, 10)   // Adjusted dimensions for better visualization
-	fluidParticles = initParticles(250, windSources, scene) // Reduced particle count for clarity
+	resetVectorField()
+	fluidParticles = initParticles(particleCount(fluidParticleCount), windSources, scene)
+}
+
+// resetVectorField recreates the flow field over the default domain
+func resetVectorField() {
+	nx, ny, nz := fieldCells()
+	vectorField = initVectorField(20, 5, 20, nx, ny, nz) // 20x5x20 m
+	clearVortexParticles()
+}
+
+// advanceFlowField moves the flow field on by dt with the selected solver
+func advanceFlowField(deltaTime float32) {
+	defer runStats.FieldStep(time.Now())
+	// in SPH mode the wind particles carry the flow and the grid rests
+	switch simConfig.Solver {
+	case SolverGrid:
+		updateVectorField(deltaTime)
+		fieldAverage.Accumulate(&vectorField, deltaTime)
+	case SolverPotential:
+		updatePotentialFlow()
+		// the flow is steady but the heat of the sources still has to travel with it
+		vectorField.updateTemperature(deltaTime, windSources)
+	case SolverVortex:
+		updateVortexFlow(deltaTime)
+		vectorField.updateTemperature(deltaTime, windSources)
+	}
 }
 
 func simulateFluid(deltaTime float32) {
 	updateParticles(deltaTime)
-	updateVectorField()
-	drawParticles()
 }
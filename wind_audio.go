@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/g3n/engine/audio"
+	"github.com/g3n/engine/camera"
+	"github.com/g3n/engine/core"
+	"github.com/g3n/engine/gui"
+	"github.com/g3n/engine/math32"
+)
+
+// The wind can be heard: a loop of rumbling noise plays from the point of fastest flow
+// around the camera, louder and higher the faster the air moves there. The listener
+// rides on the camera, so the sound comes from where the wind blows.
+
+const (
+	windAudioRate     = 22050 // samples per second of the noise loop
+	windAudioSeconds  = 3
+	windAudioFade     = 0.5 // seconds crossfaded from the end into the start, so the loop has no click
+	windAudioRange    = 1.5 // m between the points sampled around the camera
+	windAudioLoudest  = 15  // m/s at which the sound is loudest and highest
+	windAudioMinPitch = 0.7
+	windAudioMaxPitch = 1.4
+)
+
+// WindAudio holds the options of the wind sound
+type WindAudio struct {
+	Enabled bool
+	Volume  float32 // gain at windAudioLoudest
+
+	player   *audio.Player
+	listener *audio.Listener
+}
+
+var windAudio = WindAudio{Volume: 0.8}
+
+// windNoiseFile writes the noise loop to the temporary directory once and returns its path
+func windNoiseFile() (string, error) {
+	path := filepath.Join(os.TempDir(), "airflow_wind.wav")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	n := windAudioRate * windAudioSeconds
+	fade := int(windAudioRate * windAudioFade)
+	total := n + fade
+	// brown noise, white noise integrated with a leak, sounds like wind rather than hiss
+	raw := make([]float32, total)
+	var level float32
+	for i := range raw {
+		level = 0.985*level + 0.05*(rand.Float32()*2-1)
+		raw[i] = level
+	}
+	samples := make([]int16, n)
+	for i := range samples {
+		v := raw[i]
+		if i < fade {
+			t := float32(i) / float32(fade)
+			v = t*v + (1-t)*raw[n+i]
+		}
+		samples[i] = int16(clamp(v*3, -1, 1) * 32767)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	size := uint32(2 * n)
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + size, [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16), uint16(1), uint16(1), // PCM, mono
+		uint32(windAudioRate), uint32(2 * windAudioRate), uint16(2), uint16(16),
+		[4]byte{'d', 'a', 't', 'a'}, size,
+	}
+	for _, v := range header {
+		if err := binary.Write(file, binary.LittleEndian, v); err != nil {
+			return "", err
+		}
+	}
+	if err := binary.Write(file, binary.LittleEndian, samples); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SetEnabled starts or stops the sound, creating the player the first time
+func (w *WindAudio) SetEnabled(enabled bool, scene *core.Node, cam *camera.Camera) {
+	w.Enabled = enabled
+	if !enabled {
+		if w.player != nil {
+			w.player.Stop()
+		}
+		return
+	}
+	if w.player == nil {
+		path, err := windNoiseFile()
+		if err != nil {
+			log.Printf("Wind audio: %v", err)
+			w.Enabled = false
+			return
+		}
+		player, err := audio.NewPlayer(path)
+		if err != nil {
+			log.Printf("Wind audio: %v", err)
+			w.Enabled = false
+			return
+		}
+		player.SetLooping(true)
+		player.SetGain(0)
+		w.player = player
+		scene.Add(player)
+		w.listener = audio.NewListener()
+		cam.Add(w.listener)
+	}
+	if err := w.player.Play(); err != nil {
+		log.Printf("Wind audio: %v", err)
+	}
+}
+
+// Update moves the sound to the fastest flow around the camera and sets its loudness and
+// pitch by the speed there; called once per frame
+func (w *WindAudio) Update(cam *camera.Camera) {
+	if !w.Enabled || w.player == nil {
+		return
+	}
+	center := cam.Position()
+	var loudest math32.Vector3
+	var fastest float32
+	for i := -1; i <= 1; i++ {
+		for j := -1; j <= 1; j++ {
+			for k := -1; k <= 1; k++ {
+				p := math32.Vector3{
+					X: center.X + float32(i)*windAudioRange,
+					Y: center.Y + float32(j)*windAudioRange,
+					Z: center.Z + float32(k)*windAudioRange,
+				}
+				v := vectorField.SampleVelocity(p)
+				if speed := v.Length(); speed > fastest {
+					fastest, loudest = speed, p
+				}
+			}
+		}
+	}
+	if fastest == 0 {
+		loudest = center
+	}
+	strength := clamp(fastest/windAudioLoudest, 0, 1)
+	w.player.SetPositionVec(&loudest)
+	// loudness grows with the square of the speed, like the power of the noise does
+	w.player.SetGain(w.Volume * strength * strength)
+	w.player.SetPitch(windAudioMinPitch + (windAudioMaxPitch-windAudioMinPitch)*strength)
+}
+
+func showWindAudioDialog(scene *core.Node, cam *camera.Camera) {
+	dialog := gui.NewPanel(260, 120)
+	dialog.SetColor4(&math32.Color4{R: 0.15, G: 0.15, B: 0.15, A: 0.95})
+	dialog.SetBorders(1, 1, 1, 1)
+	overlays.ShowModal(dialog)
+
+	title := gui.NewLabel("Wind sound")
+	title.SetPosition(10, 8)
+	dialog.Add(title)
+
+	enabledCheck := gui.NewCheckBox("Play the wind near the camera")
+	enabledCheck.SetPosition(10, 35)
+	enabledCheck.SetValue(windAudio.Enabled)
+	enabledCheck.Subscribe(gui.OnChange, func(name string, ev interface{}) {
+		windAudio.SetEnabled(enabledCheck.Value(), scene, cam)
+		if enabledCheck.Value() && !windAudio.Enabled {
+			overlays.Notify("No audio, see the log")
+			enabledCheck.SetValue(false)
+		}
+	})
+	dialog.Add(enabledCheck)
+
+	volumeLabel := gui.NewLabel("Volume")
+	volumeLabel.SetPosition(10, 63)
+	dialog.Add(volumeLabel)
+	volumeInput := NewNumericInput(windAudio.Volume, 0, 1, 0.1, "", func(value float32) {
+		windAudio.Volume = value
+	})
+	volumeInput.SetPosition(100, 60)
+	dialog.Add(volumeInput)
+
+	closeBtn := gui.NewButton("Close")
+	closeBtn.SetPosition(200, 88)
+	closeBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		overlays.CloseModal(dialog)
+	})
+	dialog.Add(closeBtn)
+}
+
+func initializeWindAudioUI(scene *core.Node, cam camera.ICamera) {
+	audioBtn := gui.NewButton("Wind Sound...")
+	audioBtn.Subscribe(gui.OnClick, func(name string, ev interface{}) {
+		showWindAudioDialog(scene, cam.(*camera.Camera))
+	})
+	addSidebarWidget(scene, audioBtn)
+}
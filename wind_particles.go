@@ -0,0 +1,91 @@
+package main
+
+import "github.com/g3n/engine/math32"
+
+// WindParticles stores the wind particles as parallel arrays, entry i of every array
+// belonging to particle i. The simulation loops run over the few arrays they need
+// instead of chasing a pointer per particle; At and Add convert from and to the
+// WindParticle of a single particle for the code that handles them one by one.
+type WindParticles struct {
+	Mesh        []ParticleView
+	Position    []math32.Vector3 // simulated position, the mesh shows it blended with Previous
+	Previous    []math32.Vector3 // position before the last simulation step
+	Velocity    []math32.Vector3
+	Lifespan    []float32
+	Elapsed     []float32
+	Source      []int // index of the emitting wind source
+	Temperature []float32
+	Density     []float32 // kg/m³, in SPH mode
+	Pressure    []float32 // Pa above the rest density's, in SPH mode
+}
+
+// Len returns the number of particles
+func (w *WindParticles) Len() int {
+	return len(w.Position)
+}
+
+// Add appends particle p
+func (w *WindParticles) Add(p WindParticle) {
+	w.Mesh = append(w.Mesh, p.Mesh)
+	w.Position = append(w.Position, p.Position)
+	w.Previous = append(w.Previous, p.Previous)
+	w.Velocity = append(w.Velocity, p.Velocity)
+	w.Lifespan = append(w.Lifespan, p.Lifespan)
+	w.Elapsed = append(w.Elapsed, p.Elapsed)
+	w.Source = append(w.Source, p.Source)
+	w.Temperature = append(w.Temperature, p.Temperature)
+	w.Density = append(w.Density, p.Density)
+	w.Pressure = append(w.Pressure, p.Pressure)
+}
+
+// At returns a copy of particle i
+func (w *WindParticles) At(i int) WindParticle {
+	return WindParticle{
+		Mesh:        w.Mesh[i],
+		Position:    w.Position[i],
+		Previous:    w.Previous[i],
+		Velocity:    w.Velocity[i],
+		Lifespan:    w.Lifespan[i],
+		Elapsed:     w.Elapsed[i],
+		Source:      w.Source[i],
+		Temperature: w.Temperature[i],
+		Density:     w.Density[i],
+		Pressure:    w.Pressure[i],
+	}
+}
+
+// move copies particle from over particle to, for compacting the arrays in place
+func (w *WindParticles) move(to, from int) {
+	w.Mesh[to] = w.Mesh[from]
+	w.Position[to] = w.Position[from]
+	w.Previous[to] = w.Previous[from]
+	w.Velocity[to] = w.Velocity[from]
+	w.Lifespan[to] = w.Lifespan[from]
+	w.Elapsed[to] = w.Elapsed[from]
+	w.Source[to] = w.Source[from]
+	w.Temperature[to] = w.Temperature[from]
+	w.Density[to] = w.Density[from]
+	w.Pressure[to] = w.Pressure[from]
+}
+
+// truncate keeps the first n particles
+func (w *WindParticles) truncate(n int) {
+	for i := n; i < len(w.Mesh); i++ {
+		w.Mesh[i] = nil // let the removed meshes be collected
+	}
+	w.Mesh = w.Mesh[:n]
+	w.Position = w.Position[:n]
+	w.Previous = w.Previous[:n]
+	w.Velocity = w.Velocity[:n]
+	w.Lifespan = w.Lifespan[:n]
+	w.Elapsed = w.Elapsed[:n]
+	w.Source = w.Source[:n]
+	w.Temperature = w.Temperature[:n]
+	w.Density = w.Density[:n]
+	w.Pressure = w.Pressure[:n]
+}
+
+// Clear removes every particle, the meshes are the caller's to remove from the scene
+func (w *WindParticles) Clear() {
+	w.truncate(0)
+}